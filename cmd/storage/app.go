@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"github.com/ATenderholt/dockerlib"
+	"github.com/ATenderholt/rainbow-storage/internal/certs"
 	"github.com/ATenderholt/rainbow-storage/internal/domain"
 	"github.com/ATenderholt/rainbow-storage/internal/service"
 	"github.com/ATenderholt/rainbow-storage/internal/settings"
@@ -20,23 +22,51 @@ import (
 )
 
 type App struct {
-	cfg           *settings.Config
-	docker        *dockerlib.DockerController
-	notifyService *service.NotificationService
-	srv           *http.Server
+	cfg                *settings.Config
+	docker             *dockerlib.DockerController
+	notifyService      *service.NotificationService
+	lifecycleService   *service.LifecycleService
+	replicationService *service.ReplicationService
+	accessLogService   *service.AccessLogService
+	srv                *http.Server
+	tlsSrv             *http.Server
+	websiteSrv         *http.Server
 }
 
-func NewApp(cfg *settings.Config, docker *dockerlib.DockerController, notifyService *service.NotificationService, mux *chi.Mux) App {
+func NewApp(cfg *settings.Config, docker *dockerlib.DockerController, notifyService *service.NotificationService,
+	lifecycleService *service.LifecycleService, replicationService *service.ReplicationService,
+	accessLogService *service.AccessLogService, mux *chi.Mux) App {
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%d", cfg.BasePort),
 		Handler: mux,
 	}
 
+	var tlsSrv *http.Server
+	if cfg.TlsPort != 0 {
+		tlsSrv = &http.Server{
+			Addr:    fmt.Sprintf(":%d", cfg.TlsPort),
+			Handler: mux,
+		}
+	}
+
+	var websiteSrv *http.Server
+	if cfg.WebsitePort != 0 {
+		websiteSrv = &http.Server{
+			Addr:    fmt.Sprintf(":%d", cfg.WebsitePort),
+			Handler: mux,
+		}
+	}
+
 	return App{
-		cfg:           cfg,
-		docker:        docker,
-		notifyService: notifyService,
-		srv:           srv,
+		cfg:                cfg,
+		docker:             docker,
+		notifyService:      notifyService,
+		lifecycleService:   lifecycleService,
+		replicationService: replicationService,
+		accessLogService:   accessLogService,
+		srv:                srv,
+		tlsSrv:             tlsSrv,
+		websiteSrv:         websiteSrv,
 	}
 }
 
@@ -44,9 +74,18 @@ func (app App) Start() error {
 	errors := make(chan error, 5)
 
 	go app.StartHttp(errors)
+	if app.tlsSrv != nil {
+		go app.StartHttps(errors)
+	}
+	if app.websiteSrv != nil {
+		go app.StartWebsites(errors)
+	}
 
 	app.StartDocker(errors)
 	app.StartNotifications(errors)
+	app.StartLifecycle()
+	app.replicationService.Start()
+	app.StartAccessLogs()
 
 	select {
 	case err := <-errors:
@@ -66,7 +105,64 @@ func (app *App) StartHttp(errors chan error) {
 	}
 }
 
+func (app *App) StartHttps(errors chan error) {
+	certificate, err := app.loadCertificate()
+	if err != nil {
+		logger.Errorf("Problem loading certificate for HTTPS server: %v", err)
+		errors <- err
+		return
+	}
+
+	app.tlsSrv.TLSConfig = &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	logger.Infof("Starting HTTPS server on port %d", app.cfg.TlsPort)
+	err = app.tlsSrv.ListenAndServeTLS("", "")
+
+	if err != nil && err != http.ErrServerClosed {
+		logger.Errorf("Problem starting HTTPS server: %v", err)
+		errors <- err
+	}
+}
+
+func (app *App) StartWebsites(errors chan error) {
+	logger.Infof("Starting website server on port %d", app.cfg.WebsitePort)
+	err := app.websiteSrv.ListenAndServe()
+
+	if err != nil && err != http.ErrServerClosed {
+		logger.Errorf("Problem starting website server: %v", err)
+		errors <- err
+	}
+}
+
+func (app *App) loadCertificate() (tls.Certificate, error) {
+	if app.cfg.TlsCertFile != "" {
+		logger.Infof("Loading certificate %s with key %s", app.cfg.TlsCertFile, app.cfg.TlsKeyFile)
+		return tls.LoadX509KeyPair(app.cfg.TlsCertFile, app.cfg.TlsKeyFile)
+	}
+
+	bundle, err := certs.LoadOrCreate(app.cfg.TlsPath(), app.cfg.TlsHosts())
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	if bundle.Generated {
+		logger.Infof("Generated certificate for %v in %s", app.cfg.TlsHosts(), app.cfg.TlsPath())
+	}
+
+	logger.Infof("Clients can trust the local CA from https://localhost:%d/_rainbow/ca.pem", app.cfg.TlsPort)
+
+	return bundle.Certificate, nil
+}
+
 func (app *App) StartDocker(errors chan error) {
+	if app.cfg.IsExternalBackend() {
+		logger.Infof("Using existing storage at %s instead of starting a container", app.cfg.BackendUrl)
+		return
+	}
+
 	logger.Infof("Starting background storage container")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
@@ -122,8 +218,12 @@ func (app *App) StartDocker(errors chan error) {
 			9000: app.cfg.BasePort + 1,
 			9001: app.cfg.BasePort + 2,
 		},
-		Network:     app.cfg.Networks,
-		Environment: []string{"MINIO_REGION=us-west-2"},
+		Network: app.cfg.Networks,
+		Environment: []string{
+			"MINIO_ROOT_USER=" + app.cfg.BackendAccessKey,
+			"MINIO_ROOT_PASSWORD=" + app.cfg.BackendSecretKey,
+			"MINIO_REGION=" + app.cfg.BackendRegion,
+		},
 	}
 
 	ready, err := app.docker.Start(ctx, &container, "Documentation: https://docs.min.io")
@@ -148,9 +248,33 @@ func (app App) StartNotifications(errors chan error) {
 	}
 }
 
+func (app App) StartLifecycle() {
+	if app.cfg.LifecycleInterval <= 0 {
+		logger.Info("Lifecycle rules are only applied when requested")
+		return
+	}
+
+	app.lifecycleService.Start(app.cfg.LifecycleInterval)
+}
+
+func (app App) StartAccessLogs() {
+	if app.cfg.AccessLogInterval <= 0 {
+		logger.Info("Server access logging is disabled")
+		return
+	}
+
+	app.accessLogService.Start(app.cfg.AccessLogInterval)
+}
+
 func (app App) Shutdown() error {
 	logger.Info("Starting shutdown of application")
 
+	app.lifecycleService.Stop()
+
+	// queued objects are replicated, and buffered access logs written, while the backend is still running
+	app.replicationService.Stop()
+	app.accessLogService.Stop()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
@@ -159,6 +283,20 @@ func (app App) Shutdown() error {
 		logger.Error("Unable to shutdown Docker containers: %v", err)
 	}
 
+	if app.tlsSrv != nil {
+		err = app.tlsSrv.Shutdown(ctx)
+		if err != nil {
+			logger.Errorf("Unable to shutdown HTTPS server: %v", err)
+		}
+	}
+
+	if app.websiteSrv != nil {
+		err = app.websiteSrv.Shutdown(ctx)
+		if err != nil {
+			logger.Errorf("Unable to shutdown website server: %v", err)
+		}
+	}
+
 	err = app.srv.Shutdown(ctx)
 	if err != nil {
 		logger.Error("Unable to shutdown HTTP server: %v", err)
@@ -184,7 +322,7 @@ func (l LambdaInvoker) Invoke(lambdaArn string) func(interface{}) {
 	return func(i interface{}) {
 		value := i.(domain.NotificationEvent)
 
-		logger.Infof("Processing %+v for lambdaArn %s", value, lambdaArn)
+		logger.With("requestId", value.RequestId).Infof("Processing %+v for lambdaArn %s", value, lambdaArn)
 		parts := strings.Split(lambdaArn, ":")
 
 		record := domain.LambdaRecord{
@@ -197,7 +335,10 @@ func (l LambdaInvoker) Invoke(lambdaArn string) func(interface{}) {
 			RequestParameters: domain.LambdaRequestParameters{
 				SourceIPAddress: value.SourceIp,
 			},
-			ResponseElements: domain.LambdaResponseElements{},
+			ResponseElements: domain.LambdaResponseElements{
+				RequestId: value.RequestId,
+				Id2:       value.HostId,
+			},
 			S3: domain.S3Record{
 				S3SchemaVersion: "1.0",
 				ConfigurationId: "",
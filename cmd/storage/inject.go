@@ -5,6 +5,7 @@ package main
 
 import (
 	"github.com/ATenderholt/dockerlib"
+	"github.com/ATenderholt/rainbow-storage/internal/backend"
 	"github.com/ATenderholt/rainbow-storage/internal/domain"
 	"github.com/ATenderholt/rainbow-storage/internal/http"
 	"github.com/ATenderholt/rainbow-storage/internal/service"
@@ -15,6 +16,9 @@ import (
 var api = wire.NewSet(
 	http.NewChiMux,
 	http.NewMinioHandler,
+	http.NewBackendClient,
+	http.NewAdminHandler,
+	http.NewAuthHandler,
 )
 
 func mapConfig(cfg *settings.Config) service.Config {
@@ -24,8 +28,14 @@ func mapConfig(cfg *settings.Config) service.Config {
 var services = wire.NewSet(
 	service.NewNotificationService,
 	service.NewConfigurationService,
+	service.NewLifecycleService,
+	service.NewReplicationService,
+	service.NewAccessLogService,
 	wire.Bind(new(http.NotificationService), new(*service.NotificationService)),
 	wire.Bind(new(http.ConfigurationService), new(*service.ConfigurationService)),
+	wire.Bind(new(http.LifecycleService), new(*service.LifecycleService)),
+	wire.Bind(new(http.ReplicationService), new(*service.ReplicationService)),
+	wire.Bind(new(http.AccessLogService), new(*service.AccessLogService)),
 	mapConfig,
 )
 
@@ -34,6 +44,8 @@ func InjectApp(cfg *settings.Config) (App, error) {
 		NewApp,
 		NewLambdaInvoker,
 		wire.Bind(new(domain.CloudFunctionInvoker), new(*LambdaInvoker)),
+		backend.NewStore,
+		wire.Bind(new(domain.ObjectStore), new(*backend.Store)),
 		api,
 		services,
 		dockerlib.NewDockerController,
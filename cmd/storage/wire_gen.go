@@ -8,6 +8,7 @@ package main
 
 import (
 	"github.com/ATenderholt/dockerlib"
+	"github.com/ATenderholt/rainbow-storage/internal/backend"
 	"github.com/ATenderholt/rainbow-storage/internal/http"
 	"github.com/ATenderholt/rainbow-storage/internal/service"
 	"github.com/ATenderholt/rainbow-storage/internal/settings"
@@ -25,18 +26,25 @@ func InjectApp(cfg *settings.Config) (App, error) {
 	lambdaInvoker := NewLambdaInvoker(cfg)
 	notificationService := service.NewNotificationService(config, lambdaInvoker)
 	configurationService := service.NewConfigurationService(config)
-	minioHandler := http.NewMinioHandler(cfg, notificationService, configurationService)
-	mux := http.NewChiMux(minioHandler)
-	app := NewApp(cfg, dockerController, notificationService, mux)
+	client := http.NewBackendClient()
+	store := backend.NewStore(cfg, client)
+	replicationService := service.NewReplicationService(configurationService, store)
+	accessLogService := service.NewAccessLogService(configurationService, store)
+	minioHandler := http.NewMinioHandler(cfg, client, notificationService, configurationService, replicationService, accessLogService)
+	lifecycleService := service.NewLifecycleService(configurationService, store)
+	adminHandler := http.NewAdminHandler(cfg, notificationService, lifecycleService, accessLogService)
+	authHandler := http.NewAuthHandler(cfg)
+	mux := http.NewChiMux(minioHandler, adminHandler, authHandler)
+	app := NewApp(cfg, dockerController, notificationService, lifecycleService, replicationService, accessLogService, mux)
 	return app, nil
 }
 
 // inject.go:
 
-var api = wire.NewSet(http.NewChiMux, http.NewMinioHandler)
+var api = wire.NewSet(http.NewChiMux, http.NewMinioHandler, http.NewBackendClient, http.NewAdminHandler, http.NewAuthHandler)
 
 func mapConfig(cfg *settings.Config) service.Config {
 	return cfg
 }
 
-var services = wire.NewSet(service.NewNotificationService, service.NewConfigurationService, wire.Bind(new(http.NotificationService), new(*service.NotificationService)), wire.Bind(new(http.ConfigurationService), new(*service.ConfigurationService)), mapConfig)
+var services = wire.NewSet(service.NewNotificationService, service.NewConfigurationService, service.NewLifecycleService, service.NewReplicationService, service.NewAccessLogService, wire.Bind(new(http.NotificationService), new(*service.NotificationService)), wire.Bind(new(http.ConfigurationService), new(*service.ConfigurationService)), wire.Bind(new(http.LifecycleService), new(*service.LifecycleService)), wire.Bind(new(http.ReplicationService), new(*service.ReplicationService)), wire.Bind(new(http.AccessLogService), new(*service.AccessLogService)), mapConfig)
@@ -0,0 +1,189 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/ATenderholt/rainbow-storage/internal/settings"
+	"github.com/ATenderholt/rainbow-storage/internal/sigv4"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Store reads and changes objects in Minio for work that doesn't come from a client request, with the
+// backend credentials.
+type Store struct {
+	client *s3.Client
+}
+
+func backendEndpointResolver(cfg *settings.Config) aws.EndpointResolverWithOptionsFunc {
+	return func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+		return aws.Endpoint{
+			URL:               cfg.MinioUrl(),
+			HostnameImmutable: true,
+		}, nil
+	}
+}
+
+func NewStore(cfg *settings.Config, client *http.Client) *Store {
+	var credentials aws.CredentialsProviderFunc = func(ctx context.Context) (aws.Credentials, error) {
+		return aws.Credentials{AccessKeyID: cfg.BackendAccessKey, SecretAccessKey: cfg.BackendSecretKey}, nil
+	}
+
+	config := aws.Config{
+		Region:                      cfg.BackendRegion,
+		Credentials:                 credentials,
+		EndpointResolverWithOptions: backendEndpointResolver(cfg),
+		HTTPClient:                  client,
+	}
+
+	return &Store{client: s3.NewFromConfig(config, func(options *s3.Options) {
+		options.UsePathStyle = true
+	})}
+}
+
+func (s Store) ListObjectVersions(ctx context.Context, bucket string, prefix string) ([]domain.ObjectVersion, error) {
+	var versions []domain.ObjectVersion
+
+	input := s3.ListObjectVersionsInput{Bucket: aws.String(bucket), Prefix: aws.String(prefix)}
+	for {
+		page, err := s.client.ListObjectVersions(ctx, &input)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list object versions in bucket %s: %v", bucket, err)
+		}
+
+		for _, version := range page.Versions {
+			versions = append(versions, domain.ObjectVersion{
+				Key:          aws.ToString(version.Key),
+				VersionId:    aws.ToString(version.VersionId),
+				IsLatest:     version.IsLatest,
+				LastModified: aws.ToTime(version.LastModified),
+				Size:         version.Size,
+			})
+		}
+
+		for _, marker := range page.DeleteMarkers {
+			versions = append(versions, domain.ObjectVersion{
+				Key:            aws.ToString(marker.Key),
+				VersionId:      aws.ToString(marker.VersionId),
+				IsLatest:       marker.IsLatest,
+				IsDeleteMarker: true,
+				LastModified:   aws.ToTime(marker.LastModified),
+			})
+		}
+
+		if !page.IsTruncated {
+			return versions, nil
+		}
+
+		input.KeyMarker, input.VersionIdMarker = page.NextKeyMarker, page.NextVersionIdMarker
+	}
+}
+
+func (s Store) ListMultipartUploads(ctx context.Context, bucket string, prefix string) ([]domain.MultipartUpload, error) {
+	var uploads []domain.MultipartUpload
+
+	input := s3.ListMultipartUploadsInput{Bucket: aws.String(bucket), Prefix: aws.String(prefix)}
+	for {
+		page, err := s.client.ListMultipartUploads(ctx, &input)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list multipart uploads in bucket %s: %v", bucket, err)
+		}
+
+		for _, upload := range page.Uploads {
+			uploads = append(uploads, domain.MultipartUpload{
+				Key:       aws.ToString(upload.Key),
+				UploadId:  aws.ToString(upload.UploadId),
+				Initiated: aws.ToTime(upload.Initiated),
+			})
+		}
+
+		if !page.IsTruncated {
+			return uploads, nil
+		}
+
+		input.KeyMarker, input.UploadIdMarker = page.NextKeyMarker, page.NextUploadIdMarker
+	}
+}
+
+func (s Store) GetObjectTagging(ctx context.Context, bucket string, key string, versionId string) ([]domain.Tag, error) {
+	input := s3.GetObjectTaggingInput{Bucket: aws.String(bucket), Key: aws.String(key), VersionId: optional(versionId)}
+	output, err := s.client.GetObjectTagging(ctx, &input)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get tags of %s in bucket %s: %v", key, bucket, err)
+	}
+
+	tags := make([]domain.Tag, 0, len(output.TagSet))
+	for _, tag := range output.TagSet {
+		tags = append(tags, domain.Tag{Key: aws.ToString(tag.Key), Value: aws.ToString(tag.Value)})
+	}
+
+	return tags, nil
+}
+
+func (s Store) PutObject(ctx context.Context, bucket string, key string, body []byte) error {
+	input := s3.PutObjectInput{Bucket: aws.String(bucket), Key: aws.String(key), Body: bytes.NewReader(body)}
+	_, err := s.client.PutObject(ctx, &input)
+	if err != nil {
+		return fmt.Errorf("unable to put %s in bucket %s: %v", key, bucket, err)
+	}
+
+	return nil
+}
+
+// CopyObject copies a version of an object, including its metadata and tags, to the same key in the destination
+// bucket, with the storage class of the source unless one is given. The version of the copy is returned if the
+// destination is versioned.
+func (s Store) CopyObject(ctx context.Context, bucket string, key string, versionId string, destination string, storageClass string) (string, error) {
+	source := strings.TrimPrefix(sigv4.EscapePath("/"+bucket+"/"+key), "/")
+	if versionId != "" {
+		source += "?versionId=" + url.QueryEscape(versionId)
+	}
+
+	input := s3.CopyObjectInput{
+		Bucket:       aws.String(destination),
+		Key:          aws.String(key),
+		CopySource:   aws.String(source),
+		StorageClass: types.StorageClass(storageClass),
+	}
+	output, err := s.client.CopyObject(ctx, &input)
+	if err != nil {
+		return "", fmt.Errorf("unable to copy %s in bucket %s to bucket %s: %v", key, bucket, destination, err)
+	}
+
+	return aws.ToString(output.VersionId), nil
+}
+
+// DeleteObject deletes a version of an object, or the current version when versionId is empty.
+func (s Store) DeleteObject(ctx context.Context, bucket string, key string, versionId string) error {
+	input := s3.DeleteObjectInput{Bucket: aws.String(bucket), Key: aws.String(key), VersionId: optional(versionId)}
+	_, err := s.client.DeleteObject(ctx, &input)
+	if err != nil {
+		return fmt.Errorf("unable to delete %s in bucket %s: %v", key, bucket, err)
+	}
+
+	return nil
+}
+
+func (s Store) AbortMultipartUpload(ctx context.Context, bucket string, key string, uploadId string) error {
+	input := s3.AbortMultipartUploadInput{Bucket: aws.String(bucket), Key: aws.String(key), UploadId: aws.String(uploadId)}
+	_, err := s.client.AbortMultipartUpload(ctx, &input)
+	if err != nil {
+		return fmt.Errorf("unable to abort upload %s of %s in bucket %s: %v", uploadId, key, bucket, err)
+	}
+
+	return nil
+}
+
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+
+	return aws.String(value)
+}
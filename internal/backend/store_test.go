@@ -0,0 +1,77 @@
+package backend
+
+import (
+	"context"
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/ATenderholt/rainbow-storage/internal/settings"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const firstPage = `<ListVersionsResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+<Name>bucket</Name><IsTruncated>true</IsTruncated><NextKeyMarker>a.txt</NextKeyMarker><NextVersionIdMarker>1</NextVersionIdMarker>
+<Version><Key>a.txt</Key><VersionId>1</VersionId><IsLatest>true</IsLatest><LastModified>2022-01-01T12:00:00.000Z</LastModified><Size>5</Size></Version>
+</ListVersionsResult>`
+
+const secondPage = `<ListVersionsResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+<Name>bucket</Name><IsTruncated>false</IsTruncated>
+<DeleteMarker><Key>b.txt</Key><VersionId>2</VersionId><IsLatest>true</IsLatest><LastModified>2022-01-02T12:00:00.000Z</LastModified></DeleteMarker>
+</ListVersionsResult>`
+
+func TestListObjectVersions(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		assert.Equal(t, "/bucket", r.URL.Path)
+		assert.Contains(t, r.Header.Get("Authorization"), "Credential=minio/")
+
+		if r.URL.Query().Get("key-marker") == "" {
+			_, _ = w.Write([]byte(firstPage))
+		} else {
+			_, _ = w.Write([]byte(secondPage))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	cfg, _, err := settings.FromFlags("test", []string{"-backend-url", server.URL, "-data-path", t.TempDir()})
+	if err != nil {
+		t.Fatalf("Unable to create configuration: %v", err)
+	}
+
+	store := NewStore(cfg, server.Client())
+
+	versions, err := store.ListObjectVersions(context.Background(), "bucket", "")
+	assert.NoError(t, err)
+	assert.Len(t, queries, 2)
+	assert.Equal(t, []domain.ObjectVersion{
+		{Key: "a.txt", VersionId: "1", IsLatest: true, LastModified: time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC), Size: 5},
+		{Key: "b.txt", VersionId: "2", IsLatest: true, IsDeleteMarker: true, LastModified: time.Date(2022, 1, 2, 12, 0, 0, 0, time.UTC)},
+	}, versions)
+}
+
+func TestCopyObject(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/backup/docs/a%20b.txt", r.URL.EscapedPath())
+		assert.Equal(t, "source/docs/a%20b.txt?versionId=1", r.Header.Get("X-Amz-Copy-Source"))
+		assert.Equal(t, "REDUCED_REDUNDANCY", r.Header.Get("X-Amz-Storage-Class"))
+
+		w.Header().Set("X-Amz-Version-Id", "2")
+		_, _ = w.Write([]byte(`<CopyObjectResult><ETag>"abc"</ETag></CopyObjectResult>`))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg, _, err := settings.FromFlags("test", []string{"-backend-url", server.URL, "-data-path", t.TempDir()})
+	if err != nil {
+		t.Fatalf("Unable to create configuration: %v", err)
+	}
+
+	store := NewStore(cfg, server.Client())
+
+	versionId, err := store.CopyObject(context.Background(), "source", "docs/a b.txt", "1", "backup", "REDUCED_REDUNDANCY")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", versionId)
+}
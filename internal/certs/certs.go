@@ -0,0 +1,280 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Files persisted in the directory passed to LoadOrCreate
+const (
+	CAFile        = "ca.pem"
+	caKeyFile     = "ca-key.pem"
+	serverFile    = "server.pem"
+	serverKeyFile = "server-key.pem"
+)
+
+const (
+	caValidity     = 10 * 365 * 24 * time.Hour
+	serverValidity = 825 * 24 * time.Hour // the longest that Apple platforms accept
+	renewBefore    = 30 * 24 * time.Hour
+)
+
+// Bundle is a server certificate for the HTTPS listener, issued by a local CA that clients can be told
+// to trust.
+type Bundle struct {
+	CA          []byte
+	Certificate tls.Certificate
+	Generated   bool
+}
+
+type keyPair struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// LoadOrCreate loads the local CA and server certificate from dir, generating either of them if they don't
+// exist. The server certificate is also re-issued when it is close to expiring or doesn't cover all hosts,
+// which can be DNS names (including wildcards) or IP addresses. The CA is kept, so clients keep trusting it.
+func LoadOrCreate(dir string, hosts []string) (*Bundle, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create directory %s: %v", dir, err)
+	}
+
+	generated := false
+
+	ca, err := load(dir, CAFile, caKeyFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		ca, err = createCA()
+		if err == nil {
+			err = save(dir, CAFile, caKeyFile, ca)
+		}
+		generated = true
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	server, err := load(dir, serverFile, serverKeyFile)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	case generated, !isValid(server.cert, ca.cert, hosts, time.Now()):
+		server = nil
+	}
+
+	if server == nil {
+		server, err = createServer(ca, hosts)
+		if err == nil {
+			err = save(dir, serverFile, serverKeyFile, server)
+		}
+		generated = true
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	certificate := tls.Certificate{
+		Certificate: [][]byte{server.cert.Raw, ca.cert.Raw},
+		PrivateKey:  server.key,
+		Leaf:        server.cert,
+	}
+
+	return &Bundle{CA: ca.pem, Certificate: certificate, Generated: generated}, nil
+}
+
+// isValid returns whether the certificate was issued by the CA, isn't about to expire and covers all hosts.
+func isValid(cert *x509.Certificate, ca *x509.Certificate, hosts []string, now time.Time) bool {
+	if cert.CheckSignatureFrom(ca) != nil || now.Add(renewBefore).After(cert.NotAfter) {
+		return false
+	}
+
+	dnsNames, ips := splitHosts(hosts)
+	for _, name := range dnsNames {
+		if !contains(cert.DNSNames, name) {
+			return false
+		}
+	}
+
+	for _, ip := range ips {
+		found := false
+		for _, certIp := range cert.IPAddresses {
+			found = found || certIp.Equal(ip)
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func splitHosts(hosts []string) ([]string, []net.IP) {
+	var dnsNames []string
+	var ips []net.IP
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			ips = append(ips, ip)
+		} else if host != "" {
+			dnsNames = append(dnsNames, host)
+		}
+	}
+
+	return dnsNames, ips
+}
+
+func serialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func createCA() (*keyPair, error) {
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate serial number: %v", err)
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "Rainbow Storage Local CA", Organization: []string{"Rainbow"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+
+	return create(&template, nil)
+}
+
+func createServer(ca *keyPair, hosts []string) (*keyPair, error) {
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate serial number: %v", err)
+	}
+
+	dnsNames, ips := splitHosts(hosts)
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "Rainbow Storage", Organization: []string{"Rainbow"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(serverValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     dnsNames,
+		IPAddresses:  ips,
+	}
+
+	return create(&template, ca)
+}
+
+// create generates a key and certificate from the template, signed by the parent or self-signed if it is nil.
+func create(template *x509.Certificate, parent *keyPair) (*keyPair, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate key: %v", err)
+	}
+
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create certificate %s: %v", template.Subject.CommonName, err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse certificate %s: %v", template.Subject.CommonName, err)
+	}
+
+	return &keyPair{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}, nil
+}
+
+func load(dir string, certFile string, keyFile string) (*keyPair, error) {
+	certPem, err := os.ReadFile(filepath.Join(dir, certFile))
+	if err != nil {
+		return nil, err
+	}
+
+	keyPem, err := os.ReadFile(filepath.Join(dir, keyFile))
+	if err != nil {
+		return nil, err
+	}
+
+	certBlock, _ := pem.Decode(certPem)
+	keyBlock, _ := pem.Decode(keyPem)
+	if certBlock == nil || keyBlock == nil {
+		return nil, fmt.Errorf("unable to decode PEM from %s or %s in %s", certFile, keyFile, dir)
+	}
+
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse certificate %s in %s: %v", certFile, dir, err)
+	}
+
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse key %s in %s: %v", keyFile, dir, err)
+	}
+
+	return &keyPair{cert: cert, key: key, pem: certPem}, nil
+}
+
+func save(dir string, certFile string, keyFile string, pair *keyPair) error {
+	der, err := x509.MarshalECPrivateKey(pair.key)
+	if err != nil {
+		return fmt.Errorf("unable to marshal key for %s: %v", certFile, err)
+	}
+
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+
+	err = os.WriteFile(filepath.Join(dir, keyFile), keyPem, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to write %s in %s: %v", keyFile, dir, err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, certFile), pair.pem, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to write %s in %s: %v", certFile, dir, err)
+	}
+
+	return nil
+}
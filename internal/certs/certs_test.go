@@ -0,0 +1,86 @@
+package certs_test
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/ATenderholt/rainbow-storage/internal/certs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func verify(t *testing.T, bundle *certs.Bundle, host string) error {
+	block, _ := pem.Decode(bundle.CA)
+	if block == nil {
+		t.Fatal("Unable to decode CA")
+	}
+
+	ca, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("Unable to parse CA: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	_, err = bundle.Certificate.Leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots})
+	return err
+}
+
+func TestLoadOrCreateGeneratesTrustedCertificate(t *testing.T) {
+	dir := t.TempDir()
+
+	bundle, err := certs.LoadOrCreate(dir, []string{"localhost", "*.s3.localhost", "127.0.0.1"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.True(t, bundle.Generated)
+	assert.NoError(t, verify(t, bundle, "localhost"))
+	assert.NoError(t, verify(t, bundle, "bucket.s3.localhost"))
+	assert.NoError(t, verify(t, bundle, "127.0.0.1"))
+	assert.Error(t, verify(t, bundle, "example.com"))
+
+	ca, err := os.ReadFile(filepath.Join(dir, certs.CAFile))
+	assert.NoError(t, err)
+	assert.Equal(t, bundle.CA, ca)
+}
+
+func TestLoadOrCreateReusesPersistedCertificates(t *testing.T) {
+	dir := t.TempDir()
+	hosts := []string{"localhost"}
+
+	first, err := certs.LoadOrCreate(dir, hosts)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	second, err := certs.LoadOrCreate(dir, hosts)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.False(t, second.Generated)
+	assert.Equal(t, first.CA, second.CA)
+	assert.Equal(t, first.Certificate.Leaf.Raw, second.Certificate.Leaf.Raw)
+}
+
+func TestLoadOrCreateReissuesCertificateForNewHosts(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := certs.LoadOrCreate(dir, []string{"localhost"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	second, err := certs.LoadOrCreate(dir, []string{"localhost", "storage.internal"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.True(t, second.Generated)
+	assert.Equal(t, first.CA, second.CA)
+	assert.NotEqual(t, first.Certificate.Leaf.Raw, second.Certificate.Leaf.Raw)
+	assert.NoError(t, verify(t, second, "storage.internal"))
+}
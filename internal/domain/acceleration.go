@@ -1,5 +1,16 @@
 package domain
 
+import "encoding/xml"
+
 type AccelerateConfiguration struct {
-	Status string `xml:"Status"`
+	XMLName xml.Name `xml:"AccelerateConfiguration"`
+	Status  string   `xml:"Status"`
+}
+
+func (a AccelerateConfiguration) Validate() error {
+	if !isOneOf(a.Status, "Enabled", "Suspended") {
+		return malformedXML()
+	}
+
+	return nil
 }
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const accessLogTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLogRecord is a request to a bucket with logging enabled. Empty values, and sizes that are negative,
+// are written as "-".
+type AccessLogRecord struct {
+	BucketOwner      string
+	Bucket           string
+	Time             time.Time
+	RemoteIp         string
+	Requester        string
+	RequestId        string
+	Operation        string // i.e. REST.GET.OBJECT
+	Key              string
+	RequestUri       string // i.e. GET /bucket/key HTTP/1.1
+	Status           int
+	ErrorCode        string
+	BytesSent        int64
+	ObjectSize       int64
+	TotalTime        time.Duration
+	TurnAroundTime   time.Duration
+	Referer          string
+	UserAgent        string
+	VersionId        string
+	HostId           string
+	SignatureVersion string
+	CipherSuite      string
+	AuthType         string
+	HostHeader       string
+	TlsVersion       string
+}
+
+// String formats the record as a line of an S3 server access log, with fields separated by spaces.
+func (r AccessLogRecord) String() string {
+	fields := []string{
+		orDash(r.BucketOwner),
+		orDash(r.Bucket),
+		"[" + r.Time.UTC().Format(accessLogTimeFormat) + "]",
+		orDash(r.RemoteIp),
+		orDash(r.Requester),
+		orDash(r.RequestId),
+		orDash(r.Operation),
+		orDash(r.Key),
+		quoted(r.RequestUri),
+		strconv.Itoa(r.Status),
+		orDash(r.ErrorCode),
+		size(r.BytesSent, false),
+		size(r.ObjectSize, true),
+		strconv.FormatInt(r.TotalTime.Milliseconds(), 10),
+		strconv.FormatInt(r.TurnAroundTime.Milliseconds(), 10),
+		quoted(r.Referer),
+		quoted(r.UserAgent),
+		orDash(r.VersionId),
+		orDash(r.HostId),
+		orDash(r.SignatureVersion),
+		orDash(r.CipherSuite),
+		orDash(r.AuthType),
+		orDash(r.HostHeader),
+		orDash(r.TlsVersion),
+		"-", // access point ARN
+		"-", // ACL required
+	}
+
+	return strings.Join(fields, " ")
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
+
+func quoted(value string) string {
+	return `"` + strings.ReplaceAll(orDash(value), `"`, `\"`) + `"`
+}
+
+// size writes a number of bytes, where nothing sent is "-" but an empty object has a size of 0.
+func size(value int64, zeroAllowed bool) string {
+	if value < 0 || (value == 0 && !zeroAllowed) {
+		return "-"
+	}
+
+	return strconv.FormatInt(value, 10)
+}
@@ -0,0 +1,40 @@
+package domain_test
+
+import (
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAccessLogRecordString(t *testing.T) {
+	record := domain.AccessLogRecord{
+		BucketOwner:      "owner",
+		Bucket:           "bucket",
+		Time:             time.Date(2022, 2, 6, 0, 0, 38, 0, time.UTC),
+		RemoteIp:         "192.0.2.3",
+		Requester:        "arn:aws:iam::271828182845:user/owner",
+		RequestId:        "3E57427F3EXAMPLE",
+		Operation:        "REST.GET.OBJECT",
+		Key:              "photos/puppy.jpg",
+		RequestUri:       "GET /bucket/photos/puppy.jpg HTTP/1.1",
+		Status:           200,
+		BytesSent:        2662992,
+		ObjectSize:       3462992,
+		TotalTime:        70 * time.Millisecond,
+		TurnAroundTime:   10 * time.Millisecond,
+		UserAgent:        `aws-cli/1.16 "quoted"`,
+		HostId:           "hostId",
+		SignatureVersion: "SigV4",
+		AuthType:         "AuthHeader",
+		HostHeader:       "localhost:9000",
+	}
+
+	assert.Equal(t, `owner bucket [06/Feb/2022:00:00:38 +0000] 192.0.2.3 arn:aws:iam::271828182845:user/owner `+
+		`3E57427F3EXAMPLE REST.GET.OBJECT photos/puppy.jpg "GET /bucket/photos/puppy.jpg HTTP/1.1" 200 - 2662992 3462992 `+
+		`70 10 "-" "aws-cli/1.16 \"quoted\"" - hostId SigV4 - AuthHeader localhost:9000 - - -`, record.String())
+
+	empty := domain.AccessLogRecord{Time: record.Time, Status: 404, ErrorCode: "NoSuchKey", ObjectSize: -1}
+	assert.Equal(t, `- - [06/Feb/2022:00:00:38 +0000] - - - - - "-" 404 NoSuchKey - - 0 0 "-" "-" - - - - - - - - -`,
+		empty.String())
+}
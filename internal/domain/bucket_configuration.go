@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+const S3Namespace = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+// BucketConfiguration is a configuration document for a subresource of a bucket, like ?cors or ?lifecycle.
+type BucketConfiguration interface {
+	Validate() error
+}
+
+// ValidationError describes why a configuration document was rejected, using the error codes of S3.
+type ValidationError struct {
+	Code    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+func malformedXML() ValidationError {
+	return ValidationError{
+		Code:    "MalformedXML",
+		Message: "The XML you provided was not well-formed or did not validate against our published schema.",
+	}
+}
+
+func invalidArgument(format string, a ...interface{}) ValidationError {
+	return ValidationError{Code: "InvalidArgument", Message: fmt.Sprintf(format, a...)}
+}
+
+func invalidRequest(format string, a ...interface{}) ValidationError {
+	return ValidationError{Code: "InvalidRequest", Message: fmt.Sprintf(format, a...)}
+}
+
+// NormalizeBucketConfiguration decodes and validates the document into config, then encodes it again in the
+// form returned by S3, so that stored documents don't depend on how the client formatted them. Documents with
+// elements that config doesn't model are rejected, rather than losing them.
+func NormalizeBucketConfiguration(payload []byte, config BucketConfiguration) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(payload))
+
+	var root xml.StartElement
+	for root.Name.Local == "" {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, malformedXML()
+		}
+
+		if start, ok := token.(xml.StartElement); ok {
+			root = start
+		}
+	}
+
+	err := decoder.DecodeElement(config, &root)
+	if err != nil {
+		return nil, malformedXML()
+	}
+
+	err = checkKnownElements(payload, config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = config.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	encoder := xml.NewEncoder(&buf)
+	err = encoder.EncodeElement(config, xml.StartElement{Name: xml.Name{Space: S3Namespace, Local: root.Name.Local}})
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func isOneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+
+	return false
+}
+
+// countSet returns how many of the alternatives of an element are set.
+func countSet(set ...bool) int {
+	count := 0
+	for _, s := range set {
+		if s {
+			count++
+		}
+	}
+
+	return count
+}
+
+// validateRuleIds checks the limits on ids of rules shared by lifecycle and replication configuration.
+func validateRuleIds(ids []string) error {
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if len(id) > 255 {
+			return invalidArgument("ID length should not exceed allowed limit of 255")
+		}
+
+		if id != "" && seen[id] {
+			return invalidArgument("Rule ID must be unique. Found same ID for more than one rule")
+		}
+
+		seen[id] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,215 @@
+package domain_test
+
+import (
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeBucketConfiguration(t *testing.T) {
+	payload := `<?xml version="1.0" encoding="UTF-8"?>
+<CORSConfiguration>
+  <CORSRule>
+    <AllowedOrigin>https://example.com</AllowedOrigin>
+    <AllowedMethod>GET</AllowedMethod>
+    <MaxAgeSeconds>300</MaxAgeSeconds>
+  </CORSRule>
+</CORSConfiguration>`
+
+	normalized, err := domain.NormalizeBucketConfiguration([]byte(payload), &domain.CORSConfiguration{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, `<CORSConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">`+
+		`<CORSRule><AllowedMethod>GET</AllowedMethod><AllowedOrigin>https://example.com</AllowedOrigin>`+
+		`<MaxAgeSeconds>300</MaxAgeSeconds></CORSRule></CORSConfiguration>`, string(normalized))
+}
+
+func TestNormalizeBucketConfigurationKeepsEverything(t *testing.T) {
+	tests := []struct {
+		config   domain.BucketConfiguration
+		document string
+	}{
+		{&domain.ReplicationConfiguration{}, `<ReplicationConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">` +
+			`<Role>arn:aws:iam::123:role/r</Role><Rule><ID>all</ID><Priority>1</Priority><Status>Enabled</Status>` +
+			`<Filter><Prefix></Prefix></Filter>` +
+			`<SourceSelectionCriteria><ReplicaModifications><Status>Enabled</Status></ReplicaModifications>` +
+			`<SseKmsEncryptedObjects><Status>Enabled</Status></SseKmsEncryptedObjects></SourceSelectionCriteria>` +
+			`<ExistingObjectReplication><Status>Disabled</Status></ExistingObjectReplication>` +
+			`<Destination><Bucket>arn:aws:s3:::copy</Bucket><Account>123</Account><StorageClass>STANDARD_IA</StorageClass>` +
+			`<AccessControlTranslation><Owner>Destination</Owner></AccessControlTranslation>` +
+			`<EncryptionConfiguration><ReplicaKmsKeyID>arn:aws:kms:us-west-2:123:key/k</ReplicaKmsKeyID></EncryptionConfiguration>` +
+			`<ReplicationTime><Status>Enabled</Status><Time><Minutes>15</Minutes></Time></ReplicationTime>` +
+			`<Metrics><Status>Enabled</Status><EventThreshold><Minutes>15</Minutes></EventThreshold></Metrics></Destination>` +
+			`<DeleteMarkerReplication><Status>Enabled</Status></DeleteMarkerReplication></Rule></ReplicationConfiguration>`},
+		{&domain.BucketLoggingStatus{}, `<BucketLoggingStatus xmlns="http://s3.amazonaws.com/doc/2006-03-01/">` +
+			`<LoggingEnabled><TargetBucket>logs</TargetBucket><TargetGrants><Grant>` +
+			`<Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="CanonicalUser"><ID>abc</ID></Grantee>` +
+			`<Permission>READ</Permission></Grant></TargetGrants><TargetPrefix>app/</TargetPrefix>` +
+			`<TargetObjectKeyFormat><PartitionedPrefix><PartitionDateSource>EventTime</PartitionDateSource></PartitionedPrefix>` +
+			`</TargetObjectKeyFormat></LoggingEnabled></BucketLoggingStatus>`},
+	}
+
+	for _, test := range tests {
+		normalized, err := domain.NormalizeBucketConfiguration([]byte(test.document), test.config)
+		if assert.NoError(t, err) {
+			assert.Equal(t, test.document, string(normalized))
+		}
+	}
+}
+
+func TestValidateBucketConfiguration(t *testing.T) {
+	tags := func(n int) string {
+		var b strings.Builder
+		for i := 0; i < n; i++ {
+			b.WriteString("<Tag><Key>key" + strings.Repeat("x", i) + "</Key><Value>v</Value></Tag>")
+		}
+		return b.String()
+	}
+
+	tests := []struct {
+		name    string
+		config  domain.BucketConfiguration
+		payload string
+		code    string
+	}{
+		{"not xml", &domain.CORSConfiguration{}, `not xml`, "MalformedXML"},
+		{"empty", &domain.CORSConfiguration{}, ``, "MalformedXML"},
+		{"wrong root", &domain.CORSConfiguration{}, `<Tagging><TagSet/></Tagging>`, "MalformedXML"},
+		{"cors without rules", &domain.CORSConfiguration{}, `<CORSConfiguration/>`, "MalformedXML"},
+		{"cors bad method", &domain.CORSConfiguration{},
+			`<CORSConfiguration><CORSRule><AllowedOrigin>*</AllowedOrigin><AllowedMethod>PATCH</AllowedMethod></CORSRule></CORSConfiguration>`, "InvalidRequest"},
+		{"lifecycle", &domain.LifecycleConfiguration{},
+			`<LifecycleConfiguration><Rule><ID>a</ID><Filter><Prefix>logs/</Prefix></Filter><Status>Enabled</Status><Expiration><Days>7</Days></Expiration></Rule></LifecycleConfiguration>`, ""},
+		{"lifecycle duplicate ids", &domain.LifecycleConfiguration{},
+			`<LifecycleConfiguration><Rule><ID>a</ID><Status>Enabled</Status><Expiration><Days>7</Days></Expiration></Rule><Rule><ID>a</ID><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`, "InvalidArgument"},
+		{"lifecycle without action", &domain.LifecycleConfiguration{},
+			`<LifecycleConfiguration><Rule><ID>a</ID><Status>Enabled</Status></Rule></LifecycleConfiguration>`, "InvalidRequest"},
+		{"lifecycle date not midnight", &domain.LifecycleConfiguration{},
+			`<LifecycleConfiguration><Rule><Status>Enabled</Status><Expiration><Date>2030-01-01T12:00:00Z</Date></Expiration></Rule></LifecycleConfiguration>`, "InvalidArgument"},
+		{"lifecycle bad status", &domain.LifecycleConfiguration{},
+			`<LifecycleConfiguration><Rule><Status>On</Status><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`, "MalformedXML"},
+		{"encryption", &domain.ServerSideEncryptionConfiguration{},
+			`<ServerSideEncryptionConfiguration><Rule><ApplyServerSideEncryptionByDefault><SSEAlgorithm>AES256</SSEAlgorithm></ApplyServerSideEncryptionByDefault></Rule></ServerSideEncryptionConfiguration>`, ""},
+		{"encryption bad algorithm", &domain.ServerSideEncryptionConfiguration{},
+			`<ServerSideEncryptionConfiguration><Rule><ApplyServerSideEncryptionByDefault><SSEAlgorithm>DES</SSEAlgorithm></ApplyServerSideEncryptionByDefault></Rule></ServerSideEncryptionConfiguration>`, "MalformedXML"},
+		{"tagging", &domain.Tagging{}, `<Tagging><TagSet>` + tags(50) + `</TagSet></Tagging>`, ""},
+		{"tagging too many", &domain.Tagging{}, `<Tagging><TagSet>` + tags(51) + `</TagSet></Tagging>`, "InvalidTag"},
+		{"tagging duplicate keys", &domain.Tagging{},
+			`<Tagging><TagSet><Tag><Key>a</Key><Value>1</Value></Tag><Tag><Key>a</Key><Value>2</Value></Tag></TagSet></Tagging>`, "InvalidTag"},
+		{"tagging system key", &domain.Tagging{},
+			`<Tagging><TagSet><Tag><Key>aws:owner</Key><Value>1</Value></Tag></TagSet></Tagging>`, "InvalidTag"},
+		{"versioning", &domain.VersioningConfiguration{}, `<VersioningConfiguration><Status>Enabled</Status></VersioningConfiguration>`, ""},
+		{"versioning bad status", &domain.VersioningConfiguration{}, `<VersioningConfiguration><Status>On</Status></VersioningConfiguration>`, "MalformedXML"},
+		{"website", &domain.WebsiteConfiguration{},
+			`<WebsiteConfiguration><IndexDocument><Suffix>index.html</Suffix></IndexDocument></WebsiteConfiguration>`, ""},
+		{"website without index", &domain.WebsiteConfiguration{},
+			`<WebsiteConfiguration><ErrorDocument><Key>error.html</Key></ErrorDocument></WebsiteConfiguration>`, "InvalidArgument"},
+		{"website redirect with index", &domain.WebsiteConfiguration{},
+			`<WebsiteConfiguration><RedirectAllRequestsTo><HostName>example.com</HostName></RedirectAllRequestsTo><IndexDocument><Suffix>index.html</Suffix></IndexDocument></WebsiteConfiguration>`, "InvalidRequest"},
+		{"logging disabled", &domain.BucketLoggingStatus{}, `<BucketLoggingStatus/>`, ""},
+		{"logging without target", &domain.BucketLoggingStatus{},
+			`<BucketLoggingStatus><LoggingEnabled><TargetPrefix>logs/</TargetPrefix></LoggingEnabled></BucketLoggingStatus>`, "MalformedXML"},
+		{"replication", &domain.ReplicationConfiguration{},
+			`<ReplicationConfiguration><Role>arn:aws:iam::123:role/r</Role><Rule><Status>Enabled</Status><Destination><Bucket>arn:aws:s3:::copy</Bucket></Destination></Rule></ReplicationConfiguration>`, ""},
+		{"replication unknown element", &domain.ReplicationConfiguration{},
+			`<ReplicationConfiguration><Role>arn:aws:iam::123:role/r</Role><Rule><Status>Enabled</Status><Destination><Bucket>arn:aws:s3:::copy</Bucket><Unknown/></Destination></Rule></ReplicationConfiguration>`, "MalformedXML"},
+		{"replication filter on size", &domain.ReplicationConfiguration{},
+			`<ReplicationConfiguration><Role>arn:aws:iam::123:role/r</Role><Rule><Status>Enabled</Status><Filter><ObjectSizeLessThan>10</ObjectSizeLessThan></Filter><Destination><Bucket>arn:aws:s3:::copy</Bucket></Destination></Rule></ReplicationConfiguration>`, "MalformedXML"},
+		{"replication time not 15 minutes", &domain.ReplicationConfiguration{},
+			`<ReplicationConfiguration><Role>arn:aws:iam::123:role/r</Role><Rule><Status>Enabled</Status><Destination><Bucket>arn:aws:s3:::copy</Bucket><ReplicationTime><Status>Enabled</Status><Time><Minutes>5</Minutes></Time></ReplicationTime></Destination></Rule></ReplicationConfiguration>`, "MalformedXML"},
+		{"logging bad permission", &domain.BucketLoggingStatus{},
+			`<BucketLoggingStatus><LoggingEnabled><TargetBucket>logs</TargetBucket><TargetGrants><Grant><Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="Group"><URI>http://acs.amazonaws.com/groups/global/AllUsers</URI></Grantee><Permission>DELETE</Permission></Grant></TargetGrants><TargetPrefix/></LoggingEnabled></BucketLoggingStatus>`, "MalformedXML"},
+		{"cors unknown element", &domain.CORSConfiguration{},
+			`<CORSConfiguration><CORSRule><AllowedOrigin>*</AllowedOrigin><AllowedMethod>GET</AllowedMethod><AllowedProtocol>https</AllowedProtocol></CORSRule></CORSConfiguration>`, "MalformedXML"},
+		{"replication bad destination", &domain.ReplicationConfiguration{},
+			`<ReplicationConfiguration><Role>arn:aws:iam::123:role/r</Role><Rule><Status>Enabled</Status><Destination><Bucket>copy</Bucket></Destination></Rule></ReplicationConfiguration>`, "InvalidArgument"},
+		{"object lock", &domain.ObjectLockConfiguration{},
+			`<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>GOVERNANCE</Mode><Days>1</Days></DefaultRetention></Rule></ObjectLockConfiguration>`, ""},
+		{"object lock days and years", &domain.ObjectLockConfiguration{},
+			`<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>GOVERNANCE</Mode><Days>1</Days><Years>1</Years></DefaultRetention></Rule></ObjectLockConfiguration>`, "MalformedXML"},
+		{"request payment", &domain.RequestPaymentConfiguration{}, `<RequestPaymentConfiguration><Payer>Requester</Payer></RequestPaymentConfiguration>`, ""},
+		{"request payment bad payer", &domain.RequestPaymentConfiguration{}, `<RequestPaymentConfiguration><Payer>Someone</Payer></RequestPaymentConfiguration>`, "MalformedXML"},
+	}
+
+	for _, test := range tests {
+		_, err := domain.NormalizeBucketConfiguration([]byte(test.payload), test.config)
+		if test.code == "" {
+			assert.NoError(t, err, test.name)
+			continue
+		}
+
+		var validationErr domain.ValidationError
+		if assert.ErrorAs(t, err, &validationErr, test.name) {
+			assert.Equal(t, test.code, validationErr.Code, test.name)
+		}
+	}
+}
+
+func TestCORSConfigurationMatch(t *testing.T) {
+	cors := domain.CORSConfiguration{CORSRules: []domain.CORSRule{
+		{ID: "app", AllowedOrigins: []string{"https://*.example.com"}, AllowedMethods: []string{"GET", "PUT"}, AllowedHeaders: []string{"Content-*"}},
+		{ID: "any", AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}},
+	}}
+
+	tests := []struct {
+		origin  string
+		method  string
+		headers []string
+		id      string
+	}{
+		{"https://app.example.com", "PUT", []string{"content-type", "Content-MD5"}, "app"},
+		{"https://app.example.com", "GET", nil, "app"},
+		{"https://app.example.com", "PUT", []string{"x-amz-date"}, ""},
+		{"https://app.example.com", "GET", []string{"x-amz-date"}, ""},
+		{"http://app.example.com", "GET", nil, "any"},
+		{"https://other.com", "PUT", nil, ""},
+		{"https://other.com", "DELETE", nil, ""},
+	}
+
+	for _, test := range tests {
+		rule, ok := cors.Match(test.origin, test.method, test.headers)
+		assert.Equal(t, test.id != "", ok, "%s %s %v", test.method, test.origin, test.headers)
+		assert.Equal(t, test.id, rule.ID, "%s %s %v", test.method, test.origin, test.headers)
+	}
+}
+
+func TestWebsiteConfigurationRouting(t *testing.T) {
+	website := domain.WebsiteConfiguration{
+		IndexDocument: &domain.IndexDocument{Suffix: "index.html"},
+		RoutingRules: []domain.RoutingRule{
+			{
+				Condition: &domain.RoutingRuleCondition{KeyPrefixEquals: "docs/"},
+				Redirect:  domain.Redirect{ReplaceKeyPrefixWith: "documents/"},
+			},
+			{
+				Condition: &domain.RoutingRuleCondition{HttpErrorCodeReturnedEquals: "404"},
+				Redirect:  domain.Redirect{ReplaceKeyWith: "404.html", HttpRedirectCode: "302"},
+			},
+		},
+	}
+
+	assert.Equal(t, "index.html", website.IndexKey(""))
+	assert.Equal(t, "about/index.html", website.IndexKey("about/"))
+	assert.Equal(t, "about.html", website.IndexKey("about.html"))
+
+	rule, ok := website.MatchRoutingRule("docs/a.html", 0)
+	if assert.True(t, ok) {
+		assert.Equal(t, "documents/a.html", rule.RedirectKey("docs/a.html"))
+		assert.Equal(t, 301, rule.RedirectCode())
+	}
+
+	_, ok = website.MatchRoutingRule("a.html", 0)
+	assert.False(t, ok)
+
+	rule, ok = website.MatchRoutingRule("a.html", 404)
+	if assert.True(t, ok) {
+		assert.Equal(t, "404.html", rule.RedirectKey("a.html"))
+		assert.Equal(t, 302, rule.RedirectCode())
+	}
+
+	_, ok = website.MatchRoutingRule("a.html", 403)
+	assert.False(t, ok)
+}
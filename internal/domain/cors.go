@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+type CORSConfiguration struct {
+	XMLName   xml.Name   `xml:"CORSConfiguration"`
+	CORSRules []CORSRule `xml:"CORSRule"`
+}
+
+type CORSRule struct {
+	ID             string   `xml:"ID,omitempty"`
+	AllowedHeaders []string `xml:"AllowedHeader"`
+	AllowedMethods []string `xml:"AllowedMethod"`
+	AllowedOrigins []string `xml:"AllowedOrigin"`
+	ExposeHeaders  []string `xml:"ExposeHeader"`
+	MaxAgeSeconds  *int     `xml:"MaxAgeSeconds,omitempty"`
+}
+
+func (c CORSConfiguration) Validate() error {
+	if len(c.CORSRules) == 0 {
+		return malformedXML()
+	}
+
+	if len(c.CORSRules) > 100 {
+		return invalidRequest("The number of CORS rules should not exceed allowed limit of 100 rules.")
+	}
+
+	for _, rule := range c.CORSRules {
+		err := rule.validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r CORSRule) validate() error {
+	if len(r.AllowedMethods) == 0 || len(r.AllowedOrigins) == 0 {
+		return malformedXML()
+	}
+
+	if len(r.ID) > 255 {
+		return invalidArgument("ID length should not exceed allowed limit of 255")
+	}
+
+	for _, method := range r.AllowedMethods {
+		if !isOneOf(method, "GET", "PUT", "POST", "DELETE", "HEAD") {
+			return invalidRequest("Found unsupported HTTP method in CORS config. Unsupported method is %s", method)
+		}
+	}
+
+	for _, origin := range r.AllowedOrigins {
+		if strings.Count(origin, "*") > 1 {
+			return invalidRequest("AllowedOrigin \"%s\" can not have more than one wildcard.", origin)
+		}
+	}
+
+	for _, header := range r.AllowedHeaders {
+		if strings.Count(header, "*") > 1 {
+			return invalidRequest("AllowedHeader \"%s\" can not have more than one wildcard.", header)
+		}
+	}
+
+	return nil
+}
+
+// Match returns the first rule that allows a cross-origin request from the origin with the method and, for
+// preflight requests, the headers in Access-Control-Request-Headers.
+func (c CORSConfiguration) Match(origin string, method string, headers []string) (CORSRule, bool) {
+	for _, rule := range c.CORSRules {
+		if rule.allowsOrigin(origin) && isOneOf(method, rule.AllowedMethods...) && rule.allowsHeaders(headers) {
+			return rule, true
+		}
+	}
+
+	return CORSRule{}, false
+}
+
+func (r CORSRule) allowsOrigin(origin string) bool {
+	for _, allowed := range r.AllowedOrigins {
+		if WildcardMatch(allowed, origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowsHeaders checks that each header matches an AllowedHeader, ignoring case.
+func (r CORSRule) allowsHeaders(headers []string) bool {
+	for _, header := range headers {
+		allowed := false
+		for _, pattern := range r.AllowedHeaders {
+			if WildcardMatch(strings.ToLower(pattern), strings.ToLower(header)) {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			return false
+		}
+	}
+
+	return true
+}
+
+// AllowsAnyOrigin returns whether the rule allows every origin, so responses don't need to name the origin.
+func (r CORSRule) AllowsAnyOrigin() bool {
+	return isOneOf("*", r.AllowedOrigins...)
+}
@@ -0,0 +1,41 @@
+package domain
+
+import "encoding/xml"
+
+type ServerSideEncryptionConfiguration struct {
+	XMLName xml.Name                   `xml:"ServerSideEncryptionConfiguration"`
+	Rules   []ServerSideEncryptionRule `xml:"Rule"`
+}
+
+type ServerSideEncryptionRule struct {
+	ApplyServerSideEncryptionByDefault *ServerSideEncryptionByDefault `xml:"ApplyServerSideEncryptionByDefault,omitempty"`
+	BucketKeyEnabled                   *bool                          `xml:"BucketKeyEnabled,omitempty"`
+}
+
+type ServerSideEncryptionByDefault struct {
+	SSEAlgorithm   string `xml:"SSEAlgorithm"`
+	KMSMasterKeyID string `xml:"KMSMasterKeyID,omitempty"`
+}
+
+func (e ServerSideEncryptionConfiguration) Validate() error {
+	if len(e.Rules) == 0 {
+		return malformedXML()
+	}
+
+	for _, rule := range e.Rules {
+		byDefault := rule.ApplyServerSideEncryptionByDefault
+		if byDefault == nil {
+			continue
+		}
+
+		if !isOneOf(byDefault.SSEAlgorithm, "AES256", "aws:kms", "aws:kms:dsse") {
+			return malformedXML()
+		}
+
+		if byDefault.KMSMasterKeyID != "" && byDefault.SSEAlgorithm == "AES256" {
+			return invalidArgument("a KMSMasterKeyID is not applicable if the default sse algorithm is not aws:kms or aws:kms:dsse")
+		}
+	}
+
+	return nil
+}
@@ -6,9 +6,11 @@ const (
 )
 
 type NotificationEvent struct {
-	Bucket   string
-	Key      string // S3 Object key
-	Event    string // S3 event (i.e. s3:ObjectCreated", "s3:ObjectRemoved", etc.)
-	SourceIp string
-	Size     int64
+	Bucket    string
+	Key       string // S3 Object key
+	Event     string // S3 event (i.e. s3:ObjectCreated", "s3:ObjectRemoved", etc.)
+	SourceIp  string
+	Size      int64
+	RequestId string // x-amz-request-id of the request that caused the event
+	HostId    string // x-amz-id-2 of the request that caused the event
 }
@@ -1,6 +1,9 @@
 package domain
 
-import "strings"
+import (
+	"strconv"
+	"strings"
+)
 
 const (
 	ObjectCreatedFilter = "s3:ObjectCreated:*"
@@ -34,6 +37,45 @@ type Filter struct {
 	S3Key S3Key
 }
 
+// FilterRuleResult describes the outcome of a single FilterRule against an object key.
+type FilterRuleResult struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	Matched bool   `json:"matched"`
+	Reason  string `json:"reason"`
+}
+
+func (f FilterRule) IsSupported() bool {
+	return f.Name == PrefixFilter || f.Name == SuffixFilter
+}
+
+func (f FilterRule) Explain(key string) FilterRuleResult {
+	result := FilterRuleResult{
+		Name:  f.Name,
+		Value: f.Value,
+	}
+
+	if !f.IsSupported() {
+		result.Reason = "unsupported filter rule name, expected " + PrefixFilter + " or " + SuffixFilter
+		return result
+	}
+
+	result.Matched = f.FilterKey(key)
+
+	verb := "starts"
+	if f.Name == SuffixFilter {
+		verb = "ends"
+	}
+
+	if result.Matched {
+		result.Reason = "key " + verb + " with " + strconv.Quote(f.Value)
+	} else {
+		result.Reason = "key does not " + strings.TrimSuffix(verb, "s") + " with " + strconv.Quote(f.Value)
+	}
+
+	return result
+}
+
 func (f Filter) FilterEvents(i interface{}) bool {
 	event := i.(NotificationEvent)
 
@@ -45,3 +87,21 @@ func (f Filter) FilterEvents(i interface{}) bool {
 
 	return true
 }
+
+// Explain evaluates every FilterRule against the key of the provided event. The returned
+// bool is the same result as FilterEvents, except that unsupported rules never match
+// instead of panicking.
+func (f Filter) Explain(event NotificationEvent) ([]FilterRuleResult, bool) {
+	results := make([]FilterRuleResult, 0, len(f.S3Key.FilterRules))
+	supported := true
+	for _, rule := range f.S3Key.FilterRules {
+		results = append(results, rule.Explain(event.Key))
+		supported = supported && rule.IsSupported()
+	}
+
+	if !supported {
+		return results, false
+	}
+
+	return results, f.FilterEvents(event)
+}
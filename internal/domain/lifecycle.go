@@ -0,0 +1,158 @@
+package domain
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+const maxLifecycleRules = 1000
+
+type LifecycleConfiguration struct {
+	XMLName xml.Name        `xml:"LifecycleConfiguration"`
+	Rules   []LifecycleRule `xml:"Rule"`
+}
+
+type LifecycleRule struct {
+	ID                             string                          `xml:"ID,omitempty"`
+	Prefix                         *string                         `xml:"Prefix,omitempty"` // deprecated in favor of Filter
+	Filter                         *RuleFilter                     `xml:"Filter,omitempty"`
+	Status                         string                          `xml:"Status"`
+	Expiration                     *LifecycleExpiration            `xml:"Expiration,omitempty"`
+	Transitions                    []Transition                    `xml:"Transition"`
+	NoncurrentVersionExpiration    *NoncurrentVersionExpiration    `xml:"NoncurrentVersionExpiration,omitempty"`
+	NoncurrentVersionTransitions   []NoncurrentVersionTransition   `xml:"NoncurrentVersionTransition"`
+	AbortIncompleteMultipartUpload *AbortIncompleteMultipartUpload `xml:"AbortIncompleteMultipartUpload,omitempty"`
+}
+
+type LifecycleExpiration struct {
+	Date                      string `xml:"Date,omitempty"`
+	Days                      int    `xml:"Days,omitempty"`
+	ExpiredObjectDeleteMarker *bool  `xml:"ExpiredObjectDeleteMarker,omitempty"`
+}
+
+type Transition struct {
+	Date         string `xml:"Date,omitempty"`
+	Days         *int   `xml:"Days,omitempty"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type NoncurrentVersionExpiration struct {
+	NoncurrentDays          int `xml:"NoncurrentDays"`
+	NewerNoncurrentVersions int `xml:"NewerNoncurrentVersions,omitempty"`
+}
+
+type NoncurrentVersionTransition struct {
+	NoncurrentDays          int    `xml:"NoncurrentDays"`
+	StorageClass            string `xml:"StorageClass"`
+	NewerNoncurrentVersions int    `xml:"NewerNoncurrentVersions,omitempty"`
+}
+
+type AbortIncompleteMultipartUpload struct {
+	DaysAfterInitiation int `xml:"DaysAfterInitiation"`
+}
+
+func (l LifecycleConfiguration) Validate() error {
+	if len(l.Rules) == 0 {
+		return malformedXML()
+	}
+
+	if len(l.Rules) > maxLifecycleRules {
+		return invalidRequest("The number of lifecycle rules should not exceed allowed limit of 1000 rules")
+	}
+
+	var ids []string
+	for _, rule := range l.Rules {
+		ids = append(ids, rule.ID)
+
+		err := rule.validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return validateRuleIds(ids)
+}
+
+func (r LifecycleRule) validate() error {
+	if !isOneOf(r.Status, "Enabled", "Disabled") {
+		return malformedXML()
+	}
+
+	if r.Prefix != nil && r.Filter != nil {
+		return malformedXML()
+	}
+
+	if r.Filter != nil {
+		err := r.Filter.validate(true)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.Expiration == nil && len(r.Transitions) == 0 && r.NoncurrentVersionExpiration == nil &&
+		len(r.NoncurrentVersionTransitions) == 0 && r.AbortIncompleteMultipartUpload == nil {
+		return invalidRequest("At least one action needs to be specified in a rule")
+	}
+
+	if r.Expiration != nil {
+		err := r.Expiration.validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, transition := range r.Transitions {
+		if (transition.Date == "") == (transition.Days == nil) {
+			return malformedXML()
+		}
+
+		if transition.Days != nil && *transition.Days < 0 {
+			return invalidArgument("'Days' for Transition action must be a positive integer")
+		}
+
+		err := validateMidnight(transition.Date)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.NoncurrentVersionExpiration != nil && r.NoncurrentVersionExpiration.NoncurrentDays <= 0 {
+		return invalidArgument("'NoncurrentDays' for NoncurrentVersionExpiration action must be a positive integer")
+	}
+
+	if r.AbortIncompleteMultipartUpload != nil && r.AbortIncompleteMultipartUpload.DaysAfterInitiation <= 0 {
+		return invalidArgument("'DaysAfterInitiation' for AbortIncompleteMultipartUpload action must be a positive integer")
+	}
+
+	return nil
+}
+
+func (e LifecycleExpiration) validate() error {
+	if countSet(e.Date != "", e.Days != 0, e.ExpiredObjectDeleteMarker != nil) != 1 {
+		return malformedXML()
+	}
+
+	if e.Days < 0 {
+		return invalidArgument("'Days' for Expiration action must be a positive integer")
+	}
+
+	return validateMidnight(e.Date)
+}
+
+// validateMidnight checks that a date of a lifecycle action is in ISO 8601 format at midnight UTC.
+func validateMidnight(date string) error {
+	if date == "" {
+		return nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return invalidArgument("The date '%s' is not a valid ISO 8601 date", date)
+	}
+
+	if !parsed.UTC().Equal(parsed.UTC().Truncate(24 * time.Hour)) {
+		return invalidArgument("'Date' must be at midnight GMT")
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Actions of lifecycle rules that can be applied through the backend. Transitions aren't applied, since the
+// backend only has a single storage class.
+const (
+	ExpireCurrentVersion    = "Expiration"
+	ExpireDeleteMarker      = "ExpiredObjectDeleteMarker"
+	ExpireNoncurrentVersion = "NoncurrentVersionExpiration"
+	AbortUpload             = "AbortIncompleteMultipartUpload"
+)
+
+// LifecycleAction is an action of a lifecycle rule that is due for an object version or multipart upload.
+// Expiring the current version doesn't have a VersionId, so that a delete marker is created if the bucket
+// is versioned.
+type LifecycleAction struct {
+	Bucket    string `json:"bucket"`
+	Rule      string `json:"rule"`
+	Action    string `json:"action"`
+	Key       string `json:"key"`
+	VersionId string `json:"versionId,omitempty"`
+	UploadId  string `json:"uploadId,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (r LifecycleRule) isEnabled() bool {
+	return r.Status == "Enabled"
+}
+
+// ObjectPrefix is the prefix of keys the rule applies to, from either the deprecated Prefix or the Filter.
+func (r LifecycleRule) ObjectPrefix() string {
+	return rulePrefix(r.Prefix, r.Filter)
+}
+
+// Matches returns whether the filter of the rule matches an object version. Every tag of the filter must be
+// on the version, so delete markers never match filters with tags.
+func (r LifecycleRule) Matches(version ObjectVersion) bool {
+	return r.Filter.matches(r.Prefix, version.Key, version.Size, version.Tags)
+}
+
+// NeedsTags returns whether any enabled rule filters on tags, so object tags have to be loaded to evaluate it.
+func (l LifecycleConfiguration) NeedsTags() bool {
+	for _, rule := range l.Rules {
+		if rule.isEnabled() && len(rule.Filter.tags()) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NeedsUploads returns whether any enabled rule aborts incomplete multipart uploads.
+func (l LifecycleConfiguration) NeedsUploads() bool {
+	for _, rule := range l.Rules {
+		if rule.isEnabled() && rule.AbortIncompleteMultipartUpload != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expiresAt is when an action that is due a number of days after start is applied. Like S3, it is rounded
+// up to the following midnight UTC.
+func expiresAt(start time.Time, days int) time.Time {
+	return start.UTC().Truncate(24*time.Hour).AddDate(0, 0, days+1)
+}
+
+func (e LifecycleExpiration) isDue(lastModified time.Time, now time.Time) bool {
+	if e.Days > 0 {
+		return !now.Before(expiresAt(lastModified, e.Days))
+	}
+
+	if e.Date == "" {
+		return false
+	}
+
+	date, err := time.Parse(time.RFC3339, e.Date)
+	return err == nil && !now.Before(date)
+}
+
+// Evaluate returns the actions of enabled rules that are due at now, for versions listed by ListObjectVersions
+// and incomplete multipart uploads. Each version or upload gets at most one action, from the first rule that
+// matches it.
+func (l LifecycleConfiguration) Evaluate(bucket string, versions []ObjectVersion, uploads []MultipartUpload, now time.Time) []LifecycleAction {
+	var actions []LifecycleAction
+
+	for _, key := range groupVersions(versions) {
+		actions = append(actions, l.evaluateKey(bucket, key, now)...)
+	}
+
+	for _, upload := range uploads {
+		for _, rule := range l.Rules {
+			abort := rule.AbortIncompleteMultipartUpload
+			if !rule.isEnabled() || abort == nil || len(rule.Filter.tags()) > 0 || !strings.HasPrefix(upload.Key, rule.ObjectPrefix()) {
+				continue
+			}
+
+			if !now.Before(expiresAt(upload.Initiated, abort.DaysAfterInitiation)) {
+				actions = append(actions, LifecycleAction{Bucket: bucket, Rule: rule.ID, Action: AbortUpload, Key: upload.Key, UploadId: upload.UploadId})
+				break
+			}
+		}
+	}
+
+	return actions
+}
+
+// groupVersions groups versions by key in the order they were listed, with the newest version of each key first.
+func groupVersions(versions []ObjectVersion) [][]ObjectVersion {
+	var keys []string
+	byKey := make(map[string][]ObjectVersion)
+	for _, version := range versions {
+		if _, ok := byKey[version.Key]; !ok {
+			keys = append(keys, version.Key)
+		}
+		byKey[version.Key] = append(byKey[version.Key], version)
+	}
+
+	grouped := make([][]ObjectVersion, 0, len(keys))
+	for _, key := range keys {
+		group := byKey[key]
+		sort.SliceStable(group, func(i, j int) bool {
+			if group[i].IsLatest != group[j].IsLatest {
+				return group[i].IsLatest
+			}
+			return group[i].LastModified.After(group[j].LastModified)
+		})
+		grouped = append(grouped, group)
+	}
+
+	return grouped
+}
+
+func (l LifecycleConfiguration) evaluateKey(bucket string, versions []ObjectVersion, now time.Time) []LifecycleAction {
+	var actions []LifecycleAction
+	remaining := len(versions)
+
+	// noncurrent versions are evaluated first, so that a delete marker left without versions can be expired
+	for i := 1; i < len(versions); i++ {
+		version := versions[i]
+		noncurrentSince := versions[i-1].LastModified
+
+		for _, rule := range l.Rules {
+			expiration := rule.NoncurrentVersionExpiration
+			if !rule.isEnabled() || expiration == nil || i-1 < expiration.NewerNoncurrentVersions || !rule.Matches(version) {
+				continue
+			}
+
+			if !now.Before(expiresAt(noncurrentSince, expiration.NoncurrentDays)) {
+				actions = append(actions, LifecycleAction{Bucket: bucket, Rule: rule.ID, Action: ExpireNoncurrentVersion, Key: version.Key, VersionId: version.VersionId})
+				remaining--
+				break
+			}
+		}
+	}
+
+	current := versions[0]
+	if !current.IsLatest {
+		return actions
+	}
+
+	for _, rule := range l.Rules {
+		expiration := rule.Expiration
+		if !rule.isEnabled() || expiration == nil || !rule.Matches(current) {
+			continue
+		}
+
+		if current.IsDeleteMarker {
+			if expiration.ExpiredObjectDeleteMarker != nil && *expiration.ExpiredObjectDeleteMarker && remaining == 1 {
+				actions = append(actions, LifecycleAction{Bucket: bucket, Rule: rule.ID, Action: ExpireDeleteMarker, Key: current.Key, VersionId: current.VersionId})
+				break
+			}
+			continue
+		}
+
+		if expiration.isDue(current.LastModified, now) {
+			actions = append(actions, LifecycleAction{Bucket: bucket, Rule: rule.ID, Action: ExpireCurrentVersion, Key: current.Key})
+			break
+		}
+	}
+
+	return actions
+}
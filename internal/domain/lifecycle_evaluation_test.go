@@ -0,0 +1,104 @@
+package domain_test
+
+import (
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLifecycleRuleMatches(t *testing.T) {
+	logs := "logs/"
+	greaterThan := int64(10)
+	lessThan := int64(100)
+	rule := domain.LifecycleRule{Filter: &domain.RuleFilter{And: &domain.RuleAnd{
+		Prefix:                &logs,
+		Tags:                  []domain.Tag{{Key: "expire", Value: "true"}},
+		ObjectSizeGreaterThan: &greaterThan,
+		ObjectSizeLessThan:    &lessThan,
+	}}}
+
+	tags := []domain.Tag{{Key: "team", Value: "a"}, {Key: "expire", Value: "true"}}
+	assert.True(t, rule.Matches(domain.ObjectVersion{Key: "logs/a.txt", Size: 50, Tags: tags}))
+	assert.False(t, rule.Matches(domain.ObjectVersion{Key: "data/a.txt", Size: 50, Tags: tags}))
+	assert.False(t, rule.Matches(domain.ObjectVersion{Key: "logs/a.txt", Size: 50}))
+	assert.False(t, rule.Matches(domain.ObjectVersion{Key: "logs/a.txt", Size: 10, Tags: tags}))
+	assert.False(t, rule.Matches(domain.ObjectVersion{Key: "logs/a.txt", Size: 100, Tags: tags}))
+
+	assert.Equal(t, "old/", domain.LifecycleRule{Prefix: stringPointer("old/")}.ObjectPrefix())
+	assert.True(t, domain.LifecycleRule{}.Matches(domain.ObjectVersion{Key: "any"}))
+}
+
+func stringPointer(value string) *string {
+	return &value
+}
+
+func TestLifecycleConfigurationEvaluate(t *testing.T) {
+	enabled := true
+	config := domain.LifecycleConfiguration{Rules: []domain.LifecycleRule{
+		{
+			ID:         "disabled",
+			Status:     "Disabled",
+			Expiration: &domain.LifecycleExpiration{Days: 1},
+		},
+		{
+			ID:                             "tmp",
+			Status:                         "Enabled",
+			Filter:                         &domain.RuleFilter{Prefix: stringPointer("tmp/")},
+			Expiration:                     &domain.LifecycleExpiration{Days: 7},
+			AbortIncompleteMultipartUpload: &domain.AbortIncompleteMultipartUpload{DaysAfterInitiation: 2},
+		},
+		{
+			ID:                          "versions",
+			Status:                      "Enabled",
+			Expiration:                  &domain.LifecycleExpiration{ExpiredObjectDeleteMarker: &enabled},
+			NoncurrentVersionExpiration: &domain.NoncurrentVersionExpiration{NoncurrentDays: 30, NewerNoncurrentVersions: 1},
+		},
+		{
+			ID:         "archive",
+			Status:     "Enabled",
+			Filter:     &domain.RuleFilter{Prefix: stringPointer("archive/")},
+			Expiration: &domain.LifecycleExpiration{Date: "2022-06-01T00:00:00Z"},
+		},
+	}}
+
+	day := func(d int) time.Time {
+		return time.Date(2022, 1, d, 12, 0, 0, 0, time.UTC)
+	}
+
+	versions := []domain.ObjectVersion{
+		{Key: "archive/a.txt", VersionId: "1", IsLatest: true, LastModified: day(1)},
+		{Key: "data/a.txt", VersionId: "4", IsLatest: true, LastModified: day(4)},
+		{Key: "data/a.txt", VersionId: "3", LastModified: day(3)},
+		{Key: "data/a.txt", VersionId: "2", LastModified: day(2)},
+		{Key: "data/a.txt", VersionId: "1", LastModified: day(1)},
+		{Key: "deleted.txt", VersionId: "2", IsLatest: true, IsDeleteMarker: true, LastModified: day(2)},
+		{Key: "deleted.txt", VersionId: "1", LastModified: day(1)},
+		{Key: "gone.txt", VersionId: "1", IsLatest: true, IsDeleteMarker: true, LastModified: day(1)},
+		{Key: "tmp/new.txt", VersionId: "1", IsLatest: true, LastModified: day(20)},
+		{Key: "tmp/old.txt", VersionId: "1", IsLatest: true, LastModified: day(1)},
+	}
+
+	uploads := []domain.MultipartUpload{
+		{Key: "tmp/old.bin", UploadId: "old", Initiated: day(1)},
+		{Key: "tmp/new.bin", UploadId: "new", Initiated: day(24)},
+		{Key: "data/old.bin", UploadId: "other", Initiated: day(1)},
+	}
+
+	actions := config.Evaluate("bucket", versions, uploads, day(25))
+	assert.Equal(t, []domain.LifecycleAction{
+		{Bucket: "bucket", Rule: "versions", Action: domain.ExpireDeleteMarker, Key: "gone.txt", VersionId: "1"},
+		{Bucket: "bucket", Rule: "tmp", Action: domain.ExpireCurrentVersion, Key: "tmp/old.txt"},
+		{Bucket: "bucket", Rule: "tmp", Action: domain.AbortUpload, Key: "tmp/old.bin", UploadId: "old"},
+	}, actions)
+
+	actions = config.Evaluate("bucket", versions, nil, time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, []domain.LifecycleAction{
+		{Bucket: "bucket", Rule: "archive", Action: domain.ExpireCurrentVersion, Key: "archive/a.txt"},
+		{Bucket: "bucket", Rule: "versions", Action: domain.ExpireNoncurrentVersion, Key: "data/a.txt", VersionId: "2"},
+		{Bucket: "bucket", Rule: "versions", Action: domain.ExpireNoncurrentVersion, Key: "data/a.txt", VersionId: "1"},
+		{Bucket: "bucket", Rule: "versions", Action: domain.ExpireDeleteMarker, Key: "gone.txt", VersionId: "1"},
+		{Bucket: "bucket", Rule: "tmp", Action: domain.ExpireCurrentVersion, Key: "tmp/new.txt"},
+		{Bucket: "bucket", Rule: "tmp", Action: domain.ExpireCurrentVersion, Key: "tmp/old.txt"},
+	}, actions)
+}
@@ -0,0 +1,83 @@
+package domain
+
+import "encoding/xml"
+
+const xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+type BucketLoggingStatus struct {
+	XMLName        xml.Name        `xml:"BucketLoggingStatus"`
+	LoggingEnabled *LoggingEnabled `xml:"LoggingEnabled,omitempty"`
+}
+
+type LoggingEnabled struct {
+	TargetBucket          string                 `xml:"TargetBucket"`
+	TargetGrants          []TargetGrant          `xml:"TargetGrants>Grant"`
+	TargetPrefix          string                 `xml:"TargetPrefix"`
+	TargetObjectKeyFormat *TargetObjectKeyFormat `xml:"TargetObjectKeyFormat,omitempty"`
+}
+
+type TargetGrant struct {
+	Grantee    Grantee `xml:"Grantee"`
+	Permission string  `xml:"Permission"`
+}
+
+// Grantee is who a grant is for. The type is the xsi:type attribute, i.e. CanonicalUser.
+type Grantee struct {
+	Type         string `xml:"type,attr,omitempty"`
+	ID           string `xml:"ID,omitempty"`
+	DisplayName  string `xml:"DisplayName,omitempty"`
+	EmailAddress string `xml:"EmailAddress,omitempty"`
+	URI          string `xml:"URI,omitempty"`
+}
+
+// MarshalXML writes the type as xsi:type with the xsi prefix, like S3, which encoding/xml doesn't do for
+// attributes in a namespace.
+func (g Grantee) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = append(start.Attr,
+		xml.Attr{Name: xml.Name{Local: "xmlns:xsi"}, Value: xsiNamespace},
+		xml.Attr{Name: xml.Name{Local: "xsi:type"}, Value: g.Type},
+	)
+
+	type grantee Grantee
+	g.Type = ""
+	return e.EncodeElement(grantee(g), start)
+}
+
+type TargetObjectKeyFormat struct {
+	SimplePrefix      *struct{}          `xml:"SimplePrefix,omitempty"`
+	PartitionedPrefix *PartitionedPrefix `xml:"PartitionedPrefix,omitempty"`
+}
+
+type PartitionedPrefix struct {
+	PartitionDateSource string `xml:"PartitionDateSource,omitempty"`
+}
+
+// Validate allows a document without LoggingEnabled, which is how logging is disabled.
+func (l BucketLoggingStatus) Validate() error {
+	if l.LoggingEnabled == nil {
+		return nil
+	}
+
+	if l.LoggingEnabled.TargetBucket == "" {
+		return malformedXML()
+	}
+
+	for _, grant := range l.LoggingEnabled.TargetGrants {
+		if !isOneOf(grant.Grantee.Type, "CanonicalUser", "AmazonCustomerByEmail", "Group") ||
+			!isOneOf(grant.Permission, "FULL_CONTROL", "READ", "WRITE") {
+			return malformedXML()
+		}
+	}
+
+	if format := l.LoggingEnabled.TargetObjectKeyFormat; format != nil {
+		if countSet(format.SimplePrefix != nil, format.PartitionedPrefix != nil) != 1 {
+			return malformedXML()
+		}
+
+		if format.PartitionedPrefix != nil && !isOneOf(format.PartitionedPrefix.PartitionDateSource, "", "EventTime", "DeliveryTime") {
+			return malformedXML()
+		}
+	}
+
+	return nil
+}
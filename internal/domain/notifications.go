@@ -33,7 +33,7 @@ func (c CloudFunctionConfiguration) FilterEvents(i interface{}) bool {
 	event := i.(NotificationEvent)
 
 	for _, filter := range c.Events {
-		if strings.HasPrefix(filter, event.Event) {
+		if matchesEvent(filter, event.Event) {
 			return true
 		}
 	}
@@ -41,10 +41,66 @@ func (c CloudFunctionConfiguration) FilterEvents(i interface{}) bool {
 	return false
 }
 
+func matchesEvent(filter string, event string) bool {
+	return strings.HasPrefix(filter, event)
+}
+
+// EventMatch describes whether a single configured Event matches the name of an event.
+type EventMatch struct {
+	Event   string `json:"event"`
+	Matched bool   `json:"matched"`
+}
+
+// MatchExplanation describes why a CloudFunctionConfiguration would, or would not, be invoked
+// for an event.
+type MatchExplanation struct {
+	Id            string             `json:"id"`
+	CloudFunction CloudFunction      `json:"cloudFunction"`
+	Matched       bool               `json:"matched"`
+	EventMatched  bool               `json:"eventMatched"`
+	Events        []EventMatch       `json:"events"`
+	FilterMatched bool               `json:"filterMatched"`
+	FilterRules   []FilterRuleResult `json:"filterRules"`
+}
+
+func (c CloudFunctionConfiguration) Explain(event NotificationEvent) MatchExplanation {
+	events := make([]EventMatch, 0, len(c.Events))
+	for _, filter := range c.Events {
+		events = append(events, EventMatch{
+			Event:   filter,
+			Matched: matchesEvent(filter, event.Event),
+		})
+	}
+
+	eventMatched := c.FilterEvents(event)
+	rules, filterMatched := c.Filter.Explain(event)
+
+	return MatchExplanation{
+		Id:            c.Id,
+		CloudFunction: c.CloudFunction,
+		Matched:       eventMatched && filterMatched,
+		EventMatched:  eventMatched,
+		Events:        events,
+		FilterMatched: filterMatched,
+		FilterRules:   rules,
+	}
+}
+
 type NotificationConfiguration struct {
 	CloudFunctionConfigurations []CloudFunctionConfiguration `xml:"CloudFunctionConfiguration"`
 }
 
+// Explain describes, for each CloudFunctionConfiguration, whether the event would be sent to it.
+// Nothing is invoked.
+func (n NotificationConfiguration) Explain(event NotificationEvent) []MatchExplanation {
+	results := make([]MatchExplanation, 0, len(n.CloudFunctionConfigurations))
+	for _, config := range n.CloudFunctionConfigurations {
+		results = append(results, config.Explain(event))
+	}
+
+	return results
+}
+
 type EventFunction func(string, interface{})
 
 func (n NotificationConfiguration) Start(invoker CloudFunctionInvoker) (chan rxgo.Item, context.Context) {
@@ -178,3 +178,43 @@ func TestTwoNotificationCloudFunctionConfigurations(t *testing.T) {
 	deletes, _ := c.keys.Load("delete")
 	assert.Equal(t, []string{"file3.bin", "file4.bin"}, deletes)
 }
+
+func TestExplainNotificationConfiguration(t *testing.T) {
+	var notification domain.NotificationConfiguration
+	err := xml.Unmarshal([]byte(notificationExample), &notification)
+	if err != nil {
+		t.Fatalf("Unable to unmarshall: %v", err)
+	}
+
+	matched := notification.Explain(domain.NotificationEvent{Event: domain.ObjectCreatedEvent, Key: "AWSLogs/test.log"})
+	assert.Len(t, matched, 1)
+	assert.True(t, matched[0].Matched)
+	assert.Equal(t, "tf-s3-lambda-20220407133353589300000001", matched[0].Id)
+	assert.Equal(t, []domain.EventMatch{
+		{Event: "s3:ObjectRemoved:*", Matched: false},
+		{Event: "s3:ObjectCreated:*", Matched: true},
+	}, matched[0].Events)
+
+	explanation := notification.Explain(domain.NotificationEvent{Event: domain.ObjectCreatedEvent, Key: "AWSLogs/test.txt"})[0]
+	assert.False(t, explanation.Matched)
+	assert.True(t, explanation.EventMatched)
+	assert.False(t, explanation.FilterMatched)
+	assert.True(t, explanation.FilterRules[0].Matched)
+	assert.False(t, explanation.FilterRules[1].Matched)
+}
+
+func TestExplainUnsupportedFilterRule(t *testing.T) {
+	config := domain.CloudFunctionConfiguration{
+		Events: []string{domain.ObjectCreatedFilter},
+		Filter: domain.Filter{
+			S3Key: domain.S3Key{
+				FilterRules: []domain.FilterRule{{Name: "Prefix", Value: "AWSLogs/"}},
+			},
+		},
+	}
+
+	explanation := config.Explain(domain.NotificationEvent{Event: domain.ObjectCreatedEvent, Key: "AWSLogs/test.log"})
+	assert.False(t, explanation.Matched)
+	assert.False(t, explanation.FilterRules[0].Matched)
+	assert.Contains(t, explanation.FilterRules[0].Reason, "unsupported")
+}
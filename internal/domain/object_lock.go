@@ -0,0 +1,44 @@
+package domain
+
+import "encoding/xml"
+
+type ObjectLockConfiguration struct {
+	XMLName           xml.Name        `xml:"ObjectLockConfiguration"`
+	ObjectLockEnabled string          `xml:"ObjectLockEnabled,omitempty"`
+	Rule              *ObjectLockRule `xml:"Rule,omitempty"`
+}
+
+type ObjectLockRule struct {
+	DefaultRetention DefaultRetention `xml:"DefaultRetention"`
+}
+
+type DefaultRetention struct {
+	Mode  string `xml:"Mode"`
+	Days  int    `xml:"Days,omitempty"`
+	Years int    `xml:"Years,omitempty"`
+}
+
+func (o ObjectLockConfiguration) Validate() error {
+	if o.ObjectLockEnabled != "Enabled" {
+		return malformedXML()
+	}
+
+	if o.Rule == nil {
+		return nil
+	}
+
+	retention := o.Rule.DefaultRetention
+	if !isOneOf(retention.Mode, "GOVERNANCE", "COMPLIANCE") {
+		return malformedXML()
+	}
+
+	if (retention.Days == 0) == (retention.Years == 0) {
+		return malformedXML()
+	}
+
+	if retention.Days < 0 || retention.Years < 0 {
+		return invalidArgument("Default retention period must be a positive integer value.")
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ObjectStore is how background work, like lifecycle rules, reads and changes objects in the backend.
+type ObjectStore interface {
+	ListObjectVersions(ctx context.Context, bucket string, prefix string) ([]ObjectVersion, error)
+	ListMultipartUploads(ctx context.Context, bucket string, prefix string) ([]MultipartUpload, error)
+	GetObjectTagging(ctx context.Context, bucket string, key string, versionId string) ([]Tag, error)
+	PutObject(ctx context.Context, bucket string, key string, body []byte) error
+	CopyObject(ctx context.Context, bucket string, key string, versionId string, destination string, storageClass string) (string, error)
+	DeleteObject(ctx context.Context, bucket string, key string, versionId string) error
+	AbortMultipartUpload(ctx context.Context, bucket string, key string, uploadId string) error
+}
+
+// ObjectVersion is a version of an object or a delete marker, as listed by ListObjectVersions. Tags are only
+// loaded when they are needed.
+type ObjectVersion struct {
+	Key            string
+	VersionId      string
+	IsLatest       bool
+	IsDeleteMarker bool
+	LastModified   time.Time
+	Size           int64
+	Tags           []Tag
+}
+
+type MultipartUpload struct {
+	Key       string
+	UploadId  string
+	Initiated time.Time
+}
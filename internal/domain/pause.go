@@ -0,0 +1,27 @@
+package domain
+
+type PauseMode string
+
+const (
+	PauseBuffer PauseMode = "buffer" // hold events until notifications are resumed
+	PauseDrop   PauseMode = "drop"   // discard events while notifications are paused
+)
+
+func (m PauseMode) IsValid() bool {
+	return m == PauseBuffer || m == PauseDrop
+}
+
+type ConfigurationPauseStatus struct {
+	Id       string    `json:"id"`
+	Paused   bool      `json:"paused"`
+	Mode     PauseMode `json:"mode,omitempty"`
+	Buffered int       `json:"buffered"`
+}
+
+type PauseStatus struct {
+	Bucket         string                     `json:"bucket"`
+	Paused         bool                       `json:"paused"`
+	Mode           PauseMode                  `json:"mode,omitempty"`
+	Buffered       int                        `json:"buffered"`
+	Configurations []ConfigurationPauseStatus `json:"configurations"`
+}
@@ -0,0 +1,319 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"strings"
+)
+
+const maxPolicySize = 20 * 1024
+
+// StringOrSlice is a policy element that can be a single string or a list of them. Like S3, a single value
+// is written as a string.
+type StringOrSlice []string
+
+func (s *StringOrSlice) UnmarshalJSON(data []byte) error {
+	var single string
+	if json.Unmarshal(data, &single) == nil {
+		*s = StringOrSlice{single}
+		return nil
+	}
+
+	var multiple []string
+	err := json.Unmarshal(data, &multiple)
+	if err != nil {
+		return err
+	}
+
+	*s = multiple
+	return nil
+}
+
+func (s StringOrSlice) MarshalJSON() ([]byte, error) {
+	if len(s) == 1 {
+		return json.Marshal(s[0])
+	}
+
+	return json.Marshal([]string(s))
+}
+
+// Principal is either the wildcard "*" or a map of principal types (AWS, Service, ...) to their ids.
+type Principal struct {
+	Wildcard bool
+	Ids      map[string]StringOrSlice
+}
+
+func (p *Principal) UnmarshalJSON(data []byte) error {
+	var wildcard string
+	if json.Unmarshal(data, &wildcard) == nil {
+		if wildcard != "*" {
+			return malformedPolicy("Invalid principal in policy")
+		}
+
+		p.Wildcard = true
+		return nil
+	}
+
+	return json.Unmarshal(data, &p.Ids)
+}
+
+func (p Principal) MarshalJSON() ([]byte, error) {
+	if p.Wildcard {
+		return json.Marshal("*")
+	}
+
+	return json.Marshal(p.Ids)
+}
+
+// IsAnonymous returns whether the principal includes everyone.
+func (p Principal) IsAnonymous() bool {
+	if p.Wildcard {
+		return true
+	}
+
+	for _, id := range p.Ids["AWS"] {
+		if id == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PolicyConditions map condition operators, like StringEquals, to condition keys and their values.
+type PolicyConditions map[string]map[string]StringOrSlice
+
+type PolicyStatement struct {
+	Sid          string           `json:"Sid,omitempty"`
+	Effect       string           `json:"Effect"`
+	Principal    *Principal       `json:"Principal,omitempty"`
+	NotPrincipal *Principal       `json:"NotPrincipal,omitempty"`
+	Action       StringOrSlice    `json:"Action,omitempty"`
+	NotAction    StringOrSlice    `json:"NotAction,omitempty"`
+	Resource     StringOrSlice    `json:"Resource,omitempty"`
+	NotResource  StringOrSlice    `json:"NotResource,omitempty"`
+	Condition    PolicyConditions `json:"Condition,omitempty"`
+}
+
+type BucketPolicy struct {
+	Version   string            `json:"Version,omitempty"`
+	Id        string            `json:"Id,omitempty"`
+	Statement []PolicyStatement `json:"Statement"`
+}
+
+func malformedPolicy(message string) ValidationError {
+	return ValidationError{Code: "MalformedPolicy", Message: message}
+}
+
+// ParseBucketPolicy decodes a stored or received bucket policy without validating it.
+func ParseBucketPolicy(payload []byte) (BucketPolicy, error) {
+	var policy BucketPolicy
+
+	trimmed := bytes.TrimSpace(payload)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return policy, malformedPolicy("Policies must be valid JSON and the first byte must be '{'")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(&policy)
+	if err != nil {
+		var validationErr ValidationError
+		if errors.As(err, &validationErr) {
+			return policy, validationErr
+		}
+
+		return policy, malformedPolicy("Policies must be valid JSON: " + err.Error())
+	}
+
+	return policy, nil
+}
+
+// NormalizeBucketPolicy decodes and validates the policy of a bucket, then encodes it again compactly.
+func NormalizeBucketPolicy(payload []byte, bucket string) ([]byte, error) {
+	if len(payload) > maxPolicySize {
+		return nil, ValidationError{Code: "PolicyTooLarge", Message: "Policies must be less than 20 KB"}
+	}
+
+	policy, err := ParseBucketPolicy(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	err = policy.Validate(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(policy)
+}
+
+func (p BucketPolicy) Validate(bucket string) error {
+	if p.Version != "" && !isOneOf(p.Version, "2012-10-17", "2008-10-17") {
+		return malformedPolicy("Invalid policy version " + p.Version)
+	}
+
+	if len(p.Statement) == 0 {
+		return malformedPolicy("Missing required field Statement")
+	}
+
+	sids := make(map[string]bool)
+	for _, statement := range p.Statement {
+		if statement.Sid != "" && sids[statement.Sid] {
+			return malformedPolicy("Statement IDs (SID) in a single policy must be unique")
+		}
+
+		sids[statement.Sid] = true
+
+		err := statement.validate(bucket)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s PolicyStatement) validate(bucket string) error {
+	if !isOneOf(s.Effect, "Allow", "Deny") {
+		return malformedPolicy("Invalid effect: " + s.Effect)
+	}
+
+	if s.Principal == nil && s.NotPrincipal == nil {
+		return malformedPolicy("Missing required field Principal")
+	}
+
+	if s.Principal != nil && s.NotPrincipal != nil {
+		return malformedPolicy("Statement can't have both Principal and NotPrincipal")
+	}
+
+	if (len(s.Action) == 0) == (len(s.NotAction) == 0) {
+		return malformedPolicy("Missing required field Action")
+	}
+
+	for _, action := range append(append([]string{}, s.Action...), s.NotAction...) {
+		if action != "*" && !strings.HasPrefix(action, "s3:") {
+			return malformedPolicy("Policy has invalid action")
+		}
+	}
+
+	if (len(s.Resource) == 0) == (len(s.NotResource) == 0) {
+		return malformedPolicy("Missing required field Resource")
+	}
+
+	for _, resource := range append(append([]string{}, s.Resource...), s.NotResource...) {
+		if resource == "*" {
+			continue
+		}
+
+		if !strings.HasPrefix(resource, bucketArnPrefix) {
+			return malformedPolicy("Policy has invalid resource")
+		}
+
+		name := strings.SplitN(strings.TrimPrefix(resource, bucketArnPrefix), "/", 2)[0]
+		if !WildcardMatch(name, bucket) {
+			return malformedPolicy("Action does not apply to any resource(s) in statement")
+		}
+	}
+
+	return nil
+}
+
+// restrictingConditionKeys limit a statement to specific accounts, networks or sources, so a statement
+// using them doesn't make a bucket public even if its principal is "*".
+var restrictingConditionKeys = []string{
+	"aws:PrincipalAccount",
+	"aws:PrincipalArn",
+	"aws:PrincipalOrgID",
+	"aws:SourceAccount",
+	"aws:SourceArn",
+	"aws:SourceIp",
+	"aws:SourceOwner",
+	"aws:SourceVpc",
+	"aws:SourceVpce",
+	"aws:userid",
+	"s3:DataAccessPointAccount",
+	"s3:DataAccessPointArn",
+}
+
+func (s PolicyStatement) isRestricted() bool {
+	for _, keys := range s.Condition {
+		for key, values := range keys {
+			for _, restricting := range restrictingConditionKeys {
+				if strings.EqualFold(key, restricting) && !containsWildcard(values) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func containsWildcard(values []string) bool {
+	for _, value := range values {
+		if value == "*" || value == "0.0.0.0/0" || value == "::/0" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsPublic returns whether a statement allows access to anyone, as reported by GetBucketPolicyStatus.
+func (p BucketPolicy) IsPublic() bool {
+	for _, statement := range p.Statement {
+		if statement.Effect == "Allow" && statement.includesAnyone() && !statement.isRestricted() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// includesAnyone returns whether the principal of a statement is anyone, or NotPrincipal excludes only some.
+func (s PolicyStatement) includesAnyone() bool {
+	if s.NotPrincipal != nil {
+		return !s.NotPrincipal.IsAnonymous()
+	}
+
+	return s.Principal != nil && s.Principal.IsAnonymous()
+}
+
+// WildcardMatch matches a value against a pattern where * matches any sequence of characters and ? matches
+// a single character, as in policy resources and conditions. When a character doesn't match, only the last *
+// is retried with one more character, so matching takes at most len(pattern) * len(value) steps.
+func WildcardMatch(pattern string, value string) bool {
+	p, v := 0, 0
+	star, starValue := -1, 0
+	for v < len(value) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == value[v]):
+			p++
+			v++
+		case p < len(pattern) && pattern[p] == '*':
+			star, starValue = p, v
+			p++
+		case star >= 0:
+			starValue++
+			p, v = star+1, starValue
+		default:
+			return false
+		}
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+
+	return p == len(pattern)
+}
+
+// PolicyStatus is returned by GetBucketPolicyStatus.
+type PolicyStatus struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ PolicyStatus"`
+	IsPublic bool     `xml:"IsPublic"`
+}
@@ -0,0 +1,213 @@
+package domain
+
+import (
+	"net"
+	"strings"
+)
+
+// PolicyRequest is what a bucket policy is evaluated against: the S3 action and resource of a request, who
+// made it and the values of condition keys like aws:SourceIp.
+type PolicyRequest struct {
+	Action     string
+	Resource   string
+	Account    string
+	AccessKey  string // empty for anonymous requests
+	Conditions map[string]string
+}
+
+func (r PolicyRequest) IsAnonymous() bool {
+	return r.AccessKey == ""
+}
+
+type PolicyDecision int
+
+const (
+	// PolicyNotApplicable means that no statement matched the request
+	PolicyNotApplicable PolicyDecision = iota
+	PolicyAllowed
+	PolicyDenied
+)
+
+func (d PolicyDecision) String() string {
+	switch d {
+	case PolicyAllowed:
+		return "Allow"
+	case PolicyDenied:
+		return "Deny"
+	default:
+		return "NotApplicable"
+	}
+}
+
+// Evaluate returns the decision of the policy for the request, along with the statement that made it. Like
+// in AWS, an explicit Deny overrides any Allow.
+func (p BucketPolicy) Evaluate(request PolicyRequest) (PolicyDecision, PolicyStatement) {
+	decision := PolicyNotApplicable
+	var decidedBy PolicyStatement
+
+	for _, statement := range p.Statement {
+		if !statement.matches(request) {
+			continue
+		}
+
+		if statement.Effect == "Deny" {
+			return PolicyDenied, statement
+		}
+
+		if decision == PolicyNotApplicable {
+			decision, decidedBy = PolicyAllowed, statement
+		}
+	}
+
+	return decision, decidedBy
+}
+
+func (s PolicyStatement) matches(request PolicyRequest) bool {
+	switch {
+	case s.Principal != nil && !s.Principal.matches(request):
+		return false
+	case s.NotPrincipal != nil && s.NotPrincipal.matches(request):
+		return false
+	case s.Principal == nil && s.NotPrincipal == nil:
+		return false
+	}
+
+	if len(s.Action) > 0 && !matchesAny(s.Action, request.Action, true) {
+		return false
+	}
+
+	if len(s.NotAction) > 0 && matchesAny(s.NotAction, request.Action, true) {
+		return false
+	}
+
+	if len(s.Resource) > 0 && !matchesAny(s.Resource, request.Resource, false) {
+		return false
+	}
+
+	if len(s.NotResource) > 0 && matchesAny(s.NotResource, request.Resource, false) {
+		return false
+	}
+
+	for operator, keys := range s.Condition {
+		for key, values := range keys {
+			if !evaluateCondition(operator, request.Conditions, key, values) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// matches returns whether the principal includes the caller, identified by the account number, the account's
+// root ARN or the ARN of a user named after the access key. Anonymous callers only match "*".
+func (p Principal) matches(request PolicyRequest) bool {
+	if p.IsAnonymous() {
+		return true
+	}
+
+	if request.IsAnonymous() {
+		return false
+	}
+
+	callers := []string{
+		request.Account,
+		"arn:aws:iam::" + request.Account + ":root",
+		"arn:aws:iam::" + request.Account + ":user/" + request.AccessKey,
+	}
+
+	for _, id := range p.Ids["AWS"] {
+		for _, caller := range callers {
+			if WildcardMatch(id, caller) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func matchesAny(patterns []string, value string, ignoreCase bool) bool {
+	for _, pattern := range patterns {
+		if ignoreCase && WildcardMatch(strings.ToLower(pattern), strings.ToLower(value)) {
+			return true
+		}
+
+		if !ignoreCase && WildcardMatch(pattern, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// evaluateCondition checks a single condition key. Values of a key are ORed, except for negated operators
+// where the value must not match any of them. Operators that aren't supported never match.
+func evaluateCondition(operator string, conditions map[string]string, key string, values []string) bool {
+	value, exists := lookupCondition(conditions, key)
+	if strings.HasSuffix(operator, "IfExists") {
+		if !exists {
+			return true
+		}
+
+		operator = strings.TrimSuffix(operator, "IfExists")
+	}
+
+	if operator == "Null" {
+		return len(values) > 0 && strings.EqualFold(values[0], "true") != exists
+	}
+
+	negated := strings.Contains(operator, "Not")
+	if !exists {
+		return negated
+	}
+
+	var match func(pattern string) bool
+	switch operator {
+	case "StringEquals", "StringNotEquals":
+		match = func(pattern string) bool { return pattern == value }
+	case "StringEqualsIgnoreCase", "StringNotEqualsIgnoreCase":
+		match = func(pattern string) bool { return strings.EqualFold(pattern, value) }
+	case "StringLike", "StringNotLike":
+		match = func(pattern string) bool { return WildcardMatch(pattern, value) }
+	case "Bool":
+		match = func(pattern string) bool { return strings.EqualFold(pattern, value) }
+	case "IpAddress", "NotIpAddress":
+		match = func(pattern string) bool { return ipMatches(pattern, value) }
+	default:
+		return false
+	}
+
+	for _, pattern := range values {
+		if match(pattern) {
+			return !negated
+		}
+	}
+
+	return negated
+}
+
+// lookupCondition finds the value of a condition key, whose names are case-insensitive.
+func lookupCondition(conditions map[string]string, key string) (string, bool) {
+	for name, value := range conditions {
+		if strings.EqualFold(name, key) {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+func ipMatches(cidr string, address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	if !strings.Contains(cidr, "/") {
+		return ip.Equal(net.ParseIP(cidr))
+	}
+
+	_, network, err := net.ParseCIDR(cidr)
+	return err == nil && network.Contains(ip)
+}
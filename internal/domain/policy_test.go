@@ -0,0 +1,180 @@
+package domain_test
+
+import (
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeBucketPolicy(t *testing.T) {
+	payload := `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Sid": "PublicRead",
+      "Effect": "Allow",
+      "Principal": {"AWS": ["*"]},
+      "Action": ["s3:GetObject"],
+      "Resource": "arn:aws:s3:::bucket/*"
+    }
+  ]
+}`
+
+	normalized, err := domain.NormalizeBucketPolicy([]byte(payload), "bucket")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, `{"Version":"2012-10-17","Statement":[{"Sid":"PublicRead","Effect":"Allow",`+
+		`"Principal":{"AWS":"*"},"Action":"s3:GetObject","Resource":"arn:aws:s3:::bucket/*"}]}`, string(normalized))
+}
+
+func TestValidateBucketPolicy(t *testing.T) {
+	statement := func(fields string) string {
+		return `{"Version":"2012-10-17","Statement":[{` + fields + `}]}`
+	}
+
+	tests := []struct {
+		name   string
+		policy string
+		code   string
+	}{
+		{"not json", `<Policy/>`, "MalformedPolicy"},
+		{"unknown field", `{"Statement":[],"Other":1}`, "MalformedPolicy"},
+		{"bad version", `{"Version":"2020-01-01","Statement":[]}`, "MalformedPolicy"},
+		{"no statements", `{"Version":"2012-10-17","Statement":[]}`, "MalformedPolicy"},
+		{"bad effect", statement(`"Effect":"Maybe","Principal":"*","Action":"s3:*","Resource":"arn:aws:s3:::bucket"`), "MalformedPolicy"},
+		{"no principal", statement(`"Effect":"Allow","Action":"s3:*","Resource":"arn:aws:s3:::bucket"`), "MalformedPolicy"},
+		{"principal and not principal", statement(`"Effect":"Deny","Principal":"*","NotPrincipal":{"AWS":"123456789012"},"Action":"s3:*","Resource":"arn:aws:s3:::bucket"`), "MalformedPolicy"},
+		{"not principal", statement(`"Effect":"Deny","NotPrincipal":{"AWS":"123456789012"},"Action":"s3:*","Resource":"arn:aws:s3:::bucket"`), ""},
+		{"bad principal", statement(`"Effect":"Allow","Principal":"me","Action":"s3:*","Resource":"arn:aws:s3:::bucket"`), "MalformedPolicy"},
+		{"no action", statement(`"Effect":"Allow","Principal":"*","Resource":"arn:aws:s3:::bucket"`), "MalformedPolicy"},
+		{"other service", statement(`"Effect":"Allow","Principal":"*","Action":"sqs:*","Resource":"arn:aws:s3:::bucket"`), "MalformedPolicy"},
+		{"no resource", statement(`"Effect":"Allow","Principal":"*","Action":"s3:*"`), "MalformedPolicy"},
+		{"other bucket", statement(`"Effect":"Allow","Principal":"*","Action":"s3:*","Resource":"arn:aws:s3:::other/*"`), "MalformedPolicy"},
+		{"too large", `{"Id":"` + strings.Repeat("x", 20*1024) + `"}`, "PolicyTooLarge"},
+		{"duplicate sid", `{"Statement":[` +
+			`{"Sid":"a","Effect":"Allow","Principal":"*","Action":"s3:*","Resource":"*"},` +
+			`{"Sid":"a","Effect":"Deny","Principal":"*","Action":"s3:*","Resource":"*"}]}`, "MalformedPolicy"},
+		{"valid", statement(`"Effect":"Deny","Principal":{"AWS":"arn:aws:iam::123456789012:root"},` +
+			`"NotAction":["s3:GetObject","s3:ListBucket"],"Resource":["arn:aws:s3:::buck*","arn:aws:s3:::bucket/*"]`), ""},
+	}
+
+	for _, test := range tests {
+		_, err := domain.NormalizeBucketPolicy([]byte(test.policy), "bucket")
+		if test.code == "" {
+			assert.NoError(t, err, test.name)
+			continue
+		}
+
+		var validationErr domain.ValidationError
+		if assert.ErrorAs(t, err, &validationErr, test.name) {
+			assert.Equal(t, test.code, validationErr.Code, test.name)
+		}
+	}
+}
+
+func TestBucketPolicyIsPublic(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement string
+		public    bool
+	}{
+		{"anyone", `"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"*"`, true},
+		{"any AWS account", `"Effect":"Allow","Principal":{"AWS":"*"},"Action":"s3:GetObject","Resource":"*"`, true},
+		{"deny", `"Effect":"Deny","Principal":"*","Action":"s3:GetObject","Resource":"*"`, false},
+		{"anyone but an account", `"Effect":"Allow","NotPrincipal":{"AWS":"123456789012"},"Action":"s3:GetObject","Resource":"*"`, true},
+		{"single account", `"Effect":"Allow","Principal":{"AWS":"123456789012"},"Action":"s3:GetObject","Resource":"*"`, false},
+		{"source ip", `"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"*",` +
+			`"Condition":{"IpAddress":{"aws:SourceIp":"10.0.0.0/8"}}`, false},
+		{"any source ip", `"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"*",` +
+			`"Condition":{"IpAddress":{"aws:SourceIp":"0.0.0.0/0"}}`, true},
+		{"secure transport", `"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"*",` +
+			`"Condition":{"Bool":{"aws:SecureTransport":"true"}}`, true},
+	}
+
+	for _, test := range tests {
+		policy, err := domain.ParseBucketPolicy([]byte(`{"Statement":[{` + test.statement + `}]}`))
+		if assert.NoError(t, err, test.name) {
+			assert.Equal(t, test.public, policy.IsPublic(), test.name)
+		}
+	}
+}
+
+func TestWildcardMatch(t *testing.T) {
+	assert.True(t, domain.WildcardMatch("*", ""))
+	assert.True(t, domain.WildcardMatch("bucket/*", "bucket/a/b.txt"))
+	assert.True(t, domain.WildcardMatch("b?cket*", "bucket"))
+	assert.True(t, domain.WildcardMatch("*.txt", "a/b.txt"))
+	assert.False(t, domain.WildcardMatch("bucket", "bucket2"))
+	assert.False(t, domain.WildcardMatch("b?cket", "bcket"))
+	assert.False(t, domain.WildcardMatch("*.txt", "a/b.txt.gz"))
+	assert.True(t, domain.WildcardMatch("a*b*c", "aXbYbZc"))
+	assert.False(t, domain.WildcardMatch("a*b?c", "abc"))
+	assert.True(t, domain.WildcardMatch("**", "abc"))
+}
+
+func TestWildcardMatchPathological(t *testing.T) {
+	pattern := strings.Repeat("a*", 30) + "b"
+	value := strings.Repeat("a", 1000)
+
+	start := time.Now()
+	assert.False(t, domain.WildcardMatch(pattern, value))
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestBucketPolicyEvaluate(t *testing.T) {
+	policy, err := domain.ParseBucketPolicy([]byte(`{"Statement": [
+		{"Sid": "Read", "Effect": "Allow", "Principal": {"AWS": "271828182845"}, "Action": "s3:Get*", "Resource": "arn:aws:s3:::bucket/*"},
+		{"Sid": "Office", "Effect": "Deny", "Principal": "*", "Action": "s3:*", "Resource": "arn:aws:s3:::bucket/*",
+			"Condition": {"NotIpAddress": {"aws:SourceIp": ["10.0.0.0/8", "192.168.1.1"]}}},
+		{"Sid": "Logs", "Effect": "Deny", "Principal": "*", "NotAction": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/logs/*"},
+		{"Sid": "Anyone", "Effect": "Allow", "Principal": "*", "Action": "s3:ListBucket", "Resource": "arn:aws:s3:::bucket",
+			"Condition": {"StringEqualsIfExists": {"s3:prefix": "public/"}}},
+		{"Sid": "Others", "Effect": "Deny", "NotPrincipal": {"AWS": "arn:aws:iam::271828182845:root"}, "Action": "s3:DeleteObject",
+			"Resource": "arn:aws:s3:::bucket/*"}
+	]}`))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	request := func(accessKey string, action string, resource string, conditions map[string]string) domain.PolicyRequest {
+		return domain.PolicyRequest{
+			Action:     action,
+			Resource:   "arn:aws:s3:::" + resource,
+			Account:    "271828182845",
+			AccessKey:  accessKey,
+			Conditions: conditions,
+		}
+	}
+
+	office := map[string]string{"aws:SourceIp": "10.1.2.3"}
+	tests := []struct {
+		name     string
+		request  domain.PolicyRequest
+		decision domain.PolicyDecision
+		sid      string
+	}{
+		{"account reads", request("key", "s3:GetObject", "bucket/a.txt", office), domain.PolicyAllowed, "Read"},
+		{"actions ignore case", request("key", "S3:getobject", "bucket/a.txt", office), domain.PolicyAllowed, "Read"},
+		{"anonymous reads", request("", "s3:GetObject", "bucket/a.txt", office), domain.PolicyNotApplicable, ""},
+		{"outside office", request("key", "s3:GetObject", "bucket/a.txt", map[string]string{"aws:SourceIp": "8.8.8.8"}), domain.PolicyDenied, "Office"},
+		{"single address", request("key", "s3:GetObject", "bucket/a.txt", map[string]string{"aws:SourceIp": "192.168.1.1"}), domain.PolicyAllowed, "Read"},
+		{"no source ip", request("key", "s3:GetObject", "bucket/a.txt", nil), domain.PolicyDenied, "Office"},
+		{"write logs", request("key", "s3:PutObject", "bucket/logs/a.txt", office), domain.PolicyDenied, "Logs"},
+		{"write elsewhere", request("key", "s3:PutObject", "bucket/a.txt", office), domain.PolicyNotApplicable, ""},
+		{"list public", request("", "s3:ListBucket", "bucket", map[string]string{"s3:prefix": "public/"}), domain.PolicyAllowed, "Anyone"},
+		{"list without prefix", request("", "s3:ListBucket", "bucket", nil), domain.PolicyAllowed, "Anyone"},
+		{"account deletes", request("key", "s3:DeleteObject", "bucket/a.txt", office), domain.PolicyNotApplicable, ""},
+		{"anonymous deletes", request("", "s3:DeleteObject", "bucket/a.txt", office), domain.PolicyDenied, "Others"},
+		{"list private", request("", "s3:ListBucket", "bucket", map[string]string{"s3:prefix": "private/"}), domain.PolicyNotApplicable, ""},
+	}
+
+	for _, test := range tests {
+		decision, statement := policy.Evaluate(test.request)
+		assert.Equal(t, test.decision, decision, test.name)
+		assert.Equal(t, test.sid, statement.Sid, test.name)
+	}
+}
@@ -0,0 +1,288 @@
+package domain
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+const maxReplicationRules = 1000
+
+type ReplicationConfiguration struct {
+	XMLName xml.Name          `xml:"ReplicationConfiguration"`
+	Role    string            `xml:"Role"`
+	Rules   []ReplicationRule `xml:"Rule"`
+}
+
+type ReplicationRule struct {
+	ID                        string                     `xml:"ID,omitempty"`
+	Priority                  *int                       `xml:"Priority,omitempty"`
+	Status                    string                     `xml:"Status"`
+	Prefix                    *string                    `xml:"Prefix,omitempty"` // deprecated in favor of Filter
+	Filter                    *RuleFilter                `xml:"Filter,omitempty"`
+	SourceSelectionCriteria   *SourceSelectionCriteria   `xml:"SourceSelectionCriteria,omitempty"`
+	ExistingObjectReplication *ExistingObjectReplication `xml:"ExistingObjectReplication,omitempty"`
+	Destination               ReplicationDestination     `xml:"Destination"`
+	DeleteMarkerReplication   *DeleteMarkerReplication   `xml:"DeleteMarkerReplication,omitempty"`
+}
+
+type ReplicationDestination struct {
+	Bucket                   string                    `xml:"Bucket"`
+	Account                  string                    `xml:"Account,omitempty"`
+	StorageClass             string                    `xml:"StorageClass,omitempty"`
+	AccessControlTranslation *AccessControlTranslation `xml:"AccessControlTranslation,omitempty"`
+	EncryptionConfiguration  *ReplicaEncryption        `xml:"EncryptionConfiguration,omitempty"`
+	ReplicationTime          *ReplicationTime          `xml:"ReplicationTime,omitempty"`
+	Metrics                  *ReplicationMetrics       `xml:"Metrics,omitempty"`
+}
+
+type DeleteMarkerReplication struct {
+	Status string `xml:"Status"`
+}
+
+type SourceSelectionCriteria struct {
+	ReplicaModifications   *ReplicationStatus `xml:"ReplicaModifications,omitempty"`
+	SseKmsEncryptedObjects *ReplicationStatus `xml:"SseKmsEncryptedObjects,omitempty"`
+}
+
+type ExistingObjectReplication struct {
+	Status string `xml:"Status"`
+}
+
+// ReplicationStatus enables or disables an optional feature of a replication rule.
+type ReplicationStatus struct {
+	Status string `xml:"Status"`
+}
+
+type AccessControlTranslation struct {
+	Owner string `xml:"Owner"`
+}
+
+type ReplicaEncryption struct {
+	ReplicaKmsKeyID string `xml:"ReplicaKmsKeyID,omitempty"`
+}
+
+type ReplicationTime struct {
+	Status string              `xml:"Status"`
+	Time   *ReplicationMinutes `xml:"Time,omitempty"`
+}
+
+type ReplicationMetrics struct {
+	Status         string              `xml:"Status"`
+	EventThreshold *ReplicationMinutes `xml:"EventThreshold,omitempty"`
+}
+
+type ReplicationMinutes struct {
+	Minutes int `xml:"Minutes"`
+}
+
+const bucketArnPrefix = "arn:aws:s3:::"
+
+// BucketName returns the name of the destination bucket from its ARN.
+func (d ReplicationDestination) BucketName() string {
+	return strings.TrimPrefix(d.Bucket, bucketArnPrefix)
+}
+
+func (r ReplicationConfiguration) Validate() error {
+	if r.Role == "" || len(r.Rules) == 0 {
+		return malformedXML()
+	}
+
+	if len(r.Rules) > maxReplicationRules {
+		return invalidRequest("The number of replication rules should not exceed allowed limit of 1000 rules")
+	}
+
+	var ids []string
+	priorities := make(map[int]bool)
+	for _, rule := range r.Rules {
+		ids = append(ids, rule.ID)
+
+		err := rule.validate()
+		if err != nil {
+			return err
+		}
+
+		if rule.Priority != nil {
+			if priorities[*rule.Priority] {
+				return invalidRequest("Found duplicate priority %d", *rule.Priority)
+			}
+
+			priorities[*rule.Priority] = true
+		}
+	}
+
+	return validateRuleIds(ids)
+}
+
+func (r ReplicationRule) validate() error {
+	if !isOneOf(r.Status, "Enabled", "Disabled") {
+		return malformedXML()
+	}
+
+	if r.Prefix != nil && r.Filter != nil {
+		return malformedXML()
+	}
+
+	destination := r.Destination.Bucket
+	if !strings.HasPrefix(destination, bucketArnPrefix) || len(destination) == len(bucketArnPrefix) {
+		return invalidArgument("Invalid bucket ARN %s", destination)
+	}
+
+	var statuses []string
+	if r.DeleteMarkerReplication != nil {
+		statuses = append(statuses, r.DeleteMarkerReplication.Status)
+	}
+
+	if r.ExistingObjectReplication != nil {
+		statuses = append(statuses, r.ExistingObjectReplication.Status)
+	}
+
+	if criteria := r.SourceSelectionCriteria; criteria != nil {
+		for _, status := range []*ReplicationStatus{criteria.ReplicaModifications, criteria.SseKmsEncryptedObjects} {
+			if status != nil {
+				statuses = append(statuses, status.Status)
+			}
+		}
+	}
+
+	for _, status := range statuses {
+		if !isOneOf(status, "Enabled", "Disabled") {
+			return malformedXML()
+		}
+	}
+
+	err := r.Destination.validate()
+	if err != nil {
+		return err
+	}
+
+	if r.Filter != nil {
+		return r.Filter.validate(false)
+	}
+
+	return nil
+}
+
+func (d ReplicationDestination) validate() error {
+	if d.AccessControlTranslation != nil && d.AccessControlTranslation.Owner != "Destination" {
+		return malformedXML()
+	}
+
+	// S3 only supports replication within 15 minutes
+	if d.ReplicationTime != nil {
+		if !isOneOf(d.ReplicationTime.Status, "Enabled", "Disabled") || d.ReplicationTime.Time == nil || d.ReplicationTime.Time.Minutes != 15 {
+			return malformedXML()
+		}
+	}
+
+	if d.Metrics != nil {
+		if !isOneOf(d.Metrics.Status, "Enabled", "Disabled") {
+			return malformedXML()
+		}
+
+		if d.Metrics.EventThreshold != nil && d.Metrics.EventThreshold.Minutes != 15 {
+			return malformedXML()
+		}
+	}
+
+	return nil
+}
+
+// Replication statuses reported with x-amz-replication-status. Source objects are PENDING until they've been
+// copied to every destination, and copies are REPLICA.
+const (
+	ReplicationPending   = "PENDING"
+	ReplicationCompleted = "COMPLETED"
+	ReplicationFailed    = "FAILED"
+	ReplicationReplica   = "REPLICA"
+)
+
+// ObjectReplication is the replication status of an object version, and the storage class of a replica.
+type ObjectReplication struct {
+	Status       string
+	StorageClass string
+}
+
+// ObjectPrefix is the prefix of keys the rule applies to, from either the deprecated Prefix or the Filter.
+func (r ReplicationRule) ObjectPrefix() string {
+	return rulePrefix(r.Prefix, r.Filter)
+}
+
+// Matches returns whether the rule is enabled and its filter matches an object with the tags.
+func (r ReplicationRule) Matches(key string, tags []Tag) bool {
+	return r.Status == "Enabled" && r.Filter.matches(r.Prefix, key, 0, tags)
+}
+
+// ReplicatesDeleteMarkers returns whether delete markers are replicated by the rule. Like S3, rules with the
+// deprecated Prefix always replicate them, while rules with a Filter need DeleteMarkerReplication, and rules
+// filtering on tags never do.
+func (r ReplicationRule) ReplicatesDeleteMarkers() bool {
+	if r.Filter == nil {
+		return true
+	}
+
+	return r.DeleteMarkerReplication != nil && r.DeleteMarkerReplication.Status == "Enabled" && len(r.Filter.tags()) == 0
+}
+
+func (r ReplicationRule) priority() int {
+	if r.Priority == nil {
+		return 0
+	}
+
+	return *r.Priority
+}
+
+// NeedsTags returns whether any enabled rule filters on tags, so object tags have to be loaded to match it.
+func (r ReplicationConfiguration) NeedsTags() bool {
+	for _, rule := range r.Rules {
+		if rule.Status == "Enabled" && len(rule.Filter.tags()) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MayReplicate returns whether an object could be replicated by an enabled rule, before its tags are known.
+func (r ReplicationConfiguration) MayReplicate(key string) bool {
+	for _, rule := range r.Rules {
+		if rule.Status == "Enabled" && strings.HasPrefix(key, rule.ObjectPrefix()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Destinations returns where an object, or a delete marker, is replicated to. When several matching rules have
+// the same destination bucket, the rule with the highest priority is used.
+func (r ReplicationConfiguration) Destinations(key string, tags []Tag, deleteMarker bool) []ReplicationDestination {
+	var buckets []string
+	chosen := make(map[string]ReplicationRule)
+	for _, rule := range r.Rules {
+		if !rule.Matches(key, tags) {
+			continue
+		}
+
+		bucket := rule.Destination.BucketName()
+		previous, ok := chosen[bucket]
+		if !ok {
+			buckets = append(buckets, bucket)
+		}
+
+		if !ok || rule.priority() > previous.priority() {
+			chosen[bucket] = rule
+		}
+	}
+
+	var destinations []ReplicationDestination
+	for _, bucket := range buckets {
+		rule := chosen[bucket]
+		if deleteMarker && !rule.ReplicatesDeleteMarkers() {
+			continue
+		}
+
+		destinations = append(destinations, rule.Destination)
+	}
+
+	return destinations
+}
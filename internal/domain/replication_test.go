@@ -0,0 +1,77 @@
+package domain_test
+
+import (
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestReplicationConfigurationDestinations(t *testing.T) {
+	low, high := 1, 2
+	config := domain.ReplicationConfiguration{Rules: []domain.ReplicationRule{
+		{
+			ID:          "all",
+			Priority:    &low,
+			Status:      "Enabled",
+			Filter:      &domain.RuleFilter{Prefix: stringPointer("")},
+			Destination: domain.ReplicationDestination{Bucket: "arn:aws:s3:::backup"},
+		},
+		{
+			ID:                      "docs",
+			Priority:                &high,
+			Status:                  "Enabled",
+			Filter:                  &domain.RuleFilter{Prefix: stringPointer("docs/")},
+			Destination:             domain.ReplicationDestination{Bucket: "arn:aws:s3:::backup", StorageClass: "STANDARD_IA"},
+			DeleteMarkerReplication: &domain.DeleteMarkerReplication{Status: "Enabled"},
+		},
+		{
+			ID:          "tagged",
+			Status:      "Enabled",
+			Filter:      &domain.RuleFilter{Tag: &domain.Tag{Key: "replicate", Value: "true"}},
+			Destination: domain.ReplicationDestination{Bucket: "arn:aws:s3:::tagged"},
+		},
+		{
+			ID:          "disabled",
+			Status:      "Disabled",
+			Prefix:      stringPointer(""),
+			Destination: domain.ReplicationDestination{Bucket: "arn:aws:s3:::disabled"},
+		},
+	}}
+
+	tags := []domain.Tag{{Key: "replicate", Value: "true"}}
+	assert.True(t, config.NeedsTags())
+
+	assert.Equal(t, []domain.ReplicationDestination{{Bucket: "arn:aws:s3:::backup"}},
+		config.Destinations("a.txt", nil, false))
+	assert.Equal(t, []domain.ReplicationDestination{{Bucket: "arn:aws:s3:::backup", StorageClass: "STANDARD_IA"}},
+		config.Destinations("docs/a.txt", nil, false))
+	assert.Equal(t, []domain.ReplicationDestination{{Bucket: "arn:aws:s3:::backup"}, {Bucket: "arn:aws:s3:::tagged"}},
+		config.Destinations("a.txt", tags, false))
+
+	assert.Empty(t, config.Destinations("a.txt", nil, true))
+	assert.Equal(t, []domain.ReplicationDestination{{Bucket: "arn:aws:s3:::backup", StorageClass: "STANDARD_IA"}},
+		config.Destinations("docs/a.txt", nil, true))
+
+	assert.Equal(t, "backup", config.Rules[0].Destination.BucketName())
+}
+
+func TestReplicationConfigurationMayReplicate(t *testing.T) {
+	config := domain.ReplicationConfiguration{Rules: []domain.ReplicationRule{
+		{
+			ID:          "docs",
+			Status:      "Enabled",
+			Filter:      &domain.RuleFilter{And: &domain.RuleAnd{Prefix: stringPointer("docs/"), Tags: []domain.Tag{{Key: "replicate", Value: "true"}}}},
+			Destination: domain.ReplicationDestination{Bucket: "arn:aws:s3:::backup"},
+		},
+		{
+			ID:          "disabled",
+			Status:      "Disabled",
+			Prefix:      stringPointer(""),
+			Destination: domain.ReplicationDestination{Bucket: "arn:aws:s3:::disabled"},
+		},
+	}}
+
+	// tags aren't known yet, so only the prefix is matched
+	assert.True(t, config.MayReplicate("docs/a.txt"))
+	assert.False(t, config.MayReplicate("a.txt"))
+}
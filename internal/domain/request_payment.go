@@ -0,0 +1,16 @@
+package domain
+
+import "encoding/xml"
+
+type RequestPaymentConfiguration struct {
+	XMLName xml.Name `xml:"RequestPaymentConfiguration"`
+	Payer   string   `xml:"Payer"`
+}
+
+func (r RequestPaymentConfiguration) Validate() error {
+	if !isOneOf(r.Payer, "BucketOwner", "Requester") {
+		return malformedXML()
+	}
+
+	return nil
+}
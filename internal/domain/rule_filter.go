@@ -0,0 +1,108 @@
+package domain
+
+import "strings"
+
+// RuleFilter selects the objects a lifecycle or replication rule applies to. It has at most one of its
+// conditions, with And combining several. Object sizes can only be used in lifecycle rules.
+type RuleFilter struct {
+	Prefix                *string  `xml:"Prefix,omitempty"`
+	Tag                   *Tag     `xml:"Tag,omitempty"`
+	ObjectSizeGreaterThan *int64   `xml:"ObjectSizeGreaterThan,omitempty"`
+	ObjectSizeLessThan    *int64   `xml:"ObjectSizeLessThan,omitempty"`
+	And                   *RuleAnd `xml:"And,omitempty"`
+}
+
+type RuleAnd struct {
+	Prefix                *string `xml:"Prefix,omitempty"`
+	Tags                  []Tag   `xml:"Tag"`
+	ObjectSizeGreaterThan *int64  `xml:"ObjectSizeGreaterThan,omitempty"`
+	ObjectSizeLessThan    *int64  `xml:"ObjectSizeLessThan,omitempty"`
+}
+
+func (f RuleFilter) validate(sizes bool) error {
+	if countSet(f.Prefix != nil, f.Tag != nil, f.ObjectSizeGreaterThan != nil, f.ObjectSizeLessThan != nil, f.And != nil) > 1 {
+		return malformedXML()
+	}
+
+	greaterThan, lessThan := f.sizeLimits()
+	if !sizes && (greaterThan != nil || lessThan != nil) {
+		return malformedXML()
+	}
+
+	return validateTags(f.tags())
+}
+
+// rulePrefix is the prefix of keys a rule applies to, from either its deprecated Prefix or its Filter.
+func rulePrefix(prefix *string, f *RuleFilter) string {
+	switch {
+	case prefix != nil:
+		return *prefix
+	case f == nil:
+		return ""
+	case f.Prefix != nil:
+		return *f.Prefix
+	case f.And != nil && f.And.Prefix != nil:
+		return *f.And.Prefix
+	default:
+		return ""
+	}
+}
+
+func (f *RuleFilter) tags() []Tag {
+	switch {
+	case f == nil:
+		return nil
+	case f.Tag != nil:
+		return []Tag{*f.Tag}
+	case f.And != nil:
+		return f.And.Tags
+	default:
+		return nil
+	}
+}
+
+func (f *RuleFilter) sizeLimits() (*int64, *int64) {
+	switch {
+	case f == nil:
+		return nil, nil
+	case f.And != nil:
+		return f.And.ObjectSizeGreaterThan, f.And.ObjectSizeLessThan
+	default:
+		return f.ObjectSizeGreaterThan, f.ObjectSizeLessThan
+	}
+}
+
+// matches returns whether an object is selected by the prefix and filter of a rule. Every tag of the filter
+// must be on the object.
+func (f *RuleFilter) matches(prefix *string, key string, size int64, tags []Tag) bool {
+	if !strings.HasPrefix(key, rulePrefix(prefix, f)) {
+		return false
+	}
+
+	for _, tag := range f.tags() {
+		if !hasTag(tags, tag) {
+			return false
+		}
+	}
+
+	greaterThan, lessThan := f.sizeLimits()
+	if greaterThan != nil && size <= *greaterThan {
+		return false
+	}
+
+	if lessThan != nil && size >= *lessThan {
+		return false
+	}
+
+	return true
+}
+
+func hasTag(tags []Tag, tag Tag) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
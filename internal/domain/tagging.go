@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"encoding/xml"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxBucketTags = 50
+
+type Tag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+type Tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	TagSet  []Tag    `xml:"TagSet>Tag"`
+}
+
+func invalidTag(message string) ValidationError {
+	return ValidationError{Code: "InvalidTag", Message: message}
+}
+
+func (t Tagging) Validate() error {
+	if len(t.TagSet) > maxBucketTags {
+		return invalidTag("Bucket tag count cannot be greater than 50")
+	}
+
+	return validateTags(t.TagSet)
+}
+
+// validateTags checks the limits on tags shared by tagging, lifecycle and replication configuration.
+func validateTags(tags []Tag) error {
+	keys := make(map[string]bool)
+	for _, tag := range tags {
+		if tag.Key == "" || utf8.RuneCountInString(tag.Key) > 128 {
+			return invalidTag("The TagKey you have provided is invalid")
+		}
+
+		if utf8.RuneCountInString(tag.Value) > 256 {
+			return invalidTag("The TagValue you have provided is invalid")
+		}
+
+		if strings.HasPrefix(tag.Key, "aws:") {
+			return invalidTag("System tags cannot be added/updated by requester")
+		}
+
+		if keys[tag.Key] {
+			return invalidTag("Cannot provide multiple Tags with the same key")
+		}
+
+		keys[tag.Key] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "encoding/xml"
+
+type VersioningConfiguration struct {
+	XMLName   xml.Name `xml:"VersioningConfiguration"`
+	Status    string   `xml:"Status,omitempty"`
+	MfaDelete string   `xml:"MfaDelete,omitempty"`
+}
+
+func (v VersioningConfiguration) Validate() error {
+	if !isOneOf(v.Status, "Enabled", "Suspended") {
+		return malformedXML()
+	}
+
+	if v.MfaDelete != "" && !isOneOf(v.MfaDelete, "Enabled", "Disabled") {
+		return malformedXML()
+	}
+
+	return nil
+}
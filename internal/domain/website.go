@@ -0,0 +1,174 @@
+package domain
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+const maxRoutingRules = 50
+
+type WebsiteConfiguration struct {
+	XMLName               xml.Name               `xml:"WebsiteConfiguration"`
+	IndexDocument         *IndexDocument         `xml:"IndexDocument,omitempty"`
+	ErrorDocument         *ErrorDocument         `xml:"ErrorDocument,omitempty"`
+	RedirectAllRequestsTo *RedirectAllRequestsTo `xml:"RedirectAllRequestsTo,omitempty"`
+	RoutingRules          []RoutingRule          `xml:"RoutingRules>RoutingRule"`
+}
+
+type IndexDocument struct {
+	Suffix string `xml:"Suffix"`
+}
+
+type ErrorDocument struct {
+	Key string `xml:"Key"`
+}
+
+type RedirectAllRequestsTo struct {
+	HostName string `xml:"HostName"`
+	Protocol string `xml:"Protocol,omitempty"`
+}
+
+type RoutingRule struct {
+	Condition *RoutingRuleCondition `xml:"Condition,omitempty"`
+	Redirect  Redirect              `xml:"Redirect"`
+}
+
+type RoutingRuleCondition struct {
+	HttpErrorCodeReturnedEquals string `xml:"HttpErrorCodeReturnedEquals,omitempty"`
+	KeyPrefixEquals             string `xml:"KeyPrefixEquals,omitempty"`
+}
+
+type Redirect struct {
+	HostName             string `xml:"HostName,omitempty"`
+	HttpRedirectCode     string `xml:"HttpRedirectCode,omitempty"`
+	Protocol             string `xml:"Protocol,omitempty"`
+	ReplaceKeyPrefixWith string `xml:"ReplaceKeyPrefixWith,omitempty"`
+	ReplaceKeyWith       string `xml:"ReplaceKeyWith,omitempty"`
+}
+
+func (w WebsiteConfiguration) Validate() error {
+	if w.RedirectAllRequestsTo != nil {
+		if w.IndexDocument != nil || w.ErrorDocument != nil || len(w.RoutingRules) > 0 {
+			return invalidRequest("RedirectAllRequestsTo cannot be provided in conjunction with other Routing Rules.")
+		}
+
+		if w.RedirectAllRequestsTo.HostName == "" {
+			return malformedXML()
+		}
+
+		return validateProtocol(w.RedirectAllRequestsTo.Protocol)
+	}
+
+	if w.IndexDocument == nil {
+		return invalidArgument("A value for IndexDocument Suffix must be provided if RedirectAllRequestsTo is empty")
+	}
+
+	if w.IndexDocument.Suffix == "" || strings.Contains(w.IndexDocument.Suffix, "/") {
+		return invalidArgument("The IndexDocument Suffix is not well formed")
+	}
+
+	if w.ErrorDocument != nil && w.ErrorDocument.Key == "" {
+		return invalidArgument("The ErrorDocument Key is not well formed")
+	}
+
+	if len(w.RoutingRules) > maxRoutingRules {
+		return invalidArgument("The number of routing rules should not exceed allowed limit of 50 rules")
+	}
+
+	for _, rule := range w.RoutingRules {
+		err := rule.validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r RoutingRule) validate() error {
+	redirect := r.Redirect
+	if redirect.ReplaceKeyPrefixWith != "" && redirect.ReplaceKeyWith != "" {
+		return invalidRequest("You can only define ReplaceKeyPrefix or ReplaceKey but not both.")
+	}
+
+	if redirect.HttpRedirectCode != "" && !strings.HasPrefix(redirect.HttpRedirectCode, "3") {
+		return invalidRequest("The provided HTTP redirect code (%s) is not valid. Valid codes are 3XX except 300.", redirect.HttpRedirectCode)
+	}
+
+	if r.Condition != nil && r.Condition.HttpErrorCodeReturnedEquals != "" {
+		code := r.Condition.HttpErrorCodeReturnedEquals
+		if !strings.HasPrefix(code, "4") && !strings.HasPrefix(code, "5") {
+			return invalidRequest("The provided HTTP error code (%s) is not valid. Valid codes are 4XX or 5XX.", code)
+		}
+	}
+
+	return validateProtocol(redirect.Protocol)
+}
+
+func validateProtocol(protocol string) error {
+	if protocol != "" && !isOneOf(protocol, "http", "https") {
+		return invalidRequest("Invalid protocol, protocol can be http or https. If not defined the protocol will be selected automatically.")
+	}
+
+	return nil
+}
+
+// IndexKey returns the key of the index document for requests of a folder, i.e. docs/index.html for docs/.
+func (w WebsiteConfiguration) IndexKey(key string) string {
+	if w.IndexDocument == nil || (key != "" && !strings.HasSuffix(key, "/")) {
+		return key
+	}
+
+	return key + w.IndexDocument.Suffix
+}
+
+// MatchRoutingRule returns the first routing rule whose condition matches the key and the HTTP error code
+// returned for it. Before the object is requested, the code is 0 and only rules without an error code
+// condition can match.
+func (w WebsiteConfiguration) MatchRoutingRule(key string, code int) (RoutingRule, bool) {
+	for _, rule := range w.RoutingRules {
+		var condition RoutingRuleCondition
+		if rule.Condition != nil {
+			condition = *rule.Condition
+		}
+
+		if !strings.HasPrefix(key, condition.KeyPrefixEquals) {
+			continue
+		}
+
+		if (code == 0 && condition.HttpErrorCodeReturnedEquals == "") ||
+			(code != 0 && condition.HttpErrorCodeReturnedEquals == strconv.Itoa(code)) {
+			return rule, true
+		}
+	}
+
+	return RoutingRule{}, false
+}
+
+// RedirectKey returns the key that a request for key is redirected to by the rule.
+func (r RoutingRule) RedirectKey(key string) string {
+	switch {
+	case r.Redirect.ReplaceKeyWith != "":
+		return r.Redirect.ReplaceKeyWith
+	case r.Redirect.ReplaceKeyPrefixWith != "":
+		var prefix string
+		if r.Condition != nil {
+			prefix = r.Condition.KeyPrefixEquals
+		}
+
+		return r.Redirect.ReplaceKeyPrefixWith + strings.TrimPrefix(key, prefix)
+	default:
+		return key
+	}
+}
+
+// RedirectCode returns the HTTP status used for redirects of the rule, which is 301 unless it is set.
+func (r RoutingRule) RedirectCode() int {
+	code, err := strconv.Atoi(r.Redirect.HttpRedirectCode)
+	if err != nil {
+		return 301
+	}
+
+	return code
+}
@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// elementTree is the tree of elements that a type decodes from XML. Elements of a document that aren't in the
+// tree would be silently dropped by xml.Unmarshal.
+type elementTree struct {
+	children map[string]*elementTree
+	anything bool // the type decodes any content itself
+}
+
+var (
+	elementTrees     = make(map[reflect.Type]*elementTree)
+	elementTreesLock = &sync.Mutex{}
+	unmarshalerType  = reflect.TypeOf((*xml.Unmarshaler)(nil)).Elem()
+)
+
+// checkKnownElements returns MalformedXML if the document has elements that config doesn't model, so that
+// normalizing it doesn't lose anything the client sent.
+func checkKnownElements(payload []byte, config interface{}) error {
+	elementTreesLock.Lock()
+	root := treeOf(reflect.TypeOf(config))
+	elementTreesLock.Unlock()
+
+	decoder := xml.NewDecoder(bytes.NewReader(payload))
+	var stack []*elementTree
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			if len(stack) == 0 {
+				stack = append(stack, root)
+				continue
+			}
+
+			parent := stack[len(stack)-1]
+			if parent.anything {
+				stack = append(stack, parent)
+				continue
+			}
+
+			child, ok := parent.children[token.Name.Local]
+			if !ok {
+				return malformedXML()
+			}
+			stack = append(stack, child)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+// treeOf returns the element tree of a type, building it from the xml tags of its fields the first time.
+func treeOf(t reflect.Type) *elementTree {
+	for t.Kind() == reflect.Ptr || (t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8) {
+		t = t.Elem()
+	}
+
+	if tree, ok := elementTrees[t]; ok {
+		return tree
+	}
+
+	tree := &elementTree{children: make(map[string]*elementTree)}
+	elementTrees[t] = tree
+
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		tree.anything = true
+		return tree
+	}
+
+	if t.Kind() == reflect.Struct {
+		addFields(tree, t)
+	}
+
+	return tree
+}
+
+func addFields(tree *elementTree, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Name == "XMLName" || (field.PkgPath != "" && !field.Anonymous) {
+			continue
+		}
+
+		tag := field.Tag.Get("xml")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		options := strings.TrimPrefix(tag, name)
+		if strings.Contains(options, ",innerxml") || (strings.Contains(options, ",any") && !strings.Contains(options, ",attr")) {
+			tree.anything = true
+			continue
+		}
+
+		if strings.Contains(options, ",attr") || strings.Contains(options, ",chardata") ||
+			strings.Contains(options, ",comment") {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+
+			if embedded.Kind() == reflect.Struct {
+				addFields(tree, embedded)
+				continue
+			}
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		// a>b>c nests c in elements a and b
+		parent := tree
+		path := strings.Split(name, ">")
+		for _, element := range path[:len(path)-1] {
+			child, ok := parent.children[element]
+			if !ok {
+				child = &elementTree{children: make(map[string]*elementTree)}
+				parent.children[element] = child
+			}
+			parent = child
+		}
+
+		parent.children[path[len(path)-1]] = treeOf(field.Type)
+	}
+}
@@ -0,0 +1,251 @@
+package http
+
+import (
+	"crypto/tls"
+	"encoding/xml"
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/ATenderholt/rainbow-storage/internal/sigv4"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// accessLogResources are the resources in the operation of an access log record, i.e. REST.PUT.PART, for
+// operations that aren't named after their subresource.
+var accessLogResources = map[string]string{
+	"AbortMultipartUpload":    "UPLOAD",
+	"CompleteMultipartUpload": "UPLOAD",
+	"CreateMultipartUpload":   "UPLOADS",
+	"DeleteObjects":           "MULTI_OBJECT_DELETE",
+	"GetBucketLogging":        "LOGGING_STATUS",
+	"GetBucketPolicy":         "BUCKETPOLICY",
+	"GetBucketPolicyStatus":   "BUCKETPOLICYSTATUS",
+	"GetObjectTagging":        "OBJECT_TAGGING",
+	"DeleteBucketPolicy":      "BUCKETPOLICY",
+	"DeleteObjectTagging":     "OBJECT_TAGGING",
+	"ListObjectVersions":      "BUCKETVERSIONS",
+	"ListParts":               "UPLOAD",
+	"PutBucketLogging":        "LOGGING_STATUS",
+	"PutBucketPolicy":         "BUCKETPOLICY",
+	"PutObjectTagging":        "OBJECT_TAGGING",
+	"UploadPart":              "PART",
+}
+
+var tlsVersions = map[uint16]string{
+	tls.VersionTLS10: "TLSv1",
+	tls.VersionTLS11: "TLSv1.1",
+	tls.VersionTLS12: "TLSv1.2",
+	tls.VersionTLS13: "TLSv1.3",
+}
+
+// accessLogWriter keeps what is needed for an access log record while the response is written.
+type accessLogWriter struct {
+	http.ResponseWriter
+	status     int
+	bytesSent  int64
+	firstByte  time.Time
+	errorBody  limitedBuffer
+	statusSent bool
+}
+
+func (w *accessLogWriter) WriteHeader(code int) {
+	if !w.statusSent {
+		w.statusSent = true
+		w.status = code
+		w.firstByte = time.Now()
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *accessLogWriter) Write(p []byte) (int, error) {
+	if !w.statusSent {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	n, err := w.ResponseWriter.Write(p)
+	w.bytesSent += int64(n)
+	if w.status >= http.StatusBadRequest {
+		_, _ = w.errorBody.Write(p[:n])
+	}
+
+	return n, err
+}
+
+// Flush sends what has been written so far to the client, for handlers that stream responses.
+func (w *accessLogWriter) Flush() {
+	if !w.statusSent {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// ReadFrom lets io.Copy use the ReaderFrom of the underlying writer, unless the body is an error that is kept.
+func (w *accessLogWriter) ReadFrom(src io.Reader) (int64, error) {
+	if !w.statusSent {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if readerFrom, ok := w.ResponseWriter.(io.ReaderFrom); ok && w.status < http.StatusBadRequest {
+		n, err := readerFrom.ReadFrom(src)
+		w.bytesSent += n
+		return n, err
+	}
+
+	// hide ReadFrom, so that io.Copy doesn't call it again
+	return io.Copy(struct{ io.Writer }{w}, src)
+}
+
+// errorCode returns the code of the S3 error written in the response, if there was one.
+func (w *accessLogWriter) errorCode() string {
+	if w.status < http.StatusBadRequest {
+		return ""
+	}
+
+	var s3Err S3Error
+	if xml.Unmarshal(w.errorBody.Bytes(), &s3Err) != nil {
+		return ""
+	}
+
+	return s3Err.Code
+}
+
+// AccessLog records requests to buckets for server access logging, including those to website endpoints. The
+// service only keeps records of buckets with logging enabled. Like S3, signatures are recorded as sent, whether or
+// not they are valid.
+func (h MinioHandler) AccessLog(next http.Handler) http.Handler {
+	if h.accessLogService == nil {
+		return next
+	}
+
+	f := func(w http.ResponseWriter, request *http.Request) {
+		operation := getOperation(request)
+		if operation.Bucket == "" {
+			next.ServeHTTP(w, request)
+			return
+		}
+
+		start := time.Now()
+		writer := &accessLogWriter{ResponseWriter: w, status: http.StatusOK, errorBody: limitedBuffer{max: maxLoggedResponse}}
+		next.ServeHTTP(writer, request)
+
+		h.accessLogService.Log(h.accessLogRecord(request, operation, writer, start))
+	}
+
+	return http.HandlerFunc(f)
+}
+
+func (h MinioHandler) accessLogRecord(request *http.Request, operation Operation, w *accessLogWriter, start time.Time) domain.AccessLogRecord {
+	original := originalRequest(request)
+	requestId, hostId := getRequestIds(request)
+
+	record := domain.AccessLogRecord{
+		BucketOwner: defaultOwnerId,
+		Bucket:      operation.Bucket,
+		Time:        start,
+		RemoteIp:    request.RemoteAddr,
+		RequestId:   requestId,
+		Operation:   accessLogOperation(request.Method, operation),
+		Key:         sigv4.EscapePath(operation.Key),
+		RequestUri:  request.Method + " " + original.URL.RequestURI() + " " + request.Proto,
+		Status:      w.status,
+		ErrorCode:   w.errorCode(),
+		BytesSent:   w.bytesSent,
+		ObjectSize:  objectSize(request, operation, w.Header()),
+		TotalTime:   time.Since(start),
+		Referer:     request.Referer(),
+		UserAgent:   request.UserAgent(),
+		VersionId:   w.Header().Get("X-Amz-Version-Id"),
+		HostId:      hostId,
+		HostHeader:  request.Host,
+	}
+
+	// website endpoints only serve objects, even for the index document of the root
+	if _, ok := h.websiteBucket(request); ok {
+		record.Operation = "WEBSITE." + request.Method + ".OBJECT"
+	}
+
+	if !w.firstByte.IsZero() {
+		record.TurnAroundTime = w.firstByte.Sub(start)
+	}
+
+	if record.VersionId == "" {
+		record.VersionId = request.URL.Query().Get("versionId")
+	}
+
+	if host, _, err := net.SplitHostPort(request.RemoteAddr); err == nil {
+		record.RemoteIp = host
+	}
+
+	if accessKey := callerAccessKey(request); accessKey != "" {
+		record.Requester = "arn:aws:iam::" + h.cfg.AccountNumber + ":user/" + accessKey
+	}
+
+	if sigv4.IsSigned(original) {
+		record.SignatureVersion = "SigV4"
+		record.AuthType = "AuthHeader"
+		if sigv4.IsPresigned(original) {
+			record.AuthType = "QueryString"
+		}
+	}
+
+	if request.TLS != nil {
+		record.CipherSuite = tls.CipherSuiteName(request.TLS.CipherSuite)
+		record.TlsVersion = tlsVersions[request.TLS.Version]
+	}
+
+	return record
+}
+
+// accessLogOperation names the operation like S3 access logs do, i.e. REST.GET.OBJECT or REST.PUT.ACL.
+func accessLogOperation(method string, operation Operation) string {
+	resource, ok := accessLogResources[operation.Name]
+	switch {
+	case ok:
+	case method == http.MethodOptions:
+		resource = "PREFLIGHT"
+	case operation.Name == "CopyObject":
+		method, resource = "COPY", "OBJECT"
+	case operation.Name == "UploadPartCopy":
+		method, resource = "COPY", "PART"
+	case operation.Subresource != "":
+		resource = strings.ToUpper(strings.ReplaceAll(operation.Subresource, "-", "_"))
+	case operation.Key != "":
+		resource = "OBJECT"
+	default:
+		resource = "BUCKET"
+	}
+
+	return "REST." + method + "." + resource
+}
+
+// objectSize returns the total size of the object that was read or written, or -1 for other operations.
+func objectSize(request *http.Request, operation Operation, header http.Header) int64 {
+	switch operation.Name {
+	case "GetObject", "HeadObject":
+		if contentRange := header.Get("Content-Range"); contentRange != "" {
+			total := contentRange[strings.LastIndex(contentRange, "/")+1:]
+			if size, err := strconv.ParseInt(total, 10, 64); err == nil {
+				return size
+			}
+		}
+
+		if size, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil {
+			return size
+		}
+	case "PutObject", "UploadPart":
+		if size, err := strconv.ParseInt(request.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64); err == nil {
+			return size
+		}
+
+		return request.ContentLength
+	}
+
+	return -1
+}
@@ -0,0 +1,170 @@
+package http
+
+import (
+	"context"
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type accessLogRecorder struct {
+	records []domain.AccessLogRecord
+	changed []string
+	flushes int
+}
+
+func (r *accessLogRecorder) Flush(_ context.Context) error {
+	r.flushes++
+	return nil
+}
+
+func (r *accessLogRecorder) Log(record domain.AccessLogRecord) {
+	r.records = append(r.records, record)
+}
+
+func (r *accessLogRecorder) LoggingChanged(bucket string) {
+	r.changed = append(r.changed, bucket)
+}
+
+func TestAccessLog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("X-Amz-Version-Id", "v1")
+			_, _ = w.Write([]byte("hello"))
+		default:
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := testConfig(t, "-backend-url", server.URL)
+
+	accessLogs := &accessLogRecorder{}
+	mux := newTestMux(cfg, testServices{accessLogs: accessLogs})
+
+	request := httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket/a%20b.txt", nil)
+	request.RemoteAddr = "192.0.2.1:1234"
+	request.Header.Set("User-Agent", "test-agent")
+	mux.ServeHTTP(httptest.NewRecorder(), request)
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "http://localhost:9000/bucket/missing.txt?tagging", nil))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost:9000/", nil))
+
+	if !assert.Len(t, accessLogs.records, 2) {
+		return
+	}
+
+	record := accessLogs.records[0]
+	assert.Equal(t, "bucket", record.Bucket)
+	assert.Equal(t, "192.0.2.1", record.RemoteIp)
+	assert.Equal(t, "REST.GET.OBJECT", record.Operation)
+	assert.Equal(t, "a%20b.txt", record.Key)
+	assert.Equal(t, "GET /bucket/a%20b.txt HTTP/1.1", record.RequestUri)
+	assert.Equal(t, http.StatusOK, record.Status)
+	assert.Empty(t, record.ErrorCode)
+	assert.Equal(t, int64(5), record.BytesSent)
+	assert.Equal(t, int64(5), record.ObjectSize)
+	assert.Equal(t, "test-agent", record.UserAgent)
+	assert.Equal(t, "v1", record.VersionId)
+	assert.NotEmpty(t, record.RequestId)
+
+	record = accessLogs.records[1]
+	assert.Equal(t, "REST.DELETE.OBJECT_TAGGING", record.Operation)
+	assert.Equal(t, http.StatusNotFound, record.Status)
+	assert.Equal(t, "NoSuchKey", record.ErrorCode)
+	assert.Equal(t, int64(-1), record.ObjectSize)
+	assert.Empty(t, record.Requester)
+	assert.Empty(t, record.SignatureVersion)
+}
+
+func TestAccessLogWebsitesAndChanges(t *testing.T) {
+	server := objectServer(t, map[string]string{"/site/index.html": "home"}, nil)
+	cfg := testConfig(t, "-backend-url", server.URL)
+
+	configs := storedConfigs{configs: map[string][]byte{"site?website": []byte(testWebsite)}}
+	accessLogs := &accessLogRecorder{}
+	mux := newTestMux(cfg, testServices{configurations: configs, accessLogs: accessLogs})
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://site.s3-website.localhost:9000/", nil))
+	if assert.Len(t, accessLogs.records, 1) {
+		record := accessLogs.records[0]
+		assert.Equal(t, "site", record.Bucket)
+		assert.Equal(t, "WEBSITE.GET.OBJECT", record.Operation)
+		assert.Equal(t, http.StatusOK, record.Status)
+		assert.Equal(t, int64(4), record.BytesSent)
+	}
+
+	body := strings.NewReader(`<BucketLoggingStatus><LoggingEnabled><TargetBucket>logs</TargetBucket><TargetPrefix/></LoggingEnabled></BucketLoggingStatus>`)
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "http://localhost:9000/site?logging", body))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "http://localhost:9000/site?cors", nil))
+	assert.Equal(t, []string{"site"}, accessLogs.changed)
+}
+
+// readerFromWriter is a response writer that copies bodies with ReadFrom, like the one of net/http
+type readerFromWriter struct {
+	*httptest.ResponseRecorder
+	readFrom bool
+}
+
+func (w *readerFromWriter) ReadFrom(src io.Reader) (int64, error) {
+	w.readFrom = true
+	return io.Copy(w.ResponseRecorder, src)
+}
+
+func TestAccessLogWriterPassesThrough(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	var w http.ResponseWriter = &accessLogWriter{ResponseWriter: recorder, status: http.StatusOK}
+
+	flusher, ok := w.(http.Flusher)
+	if assert.True(t, ok) {
+		flusher.Flush()
+		assert.True(t, recorder.Flushed)
+	}
+
+	underlying := &readerFromWriter{ResponseRecorder: httptest.NewRecorder()}
+	writer := &accessLogWriter{ResponseWriter: underlying, status: http.StatusOK, errorBody: limitedBuffer{max: maxLoggedResponse}}
+	// like the body of a response from the backend, the reader doesn't have WriteTo
+	n, err := io.Copy(writer, struct{ io.Reader }{strings.NewReader("hello")})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+	assert.True(t, underlying.readFrom)
+	assert.Equal(t, int64(5), writer.bytesSent)
+
+	// error bodies are kept to find their code
+	underlying = &readerFromWriter{ResponseRecorder: httptest.NewRecorder()}
+	writer = &accessLogWriter{ResponseWriter: underlying, status: http.StatusOK, errorBody: limitedBuffer{max: maxLoggedResponse}}
+	writer.WriteHeader(http.StatusNotFound)
+	_, err = io.Copy(writer, struct{ io.Reader }{strings.NewReader("<Error><Code>NoSuchKey</Code></Error>")})
+	assert.NoError(t, err)
+	assert.False(t, underlying.readFrom)
+	assert.Equal(t, "NoSuchKey", writer.errorCode())
+	assert.Equal(t, "<Error><Code>NoSuchKey</Code></Error>", underlying.Body.String())
+}
+
+func TestFlushAccessLogsNeedsSignature(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.VerifySignatures = true
+	cfg.Credentials = map[string]string{"AKIDEXAMPLE": "secret"}
+
+	accessLogs := &accessLogRecorder{}
+	mux := newTestMux(cfg, testServices{accessLogs: accessLogs})
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/_rainbow/logging/flush", nil))
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Equal(t, 0, accessLogs.flushes)
+
+	target := presign(t, http.MethodPost, "http://localhost:9000/_rainbow/logging/flush?X-Amz-Expires=900", "secret", time.Now())
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, target, nil))
+	assert.Equal(t, http.StatusNoContent, recorder.Code, recorder.Body.String())
+	assert.Equal(t, 1, accessLogs.flushes)
+}
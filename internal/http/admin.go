@@ -0,0 +1,263 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ATenderholt/rainbow-storage/internal/certs"
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/ATenderholt/rainbow-storage/internal/service"
+	"github.com/ATenderholt/rainbow-storage/internal/settings"
+	"github.com/go-chi/chi/v5"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type AdminHandler struct {
+	cfg                 *settings.Config
+	notificationService NotificationService
+	lifecycleService    LifecycleService
+	accessLogService    AccessLogService
+}
+
+func NewAdminHandler(
+	cfg *settings.Config,
+	notificationService NotificationService,
+	lifecycleService LifecycleService,
+	accessLogService AccessLogService,
+) AdminHandler {
+	return AdminHandler{
+		cfg:                 cfg,
+		notificationService: notificationService,
+		lifecycleService:    lifecycleService,
+		accessLogService:    accessLogService,
+	}
+}
+
+// DownloadCA returns the local CA that issued the certificate of the HTTPS listener, so that clients
+// can be configured to trust it.
+func (h AdminHandler) DownloadCA(w http.ResponseWriter, request *http.Request) {
+	log := requestLogger(request)
+
+	if h.cfg.TlsCertFile != "" {
+		http.Error(w, "certificate was provided with -tls-cert, so there is no local CA", http.StatusNotFound)
+		return
+	}
+
+	path := filepath.Join(h.cfg.TlsPath(), certs.CAFile)
+	ca, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		http.Error(w, "local CA has not been generated, start with -tls-port to generate it", http.StatusNotFound)
+		return
+	case err != nil:
+		log.Errorf("Unable to read local CA from %s: %v", path, err)
+		http.Error(w, "unable to read local CA", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	w.Header().Set("Content-Disposition", `attachment; filename="rainbow-ca.pem"`)
+
+	_, err = w.Write(ca)
+	if err != nil {
+		log.Warnf("unable to write local CA to response: %v", err)
+	}
+}
+
+type ExplainResponse struct {
+	Bucket         string                    `json:"bucket"`
+	Key            string                    `json:"key"`
+	Event          string                    `json:"event"`
+	Matched        bool                      `json:"matched"`
+	Configurations []domain.MatchExplanation `json:"configurations"`
+}
+
+// ExplainNotifications reports which CloudFunctionConfigurations of a bucket would be invoked for
+// the key and event provided as query parameters, without invoking any of them. The event defaults
+// to s3:ObjectCreated.
+func (h AdminHandler) ExplainNotifications(w http.ResponseWriter, request *http.Request) {
+	bucket := chi.URLParam(request, "bucket")
+	key := request.URL.Query().Get("key")
+	if key == "" {
+		http.Error(w, "query parameter key is required", http.StatusBadRequest)
+		return
+	}
+
+	eventName := request.URL.Query().Get("event")
+	if eventName == "" {
+		eventName = domain.ObjectCreatedEvent
+	}
+
+	requestLogger(request).Infof("Explaining notifications for %s of key %s in bucket %s", eventName, key, bucket)
+
+	config, err := h.notificationService.GetConfiguration(bucket)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		http.Error(w, "no NotificationConfiguration for bucket "+bucket, http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, "unable to load NotificationConfiguration for bucket "+bucket, http.StatusInternalServerError)
+		return
+	}
+
+	event := domain.NotificationEvent{
+		Bucket: bucket,
+		Key:    key,
+		Event:  eventName,
+	}
+
+	response := ExplainResponse{
+		Bucket:         bucket,
+		Key:            key,
+		Event:          eventName,
+		Configurations: config.Explain(event),
+	}
+
+	for _, explanation := range response.Configurations {
+		response.Matched = response.Matched || explanation.Matched
+	}
+
+	writeJson(w, http.StatusOK, response)
+}
+
+// PauseNotifications pauses delivery of notifications for a bucket, or for a single configuration when
+// the id query parameter is provided. The mode query parameter decides whether events are buffered
+// until resumed (the default) or dropped.
+func (h AdminHandler) PauseNotifications(w http.ResponseWriter, request *http.Request) {
+	bucket := chi.URLParam(request, "bucket")
+	id := request.URL.Query().Get("id")
+
+	mode := domain.PauseMode(request.URL.Query().Get("mode"))
+	if mode == "" {
+		mode = domain.PauseBuffer
+	}
+
+	if !mode.IsValid() {
+		msg := fmt.Sprintf("mode must be %s or %s but was %s", domain.PauseBuffer, domain.PauseDrop, mode)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	err := h.notificationService.Pause(bucket, id, mode)
+	if err != nil {
+		writePauseError(w, err)
+		return
+	}
+
+	h.NotificationStatus(w, request)
+}
+
+// ResumeNotifications resumes delivery of notifications paused by PauseNotifications, flushing any
+// buffered events.
+func (h AdminHandler) ResumeNotifications(w http.ResponseWriter, request *http.Request) {
+	bucket := chi.URLParam(request, "bucket")
+	id := request.URL.Query().Get("id")
+
+	_, err := h.notificationService.Resume(bucket, id)
+	if err != nil {
+		writePauseError(w, err)
+		return
+	}
+
+	h.NotificationStatus(w, request)
+}
+
+func (h AdminHandler) NotificationStatus(w http.ResponseWriter, request *http.Request) {
+	bucket := chi.URLParam(request, "bucket")
+
+	status, err := h.notificationService.PauseStatus(bucket)
+	if err != nil {
+		writePauseError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, status)
+}
+
+type LifecycleResponse struct {
+	Now     time.Time                `json:"now"`
+	Actions []domain.LifecycleAction `json:"actions"`
+	Error   string                   `json:"error,omitempty"`
+}
+
+// RunLifecycle evaluates lifecycle rules now and applies the actions that are due, for a single bucket when
+// the bucket URL parameter is provided. The now query parameter simulates the current time in RFC 3339
+// format, so that rules due in days can be applied right away.
+func (h AdminHandler) RunLifecycle(w http.ResponseWriter, request *http.Request) {
+	bucket := chi.URLParam(request, "bucket")
+
+	now := time.Now().UTC()
+	if value := request.URL.Query().Get("now"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, "now must be in RFC 3339 format but was "+value, http.StatusBadRequest)
+			return
+		}
+		now = parsed
+	}
+
+	requestLogger(request).Infof("Running lifecycle rules of bucket %q as of %v", bucket, now)
+
+	var actions []domain.LifecycleAction
+	var err error
+	if bucket == "" {
+		actions, err = h.lifecycleService.Run(request.Context(), now)
+	} else {
+		actions, err = h.lifecycleService.RunBucket(request.Context(), bucket, now)
+	}
+
+	response := LifecycleResponse{Now: now, Actions: actions}
+	if response.Actions == nil {
+		response.Actions = []domain.LifecycleAction{}
+	}
+
+	if err != nil {
+		response.Error = err.Error()
+		writeJson(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	writeJson(w, http.StatusOK, response)
+}
+
+// FlushAccessLogs writes the buffered server access log records to their target buckets right away, instead
+// of waiting for the next flush interval.
+func (h AdminHandler) FlushAccessLogs(w http.ResponseWriter, request *http.Request) {
+	requestLogger(request).Info("Writing buffered server access logs")
+
+	err := h.accessLogService.Flush(request.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writePauseError(w http.ResponseWriter, err error) {
+	var unknownBucket service.UnknownBucketError
+	var unknownConfig service.UnknownConfigurationError
+
+	switch {
+	case errors.As(err, &unknownBucket), errors.As(err, &unknownConfig):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJson(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(value)
+	if err != nil {
+		logger.Warnf("unable to write %+v to response: %v", value, err)
+	}
+}
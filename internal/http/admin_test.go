@@ -0,0 +1,134 @@
+package http
+
+import (
+	"context"
+	"github.com/ATenderholt/rainbow-storage/internal/certs"
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDownloadCA(t *testing.T) {
+	cfg := testConfig(t, "-tls-port", "9443")
+
+	mux := newTestMux(cfg, testServices{})
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/_rainbow/ca.pem", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	bundle, err := certs.LoadOrCreate(cfg.TlsPath(), cfg.TlsHosts())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/_rainbow/ca.pem", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/x-pem-file", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, bundle.CA, recorder.Body.Bytes())
+}
+
+type lifecycleRecorder struct {
+	runs []string
+}
+
+func (r *lifecycleRecorder) Run(_ context.Context, now time.Time) ([]domain.LifecycleAction, error) {
+	r.runs = append(r.runs, "*@"+now.Format(time.RFC3339))
+	return nil, nil
+}
+
+func (r *lifecycleRecorder) RunBucket(_ context.Context, bucket string, now time.Time) ([]domain.LifecycleAction, error) {
+	r.runs = append(r.runs, bucket+"@"+now.Format(time.RFC3339))
+	return []domain.LifecycleAction{{Bucket: bucket, Rule: "expire", Action: domain.ExpireCurrentVersion, Key: "a.txt"}}, nil
+}
+
+func TestRunLifecycle(t *testing.T) {
+	cfg := testConfig(t)
+
+	lifecycle := &lifecycleRecorder{}
+	mux := newTestMux(cfg, testServices{lifecycle: lifecycle})
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/_rainbow/lifecycle/run?now=2022-02-01T00:00:00Z", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"now": "2022-02-01T00:00:00Z", "actions": []}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/_rainbow/lifecycle/bucket/run?now=2022-03-01T00:00:00Z", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"now": "2022-03-01T00:00:00Z", "actions": [`+
+		`{"bucket": "bucket", "rule": "expire", "action": "Expiration", "key": "a.txt"}]}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/_rainbow/lifecycle/run?now=tomorrow", nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	assert.Equal(t, []string{"*@2022-02-01T00:00:00Z", "bucket@2022-03-01T00:00:00Z"}, lifecycle.runs)
+}
+
+func TestRunLifecycleNeedsSignature(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.VerifySignatures = true
+	cfg.Credentials = map[string]string{"AKIDEXAMPLE": "secret"}
+
+	lifecycle := &lifecycleRecorder{}
+	mux := newTestMux(cfg, testServices{lifecycle: lifecycle})
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/_rainbow/lifecycle/bucket/run", nil))
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Empty(t, lifecycle.runs)
+
+	target := presign(t, http.MethodPost, "http://localhost:9000/_rainbow/lifecycle/run?X-Amz-Expires=900&now=2022-02-01T00:00:00Z", "secret", time.Now())
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, target, nil))
+	assert.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Equal(t, []string{"*@2022-02-01T00:00:00Z"}, lifecycle.runs)
+}
+
+type pauseRecorder struct {
+	NotificationService
+	calls []string
+}
+
+func (r *pauseRecorder) Pause(bucket string, id string, mode domain.PauseMode) error {
+	r.calls = append(r.calls, "pause "+bucket)
+	return nil
+}
+
+func (r *pauseRecorder) Resume(bucket string, id string) (int, error) {
+	r.calls = append(r.calls, "resume "+bucket)
+	return 0, nil
+}
+
+func (r *pauseRecorder) PauseStatus(bucket string) (domain.PauseStatus, error) {
+	return domain.PauseStatus{}, nil
+}
+
+func TestPauseNotificationsNeedsSignature(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.VerifySignatures = true
+	cfg.Credentials = map[string]string{"AKIDEXAMPLE": "secret"}
+
+	notifications := &pauseRecorder{}
+	mux := newTestMux(cfg, testServices{notifications: notifications})
+
+	for _, action := range []string{"pause", "resume"} {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/_rainbow/notifications/bucket/"+action, nil))
+		assert.Equal(t, http.StatusForbidden, recorder.Code, action)
+	}
+	assert.Empty(t, notifications.calls)
+
+	for _, action := range []string{"pause", "resume"} {
+		target := presign(t, http.MethodPost, "http://localhost:9000/_rainbow/notifications/bucket/"+action+"?X-Amz-Expires=900", "secret", time.Now())
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, target, nil))
+		assert.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+	assert.Equal(t, []string{"pause bucket", "resume bucket"}, notifications.calls)
+}
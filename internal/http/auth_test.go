@@ -0,0 +1,109 @@
+package http
+
+import (
+	"context"
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type eventRecorder struct {
+	NotificationService
+	events chan domain.NotificationEvent
+}
+
+func (r eventRecorder) ProcessEvent(event domain.NotificationEvent) error {
+	r.events <- event
+	return nil
+}
+
+func presign(t *testing.T, method string, target string, secret string, date time.Time) string {
+	request, _ := http.NewRequest(method, target, nil)
+
+	creds := aws.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: secret}
+	signer := v4.NewSigner(func(options *v4.SignerOptions) {
+		options.DisableURIPathEscaping = true
+	})
+
+	presigned, _, err := signer.PresignHTTP(context.Background(), creds, request, "UNSIGNED-PAYLOAD", "s3", "us-west-2", date)
+	if err != nil {
+		t.Fatalf("Unable to presign %s: %v", target, err)
+	}
+
+	return presigned
+}
+
+func TestPresignedUploadThroughProxy(t *testing.T) {
+	received := make(chan receivedRequest, 1)
+	cfg := newVerifyingBackend(t, received)
+	cfg.VerifySignatures = true
+	cfg.Credentials = map[string]string{"AKIDEXAMPLE": "secret"}
+
+	events := make(chan domain.NotificationEvent, 1)
+	mux := newTestMux(cfg, testServices{notifications: eventRecorder{events: events}})
+
+	target := presign(t, http.MethodPut, "http://localhost:9000/bucket/upload.txt?X-Amz-Expires=900", "secret", time.Now())
+	request := httptest.NewRequest(http.MethodPut, target, strings.NewReader("contents"))
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, request)
+
+	if !assert.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String()) {
+		return
+	}
+
+	result := <-received
+	assert.Equal(t, "/bucket/upload.txt", result.Path)
+	assert.Equal(t, "", result.Query)
+	assert.Equal(t, "contents", result.Body)
+
+	event := <-events
+	assert.Equal(t, "bucket", event.Bucket)
+	assert.Equal(t, "upload.txt", event.Key)
+	assert.Equal(t, domain.ObjectCreatedEvent, event.Event)
+	assert.Equal(t, int64(8), event.Size)
+}
+
+func TestPresignedRequestRejected(t *testing.T) {
+	cfg := newVerifyingBackend(t, make(chan receivedRequest, 1))
+
+	tests := []struct {
+		name   string
+		verify bool
+		secret string
+		date   time.Time
+		code   string
+	}{
+		{"expired without verifying", false, "secret", time.Now().Add(-time.Hour), "AccessDenied"},
+		{"expired when verifying", true, "secret", time.Now().Add(-time.Hour), "AccessDenied"},
+		{"wrong secret", true, "wrong", time.Now(), "SignatureDoesNotMatch"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg.VerifySignatures = test.verify
+			cfg.Credentials = map[string]string{"AKIDEXAMPLE": "secret"}
+			mux := newTestMux(cfg, testServices{})
+
+			target := presign(t, http.MethodGet, "http://localhost:9000/bucket/key.txt?X-Amz-Expires=900", test.secret, test.date)
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+
+			assert.Equal(t, http.StatusForbidden, recorder.Code)
+			assert.Contains(t, recorder.Body.String(), "<Code>"+test.code+"</Code>")
+		})
+	}
+}
+
+func TestStripPresignedQuery(t *testing.T) {
+	header := http.Header{}
+	query := stripPresignedQuery("uploadId=abc%2Fdef&X-Amz-Algorithm=AWS4-HMAC-SHA256&partNumber=2&X-Amz-Signature=abc&x-amz-expected-bucket-owner=12345", header)
+
+	assert.Equal(t, "uploadId=abc%2Fdef&partNumber=2", query)
+	assert.Equal(t, "12345", header.Get("X-Amz-Expected-Bucket-Owner"))
+}
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"github.com/ATenderholt/rainbow-storage/internal/sigv4"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type chunkedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// decodeChunkedUploads removes the aws-chunked content encoding from request bodies as they are streamed,
+// so that Minio receives the object as it should be stored. Chunk signatures are verified when the
+// signature of the request was verified.
+func decodeChunkedUploads(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, request *http.Request) {
+		if !sigv4.IsChunked(request) {
+			next.ServeHTTP(w, request)
+			return
+		}
+
+		var verified *sigv4.Signature
+		if signature, ok := getSignature(request); ok && !signature.Presigned {
+			verified = &signature
+		}
+
+		reader, err := sigv4.NewChunkedReader(request, verified)
+		if err != nil {
+			requestLogger(request).Warnf("Rejecting aws-chunked %s %s: %v", request.Method, request.URL.Path, err)
+			writeS3Error(w, request, err)
+			return
+		}
+
+		decodedLength, _ := sigv4.DecodedContentLength(request)
+
+		r := request.Clone(request.Context())
+		r.Body = chunkedBody{Reader: reader, Closer: request.Body}
+		r.ContentLength = decodedLength
+		r.Header.Del("Content-Length")
+		r.Header.Del("X-Amz-Decoded-Content-Length")
+		r.Header.Del("X-Amz-Trailer")
+
+		var encodings []string
+		for _, encoding := range strings.Split(r.Header.Get("Content-Encoding"), ",") {
+			encoding = strings.TrimSpace(encoding)
+			if encoding != "" && encoding != "aws-chunked" {
+				encodings = append(encodings, encoding)
+			}
+		}
+
+		if len(encodings) > 0 {
+			r.Header.Set("Content-Encoding", strings.Join(encodings, ","))
+		} else {
+			r.Header.Del("Content-Encoding")
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return http.HandlerFunc(f)
+}
+
+// encodeTrailingChecksum sends a body that had a trailing checksum on to Minio with the same checksum, so that it is
+// stored with the object. It returns false if the body of the request has no trailing checksum.
+func encodeTrailingChecksum(proxyReq *http.Request, request *http.Request) bool {
+	body, ok := request.Body.(chunkedBody)
+	if !ok {
+		return false
+	}
+
+	reader, ok := body.Reader.(*sigv4.ChunkedReader)
+	if !ok {
+		return false
+	}
+
+	encoded, name, length, ok := reader.WithTrailingChecksum()
+	if !ok {
+		return false
+	}
+
+	encoding := "aws-chunked"
+	if other := proxyReq.Header.Get("Content-Encoding"); other != "" {
+		encoding += "," + other
+	}
+
+	proxyReq.Body = io.NopCloser(encoded)
+	proxyReq.ContentLength = length
+	proxyReq.Header.Set("Content-Encoding", encoding)
+	proxyReq.Header.Set("X-Amz-Decoded-Content-Length", strconv.FormatInt(request.ContentLength, 10))
+	proxyReq.Header.Set("X-Amz-Trailer", name)
+	return true
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChunkedUploadIsDecodedForMinio(t *testing.T) {
+	received := make(chan receivedRequest, 1)
+	cfg := newVerifyingBackend(t, received)
+	mux := newTestMux(cfg, testServices{notifications: eventRecorder{events: make(chan domain.NotificationEvent, 1)}})
+
+	body := "5\r\nhello\r\n6\r\n world\r\n0\r\nx-amz-checksum-crc32:DUoRhQ==\r\n\r\n"
+	request := httptest.NewRequest(http.MethodPut, "http://localhost:9000/bucket/chunked.txt", strings.NewReader(body))
+	request.Header.Set("Content-Encoding", "aws-chunked,gzip")
+	request.Header.Set("X-Amz-Content-Sha256", "STREAMING-UNSIGNED-PAYLOAD-TRAILER")
+	request.Header.Set("X-Amz-Decoded-Content-Length", "11")
+	request.Header.Set("X-Amz-Trailer", "x-amz-checksum-crc32")
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, request)
+
+	if !assert.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String()) {
+		return
+	}
+
+	result := <-received
+	assert.Equal(t, "hello world", result.Body)
+	assert.Equal(t, "UNSIGNED-PAYLOAD", result.Hash)
+	assert.Equal(t, "gzip", result.Header.Get("Content-Encoding"))
+	assert.Empty(t, result.Header.Get("X-Amz-Trailer"))
+}
+
+func TestChunkedUploadForwardsTrailingChecksum(t *testing.T) {
+	received := make(chan receivedRequest, 1)
+	cfg := newVerifyingBackend(t, received)
+	cfg.ForwardChecksums = true
+	mux := newTestMux(cfg, testServices{notifications: eventRecorder{events: make(chan domain.NotificationEvent, 1)}})
+
+	body := "5\r\nhello\r\n6\r\n world\r\n0\r\nx-amz-checksum-crc32:DUoRhQ==\r\n\r\n"
+	request := httptest.NewRequest(http.MethodPut, "http://localhost:9000/bucket/chunked.txt", strings.NewReader(body))
+	request.Header.Set("Content-Encoding", "aws-chunked,gzip")
+	request.Header.Set("X-Amz-Content-Sha256", "STREAMING-UNSIGNED-PAYLOAD-TRAILER")
+	request.Header.Set("X-Amz-Decoded-Content-Length", "11")
+	request.Header.Set("X-Amz-Trailer", "x-amz-checksum-crc32")
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, request)
+
+	if !assert.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String()) {
+		return
+	}
+
+	result := <-received
+	assert.Equal(t, "b\r\nhello world\r\n0\r\nx-amz-checksum-crc32:DUoRhQ==\r\n\r\n", result.Body)
+	assert.Equal(t, "STREAMING-UNSIGNED-PAYLOAD-TRAILER", result.Hash)
+	assert.Equal(t, "aws-chunked,gzip", result.Header.Get("Content-Encoding"))
+	assert.Equal(t, "11", result.Header.Get("X-Amz-Decoded-Content-Length"))
+	assert.Equal(t, "x-amz-checksum-crc32", result.Header.Get("X-Amz-Trailer"))
+}
+
+func TestChunkedUploadWithBadChecksum(t *testing.T) {
+	cfg := newVerifyingBackend(t, make(chan receivedRequest, 1))
+	mux := newTestMux(cfg, testServices{})
+
+	body := "5\r\nhello\r\n0\r\nx-amz-checksum-crc32:AAAAAA==\r\n\r\n"
+	request := httptest.NewRequest(http.MethodPost, "http://localhost:9000/bucket/chunked.txt?uploadId=abc", strings.NewReader(body))
+	request.Header.Set("Content-Encoding", "aws-chunked")
+	request.Header.Set("X-Amz-Content-Sha256", "STREAMING-UNSIGNED-PAYLOAD-TRAILER")
+	request.Header.Set("X-Amz-Decoded-Content-Length", "5")
+	request.Header.Set("X-Amz-Trailer", "x-amz-checksum-crc32")
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "<Code>BadDigest</Code>")
+}
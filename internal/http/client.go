@@ -0,0 +1,51 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// Limits for connections to Minio. There's no overall timeout, since uploads and downloads of large objects
+// can take as long as they need; they're instead cancelled when the client goes away.
+const (
+	backendDialTimeout           = 10 * time.Second
+	backendKeepAlive             = 30 * time.Second
+	backendTLSHandshakeTimeout   = 10 * time.Second
+	backendResponseHeaderTimeout = 5 * time.Minute
+	backendExpectContinueTimeout = 1 * time.Second
+	backendIdleConnTimeout       = 90 * time.Second
+	backendMaxIdleConns          = 100
+	backendMaxConnsPerHost       = 256
+)
+
+// NewBackendClient creates the client shared by all requests proxied to Minio, so that connections are
+// pooled and kept alive between requests.
+func NewBackendClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   backendDialTimeout,
+		KeepAlive: backendKeepAlive,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   backendTLSHandshakeTimeout,
+		ResponseHeaderTimeout: backendResponseHeaderTimeout,
+		ExpectContinueTimeout: backendExpectContinueTimeout,
+		IdleConnTimeout:       backendIdleConnTimeout,
+		MaxIdleConns:          backendMaxIdleConns,
+		MaxIdleConnsPerHost:   backendMaxIdleConns,
+		MaxConnsPerHost:       backendMaxConnsPerHost,
+		// responses are streamed back to the client as they are, so Minio shouldn't compress them for us
+		DisableCompression: true,
+	}
+
+	return &http.Client{
+		Transport: transport,
+		// redirects are returned to the client rather than followed
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
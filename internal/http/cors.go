@@ -0,0 +1,174 @@
+package http
+
+import (
+	"encoding/xml"
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// corsResponseHeaders are set by rainbow from the stored CORS configuration, so Minio's values are replaced
+var corsResponseHeaders = []string{
+	"Access-Control-Allow-Credentials",
+	"Access-Control-Allow-Headers",
+	"Access-Control-Allow-Methods",
+	"Access-Control-Allow-Origin",
+	"Access-Control-Expose-Headers",
+	"Access-Control-Max-Age",
+	"Vary",
+}
+
+const corsVary = "Origin, Access-Control-Request-Headers, Access-Control-Request-Method"
+
+func corsForbidden(message string) S3Error {
+	return S3Error{Code: "AccessForbidden", Message: "CORSResponse: " + message, StatusCode: http.StatusForbidden}
+}
+
+func badRequest(message string) S3Error {
+	return S3Error{Code: "BadRequest", Message: message, StatusCode: http.StatusBadRequest}
+}
+
+// loadCors returns the CORS configuration of the bucket, which has no rules if it was never set.
+func (h MinioHandler) loadCors(bucket string) (domain.CORSConfiguration, error) {
+	var cors domain.CORSConfiguration
+
+	config, err := h.configurationService.LoadConfiguration(bucket, "cors")
+	if err != nil || len(config) == 0 {
+		return cors, err
+	}
+
+	err = xml.Unmarshal(config, &cors)
+	if err != nil {
+		logger.Errorf("unable to unmarshal CORS configuration %s: %v", string(config), err)
+		return cors, internalError("Unable to decode stored CORSConfiguration")
+	}
+
+	return cors, nil
+}
+
+func setCorsHeaders(header http.Header, rule domain.CORSRule, origin string) {
+	if rule.AllowsAnyOrigin() {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	header.Set("Access-Control-Allow-Methods", strings.Join(rule.AllowedMethods, ", "))
+
+	if len(rule.ExposeHeaders) > 0 {
+		header.Set("Access-Control-Expose-Headers", strings.Join(rule.ExposeHeaders, ", "))
+	}
+
+	if rule.MaxAgeSeconds != nil {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(*rule.MaxAgeSeconds))
+	}
+}
+
+// requestedHeaders splits the comma-separated Access-Control-Request-Headers of a preflight request.
+func requestedHeaders(request *http.Request) []string {
+	var headers []string
+	for _, value := range request.Header.Values("Access-Control-Request-Headers") {
+		for _, header := range strings.Split(value, ",") {
+			if header = strings.TrimSpace(header); header != "" {
+				headers = append(headers, header)
+			}
+		}
+	}
+
+	return headers
+}
+
+// Preflight answers CORS preflight (OPTIONS) requests from the CORS configuration of the bucket. Like S3,
+// preflight requests aren't authenticated, so this comes before signatures are verified.
+func (h MinioHandler) Preflight(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodOptions {
+			next.ServeHTTP(w, request)
+			return
+		}
+
+		h.preflight(w, request)
+	}
+
+	return http.HandlerFunc(f)
+}
+
+func (h MinioHandler) preflight(w http.ResponseWriter, request *http.Request) {
+	log := requestLogger(request)
+	bucket := getOperation(request).Bucket
+
+	origin := request.Header.Get("Origin")
+	if origin == "" {
+		writeS3Error(w, request, badRequest("Insufficient information. Origin request header needed."))
+		return
+	}
+
+	method := request.Header.Get("Access-Control-Request-Method")
+	if method == "" {
+		writeS3Error(w, request, badRequest("Invalid Access-Control-Request-Method: "+method))
+		return
+	}
+
+	cors, err := h.loadCors(bucket)
+	if err != nil {
+		log.Errorf("unable to load CORS configuration for bucket %s: %v", bucket, err)
+		writeS3Error(w, request, err)
+		return
+	}
+
+	if len(cors.CORSRules) == 0 {
+		log.Infof("Rejecting preflight from %s for bucket %s without CORS configuration", origin, bucket)
+		writeS3Error(w, request, corsForbidden("CORS is not enabled for this bucket."))
+		return
+	}
+
+	headers := requestedHeaders(request)
+	rule, ok := cors.Match(origin, method, headers)
+	if !ok {
+		log.Infof("Rejecting preflight of %s from %s for bucket %s with headers %v", method, origin, bucket, headers)
+		writeS3Error(w, request, corsForbidden("This CORS request is not allowed. This is usually because the "+
+			"evalution of Origin, request method / Access-Control-Request-Method or Access-Control-Request-Headers "+
+			"are not whitelisted by the resource's CORS spec."))
+		return
+	}
+
+	setCorsHeaders(w.Header(), rule, origin)
+	if len(headers) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	w.Header().Set("Vary", corsVary)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// CorsHeaders adds Access-Control-* headers to responses for cross-origin requests allowed by the CORS
+// configuration of the bucket, including error responses.
+func (h MinioHandler) CorsHeaders(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, request *http.Request) {
+		operation := getOperation(request)
+		origin := request.Header.Get("Origin")
+		if operation.Bucket == "" || origin == "" {
+			next.ServeHTTP(w, request)
+			return
+		}
+
+		cors, err := h.loadCors(operation.Bucket)
+		if err != nil {
+			requestLogger(request).Warnf("Unable to load CORS configuration for bucket %s: %v", operation.Bucket, err)
+		}
+
+		if len(cors.CORSRules) > 0 {
+			w.Header().Set("Vary", corsVary)
+		}
+
+		if rule, ok := cors.Match(origin, request.Method, nil); ok {
+			setCorsHeaders(w.Header(), rule, origin)
+		}
+
+		next.ServeHTTP(w, request)
+	}
+
+	return http.HandlerFunc(f)
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testCors = `<CORSConfiguration>` +
+	`<CORSRule><AllowedOrigin>https://*.example.com</AllowedOrigin><AllowedMethod>PUT</AllowedMethod><AllowedMethod>GET</AllowedMethod>` +
+	`<AllowedHeader>Content-*</AllowedHeader><AllowedHeader>x-amz-date</AllowedHeader><ExposeHeader>ETag</ExposeHeader>` +
+	`<MaxAgeSeconds>300</MaxAgeSeconds></CORSRule>` +
+	`<CORSRule><AllowedOrigin>*</AllowedOrigin><AllowedMethod>GET</AllowedMethod></CORSRule>` +
+	`</CORSConfiguration>`
+
+func preflightRequest(url string, origin string, method string, headers string) *http.Request {
+	request := httptest.NewRequest(http.MethodOptions, url, nil)
+	request.Header.Set("Origin", origin)
+	request.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		request.Header.Set("Access-Control-Request-Headers", headers)
+	}
+
+	return request
+}
+
+func TestPreflight(t *testing.T) {
+	cfg := testConfig(t, "-verify-signatures")
+
+	configs := storedConfigs{configs: map[string][]byte{"bucket?cors": []byte(testCors)}}
+	mux := newTestMux(cfg, testServices{configurations: configs})
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, preflightRequest("http://localhost:9000/bucket/dir/key.txt", "https://app.example.com", "PUT", "content-type, X-Amz-Date"))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, http.Header{
+		"Access-Control-Allow-Credentials": {"true"},
+		"Access-Control-Allow-Headers":     {"content-type, X-Amz-Date"},
+		"Access-Control-Allow-Methods":     {"PUT, GET"},
+		"Access-Control-Allow-Origin":      {"https://app.example.com"},
+		"Access-Control-Expose-Headers":    {"ETag"},
+		"Access-Control-Max-Age":           {"300"},
+		"Vary":                             {corsVary},
+	}, withoutRequestIds(recorder.Header()))
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, preflightRequest("http://localhost:9000/bucket", "https://other.com", "GET", ""))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "*", recorder.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Credentials"))
+
+	tests := []struct {
+		name    string
+		request *http.Request
+		status  int
+		code    string
+	}{
+		{"method not allowed", preflightRequest("http://localhost:9000/bucket/key.txt", "https://other.com", "PUT", ""), http.StatusForbidden, "AccessForbidden"},
+		{"header not allowed", preflightRequest("http://localhost:9000/bucket/key.txt", "https://app.example.com", "PUT", "x-amz-meta-owner"), http.StatusForbidden, "AccessForbidden"},
+		{"no configuration", preflightRequest("http://localhost:9000/other/key.txt", "https://app.example.com", "GET", ""), http.StatusForbidden, "AccessForbidden"},
+		{"no origin", preflightRequest("http://localhost:9000/bucket/key.txt", "", "GET", ""), http.StatusBadRequest, "BadRequest"},
+		{"no method", preflightRequest("http://localhost:9000/bucket/key.txt", "https://app.example.com", "", ""), http.StatusBadRequest, "BadRequest"},
+	}
+
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, test.request)
+		assert.Equal(t, test.status, recorder.Code, test.name)
+		assert.Contains(t, recorder.Body.String(), "<Code>"+test.code+"</Code>", test.name)
+		assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"), test.name)
+	}
+}
+
+func withoutRequestIds(header http.Header) http.Header {
+	result := header.Clone()
+	for _, name := range rainbowResponseHeaders {
+		result.Del(name)
+	}
+
+	return result
+}
+
+func TestCorsHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Vary", "Origin")
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := testConfig(t, "-backend-url", server.URL)
+
+	configs := storedConfigs{configs: map[string][]byte{"bucket?cors": []byte(testCors)}}
+	mux := newTestMux(cfg, testServices{configurations: configs})
+
+	request := httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket/key.txt", nil)
+	request.Header.Set("Origin", "https://app.example.com")
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, []string{"https://app.example.com"}, recorder.Header().Values("Access-Control-Allow-Origin"))
+	assert.Equal(t, "ETag", recorder.Header().Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, []string{corsVary}, recorder.Header().Values("Vary"))
+
+	// Minio's own CORS headers are never returned
+	request = httptest.NewRequest(http.MethodDelete, "http://localhost:9000/bucket/key.txt", nil)
+	request.Header.Set("Origin", "https://app.example.com")
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, request)
+	assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, []string{corsVary}, recorder.Header().Values("Vary"))
+
+	request = httptest.NewRequest(http.MethodGet, "http://localhost:9000/other/key.txt", nil)
+	request.Header.Set("Origin", "https://app.example.com")
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, request)
+	assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, recorder.Header().Get("Vary"))
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"encoding/xml"
+	"errors"
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/ATenderholt/rainbow-storage/internal/service"
+	"github.com/ATenderholt/rainbow-storage/internal/sigv4"
+	"net/http"
+)
+
+// S3Error is written as the XML error document returned by S3, so that SDKs can parse it.
+type S3Error struct {
+	XMLName    xml.Name `xml:"Error"`
+	Code       string   `xml:"Code"`
+	Message    string   `xml:"Message"`
+	Resource   string   `xml:"Resource,omitempty"`
+	RequestId  string   `xml:"RequestId"`
+	HostId     string   `xml:"HostId"`
+	StatusCode int      `xml:"-"`
+}
+
+func (e S3Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+func internalError(message string) S3Error {
+	return S3Error{Code: "InternalError", Message: message, StatusCode: http.StatusInternalServerError}
+}
+
+func malformedXML(message string) S3Error {
+	return S3Error{Code: "MalformedXML", Message: message, StatusCode: http.StatusBadRequest}
+}
+
+func invalidArgument(message string) S3Error {
+	return S3Error{Code: "InvalidArgument", Message: message, StatusCode: http.StatusBadRequest}
+}
+
+func accessDenied(message string) S3Error {
+	return S3Error{Code: "AccessDenied", Message: message, StatusCode: http.StatusForbidden}
+}
+
+func methodNotAllowed() S3Error {
+	return S3Error{
+		Code:       "MethodNotAllowed",
+		Message:    "The specified method is not allowed against this resource.",
+		StatusCode: http.StatusMethodNotAllowed,
+	}
+}
+
+func noSuchBucket() S3Error {
+	return S3Error{Code: "NoSuchBucket", Message: "The specified bucket does not exist", StatusCode: http.StatusNotFound}
+}
+
+func serviceUnavailable(message string) S3Error {
+	return S3Error{Code: "ServiceUnavailable", Message: message, StatusCode: http.StatusServiceUnavailable}
+}
+
+// toS3Error maps errors from services and signature verification to the S3Error returned to clients.
+func toS3Error(err error) S3Error {
+	var s3Err S3Error
+	var validationErr domain.ValidationError
+	var sigErr sigv4.Error
+	var loadErr service.LoadError
+	var saveErr service.SaveError
+	var deleteErr service.DeleteError
+	var decodeErr service.DecodeError
+	var encodeErr service.EncodeError
+
+	switch {
+	case errors.As(err, &s3Err):
+		return s3Err
+	case errors.As(err, &validationErr):
+		return S3Error{Code: validationErr.Code, Message: validationErr.Message, StatusCode: http.StatusBadRequest}
+	case errors.As(err, &sigErr):
+		return S3Error{Code: sigErr.Code, Message: sigErr.Message, StatusCode: sigErr.StatusCode}
+	case errors.As(err, &loadErr):
+		return internalError(loadErr.Error())
+	case errors.As(err, &saveErr):
+		return internalError(saveErr.Error())
+	case errors.As(err, &deleteErr):
+		return internalError(deleteErr.Error())
+	case errors.As(err, &decodeErr):
+		return internalError(decodeErr.Error())
+	case errors.As(err, &encodeErr):
+		return internalError(encodeErr.Error())
+	default:
+		return internalError(err.Error())
+	}
+}
+
+// writeS3Error writes the error as XML with its HTTP status. The resource defaults to the path of the request.
+func writeS3Error(w http.ResponseWriter, request *http.Request, err error) {
+	e := toS3Error(err)
+	if e.Resource == "" {
+		e.Resource = request.URL.Path
+	}
+
+	e.RequestId, e.HostId = getRequestIds(request)
+
+	body, err := xml.Marshal(e)
+	if err != nil {
+		requestLogger(request).Errorf("unable to marshal %+v: %v", e, err)
+		http.Error(w, e.Message, e.StatusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(e.StatusCode)
+
+	_, err = w.Write([]byte(xml.Header))
+	if err == nil {
+		_, err = w.Write(body)
+	}
+
+	if err != nil {
+		requestLogger(request).Warnf("unable to write %s error to response: %v", e.Code, err)
+	}
+}
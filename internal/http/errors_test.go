@@ -0,0 +1,43 @@
+package http
+
+import (
+	"errors"
+	"github.com/ATenderholt/rainbow-storage/internal/service"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestToS3Error(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		code   string
+		status int
+	}{
+		{"s3 error", malformedXML("bad"), "MalformedXML", http.StatusBadRequest},
+		{"load error", service.LoadError{}, "InternalError", http.StatusInternalServerError},
+		{"save error", service.SaveError{}, "InternalError", http.StatusInternalServerError},
+		{"decode error", service.DecodeError{}, "InternalError", http.StatusInternalServerError},
+		{"other error", errors.New("unexpected"), "InternalError", http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		result := toS3Error(test.err)
+		assert.Equal(t, test.code, result.Code, test.name)
+		assert.Equal(t, test.status, result.StatusCode, test.name)
+	}
+}
+
+func TestWriteS3Error(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPut, "http://localhost:9000/bucket?notification", nil)
+	recorder := httptest.NewRecorder()
+
+	writeS3Error(recorder, request, invalidArgument("No CloudFunctionConfiguration was provided"))
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "application/xml", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<Error><Code>InvalidArgument</Code><Message>No CloudFunctionConfiguration was provided</Message><Resource>/bucket</Resource><RequestId></RequestId><HostId></HostId></Error>`, recorder.Body.String())
+}
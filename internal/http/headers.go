@@ -0,0 +1,76 @@
+package http
+
+import (
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// hopByHopHeaders only apply to a single connection, so are never forwarded in either direction
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// clientOnlyHeaders belong to the client's request and are replaced when re-signing it for Minio
+var clientOnlyHeaders = []string{
+	"Authorization",
+	"Content-Length",
+	"Expect",
+	"Host",
+	"X-Amz-Content-Sha256",
+	"X-Amz-Date",
+	"X-Amz-Security-Token",
+}
+
+// rainbowResponseHeaders are set by rainbow for every response, so Minio's values are replaced
+var rainbowResponseHeaders = []string{
+	"X-Amz-Id-2",
+	"X-Amz-Request-Id",
+}
+
+// forwardRequestHeaders copies the headers of a client request that Minio should see, like Content-Type,
+// Range, conditional headers and all x-amz-* headers, leaving out hop-by-hop headers and anything used to
+// authenticate the client.
+func forwardRequestHeaders(dst http.Header, src http.Header) {
+	copyHeaders(dst, src, hopByHopHeaders, clientOnlyHeaders)
+}
+
+// forwardResponseHeaders copies the headers of a Minio response back to the client, leaving out hop-by-hop headers,
+// the request ids assigned by rainbow and CORS headers.
+func forwardResponseHeaders(dst http.Header, src http.Header) {
+	copyHeaders(dst, src, hopByHopHeaders, rainbowResponseHeaders, corsResponseHeaders)
+}
+
+func copyHeaders(dst http.Header, src http.Header, excluded ...[]string) {
+	skip := make(map[string]bool)
+	for _, names := range excluded {
+		for _, name := range names {
+			skip[textproto.CanonicalMIMEHeaderKey(name)] = true
+		}
+	}
+
+	// headers listed in Connection are also hop-by-hop
+	for _, value := range src.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			skip[textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))] = true
+		}
+	}
+
+	for key, values := range src {
+		if skip[textproto.CanonicalMIMEHeaderKey(key)] {
+			continue
+		}
+
+		for _, v := range values {
+			dst.Add(key, v)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestForwardRequestHeaders(t *testing.T) {
+	src := http.Header{}
+	src.Set("Authorization", "AWS4-HMAC-SHA256 Credential=abc")
+	src.Set("Connection", "keep-alive, X-Custom-Hop")
+	src.Set("Content-Type", "text/plain")
+	src.Set("Content-Md5", "1B2M2Y8AsgTpgAmY7PhCfg==")
+	src.Set("Range", "bytes=0-99")
+	src.Set("If-None-Match", "\"abc\"")
+	src.Set("Transfer-Encoding", "chunked")
+	src.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+	src.Set("X-Amz-Copy-Source", "/bucket/key")
+	src.Set("X-Amz-Date", "20220101T000000Z")
+	src.Set("X-Amz-Meta-Owner", "someone")
+	src.Set("X-Amz-Tagging", "a=b")
+	src.Set("X-Custom-Hop", "dropped")
+
+	dst := http.Header{}
+	forwardRequestHeaders(dst, src)
+
+	assert.Equal(t, http.Header{
+		"Content-Type":      {"text/plain"},
+		"Content-Md5":       {"1B2M2Y8AsgTpgAmY7PhCfg=="},
+		"Range":             {"bytes=0-99"},
+		"If-None-Match":     {"\"abc\""},
+		"X-Amz-Copy-Source": {"/bucket/key"},
+		"X-Amz-Meta-Owner":  {"someone"},
+		"X-Amz-Tagging":     {"a=b"},
+	}, dst)
+}
+
+func TestForwardResponseHeaders(t *testing.T) {
+	src := http.Header{}
+	src.Set("Connection", "close")
+	src.Set("Content-Range", "bytes 0-99/1000")
+	src.Set("Etag", "\"abc\"")
+	src.Set("Keep-Alive", "timeout=5")
+
+	dst := http.Header{}
+	forwardResponseHeaders(dst, src)
+
+	assert.Equal(t, http.Header{
+		"Content-Range": {"bytes 0-99/1000"},
+		"Etag":          {"\"abc\""},
+	}, dst)
+}
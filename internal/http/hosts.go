@@ -0,0 +1,77 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const originalUrlContextKey = RainbowContextKey("originalUrl")
+
+// bucketFromHost returns the bucket of a virtual-hosted-style request, i.e. my-bucket for a Host of
+// my-bucket.s3.localhost:9000 when the domain is s3.localhost.
+func bucketFromHost(host string, domain string) (string, bool) {
+	if domain == "" {
+		return "", false
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	host = strings.ToLower(host)
+	suffix := "." + strings.ToLower(domain)
+	if !strings.HasSuffix(host, suffix) || len(host) == len(suffix) {
+		return "", false
+	}
+
+	return strings.TrimSuffix(host, suffix), true
+}
+
+// VirtualHosts rewrites virtual-hosted-style requests to path-style ones before they are routed, so that
+// every handler can get the bucket from the path. The original URL is kept in the request context, since
+// that is what the client signed.
+func (h MinioHandler) VirtualHosts(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, request *http.Request) {
+		bucket, ok := bucketFromHost(request.Host, h.cfg.VirtualHostDomain)
+		if !ok {
+			next.ServeHTTP(w, request)
+			return
+		}
+
+		original := *request.URL
+		ctx := context.WithValue(request.Context(), originalUrlContextKey, &original)
+		r := request.Clone(ctx)
+
+		path := "/" + bucket
+		rawPath := "/" + url.PathEscape(bucket)
+		if request.URL.Path != "/" && request.URL.Path != "" {
+			path += request.URL.Path
+			rawPath += request.URL.EscapedPath()
+		}
+
+		r.URL.Path = path
+		r.URL.RawPath = rawPath
+		r.RequestURI = r.URL.RequestURI()
+
+		requestLogger(request).Debugf("Rewrote virtual-hosted-style request for %s%s to %s", request.Host, original.Path, path)
+
+		next.ServeHTTP(w, r)
+	}
+
+	return http.HandlerFunc(f)
+}
+
+// originalRequest returns the request as the client sent it, before any virtual-hosted-style rewrite.
+func originalRequest(request *http.Request) *http.Request {
+	original, ok := request.Context().Value(originalUrlContextKey).(*url.URL)
+	if !ok {
+		return request
+	}
+
+	r := request.Clone(request.Context())
+	r.URL = original
+	return r
+}
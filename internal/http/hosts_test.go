@@ -0,0 +1,69 @@
+package http
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBucketFromHost(t *testing.T) {
+	tests := []struct {
+		host   string
+		domain string
+		bucket string
+		ok     bool
+	}{
+		{"my-bucket.s3.localhost:9000", "s3.localhost", "my-bucket", true},
+		{"My.Dotted.Bucket.S3.localhost", "s3.localhost", "my.dotted.bucket", true},
+		{"s3.localhost:9000", "s3.localhost", "", false},
+		{"localhost:9000", "s3.localhost", "", false},
+		{"my-bucket.s3.localhost:9000", "", "", false},
+		{"rainbow-storage:9000", "s3.localhost", "", false},
+	}
+
+	for _, test := range tests {
+		bucket, ok := bucketFromHost(test.host, test.domain)
+		assert.Equal(t, test.ok, ok, test.host)
+		assert.Equal(t, test.bucket, bucket, test.host)
+	}
+}
+
+func TestVirtualHostedRequestThroughProxy(t *testing.T) {
+	received := make(chan receivedRequest, 1)
+	cfg := newVerifyingBackend(t, received)
+	cfg.VerifySignatures = true
+	cfg.Credentials = map[string]string{"AKIDEXAMPLE": "secret"}
+
+	mux := newTestMux(cfg, testServices{})
+
+	request, _ := http.NewRequest(http.MethodGet, "http://my-bucket.s3.localhost:9000/dir/some%20key.txt", nil)
+	request.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+
+	creds := aws.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}
+	signer := v4.NewSigner(func(options *v4.SignerOptions) {
+		options.DisableURIPathEscaping = true
+	})
+	err := signer.SignHTTP(context.Background(), creds, request, "UNSIGNED-PAYLOAD", "s3", "us-west-2", time.Now())
+	if err != nil {
+		t.Fatalf("Unable to sign request: %v", err)
+	}
+
+	incoming := httptest.NewRequest(request.Method, request.URL.String(), strings.NewReader(""))
+	incoming.Header = request.Header
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, incoming)
+
+	if !assert.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String()) {
+		return
+	}
+
+	result := <-received
+	assert.Equal(t, "/my-bucket/dir/some key.txt", result.Path)
+}
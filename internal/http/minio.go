@@ -1,37 +1,52 @@
 package http
 
 import (
-	"bytes"
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"github.com/ATenderholt/rainbow-storage/internal/domain"
 	"github.com/ATenderholt/rainbow-storage/internal/settings"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
-	"github.com/go-chi/chi/v5"
-	"gopkg.in/yaml.v2"
+	"github.com/ATenderholt/rainbow-storage/internal/sigv4"
 	"io"
 	"io/fs"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
 	"time"
 )
 
 type NotificationService interface {
-	GetConfigurationPath(bucket string) string
+	GetConfiguration(bucket string) (domain.NotificationConfiguration, error)
+	Pause(bucket string, id string, mode domain.PauseMode) error
+	PauseStatus(bucket string) (domain.PauseStatus, error)
 	ProcessEvent(event domain.NotificationEvent) error
+	Resume(bucket string, id string) (int, error)
 	Save(bucket string, config domain.NotificationConfiguration) (string, error)
 }
 
 type ConfigurationService interface {
 	CleanupAllConfiguration(bucket string)
+	DeleteConfiguration(bucket string, configType string) (string, error)
 	LoadConfiguration(bucket string, configType string) ([]byte, error)
 	SaveConfiguration(bucket string, configType string, config []byte) (string, error)
 }
 
+type LifecycleService interface {
+	Run(ctx context.Context, now time.Time) ([]domain.LifecycleAction, error)
+	RunBucket(ctx context.Context, bucket string, now time.Time) ([]domain.LifecycleAction, error)
+}
+
+type ReplicationService interface {
+	DeleteMarkerCreated(ctx context.Context, bucket string, key string)
+	ObjectCreated(ctx context.Context, bucket string, key string, versionId string)
+	Status(bucket string, key string, versionId string) (domain.ObjectReplication, bool)
+}
+
+type AccessLogService interface {
+	Flush(ctx context.Context) error
+	Log(record domain.AccessLogRecord)
+	LoggingChanged(bucket string)
+}
+
 type ResponseWriter struct {
 	http.ResponseWriter
 	Code *int
@@ -44,62 +59,71 @@ func (w ResponseWriter) WriteHeader(code int) {
 
 type MinioHandler struct {
 	cfg                  *settings.Config
+	client               *http.Client
 	notificationService  NotificationService
 	configurationService ConfigurationService
+	replicationService   ReplicationService
+	accessLogService     AccessLogService
+	policies             policyCache
 }
 
 func NewMinioHandler(
 	cfg *settings.Config,
+	client *http.Client,
 	notificationService NotificationService,
 	configurationService ConfigurationService,
+	replicationService ReplicationService,
+	accessLogService AccessLogService,
 ) MinioHandler {
 	return MinioHandler{
 		cfg:                  cfg,
+		client:               client,
 		notificationService:  notificationService,
 		configurationService: configurationService,
+		replicationService:   replicationService,
+		accessLogService:     accessLogService,
+		policies:             newPolicyCache(),
 	}
 }
 
 func (h MinioHandler) GetNotifications(next http.Handler) http.Handler {
 	f := func(w http.ResponseWriter, request *http.Request) {
-		if !request.URL.Query().Has("notification") {
+		operation := getOperation(request)
+		if operation.Name != "GetBucketNotificationConfiguration" {
 			next.ServeHTTP(w, request)
 			return
 		}
 
-		bucket := chi.URLParam(request, "bucket")
-		logger.Infof("Loading NotificationConfiguration for bucket %s", bucket)
+		log := requestLogger(request)
+		bucket := operation.Bucket
+		log.Infof("Loading NotificationConfiguration for bucket %s", bucket)
 
-		path := h.notificationService.GetConfigurationPath(bucket)
-		file, err := os.Open(path)
+		notification, err := h.notificationService.GetConfiguration(bucket)
 		switch {
 		case errors.Is(err, fs.ErrNotExist):
-			logger.Warnf("File %s does not exist: %v", path, err)
-			http.NotFound(w, request)
+			log.Warnf("NotificationConfiguration for bucket %s does not exist: %v", bucket, err)
+			writeS3Error(w, request, S3Error{
+				Code:       "NoSuchConfiguration",
+				Message:    "The specified configuration does not exist.",
+				StatusCode: http.StatusNotFound,
+			})
 			return
 		case err != nil:
-			logger.Errorf("Error when opening file %s: %v", path, err)
-			http.Error(w, "error", http.StatusInternalServerError)
+			log.Errorf("Unable to load NotificationConfiguration for bucket %s: %v", bucket, err)
+			writeS3Error(w, request, err)
 			return
 		}
-		defer file.Close()
-
-		decoder := yaml.NewDecoder(file)
 
-		var notification domain.NotificationConfiguration
-		err = decoder.Decode(&notification)
+		payload, err := xml.Marshal(notification)
 		if err != nil {
-			logger.Errorf("Unable to decode NotificationConfiguration for bucket %s: %v", bucket, err)
-			http.Error(w, "Unable to decode NotificationConfiguration", http.StatusInternalServerError)
+			log.Errorf("Unable to encode NotificationConfiguration for bucket %s: %v", bucket, err)
+			writeS3Error(w, request, internalError("Unable to encode NotificationConfiguration"))
 			return
 		}
 
-		encoder := xml.NewEncoder(w)
-		err = encoder.Encode(notification)
+		_, err = w.Write(payload)
 		if err != nil {
-			logger.Errorf("Unable to encode NotificationConfiguration for bucket %s: %v", bucket, err)
-			http.Error(w, "Unable to encode NotificationConfiguration", http.StatusInternalServerError)
-			return
+			log.Warnf("Unable to write NotificationConfiguration for bucket %s to response: %v", bucket, err)
 		}
 	}
 
@@ -108,13 +132,15 @@ func (h MinioHandler) GetNotifications(next http.Handler) http.Handler {
 
 func (h MinioHandler) PutNotifications(next http.Handler) http.Handler {
 	f := func(w http.ResponseWriter, request *http.Request) {
-		if !request.URL.Query().Has("notification") {
+		operation := getOperation(request)
+		if operation.Name != "PutBucketNotificationConfiguration" {
 			next.ServeHTTP(w, request)
 			return
 		}
 
-		bucket := chi.URLParam(request, "bucket")
-		logger.Infof("Saving NotificationConfiguration for bucket %s", bucket)
+		log := requestLogger(request)
+		bucket := operation.Bucket
+		log.Infof("Saving NotificationConfiguration for bucket %s", bucket)
 
 		payload, _ := io.ReadAll(request.Body)
 		request.Body.Close()
@@ -123,24 +149,24 @@ func (h MinioHandler) PutNotifications(next http.Handler) http.Handler {
 		err := xml.Unmarshal(payload, &notification)
 		if err != nil {
 			msg := fmt.Sprintf("unable to unmarshall notification %s: %v", string(payload), err)
-			logger.Error(msg)
-			http.Error(w, msg, http.StatusInternalServerError)
+			log.Error(msg)
+			writeS3Error(w, request, malformedXML("The XML you provided was not well-formed or did not validate against our published schema."))
 			return
 		}
 
-		logger.Infof("Received Notification %+v for URL %s", notification, request.URL.Path)
+		log.Infof("Received Notification %+v for URL %s", notification, request.URL.Path)
 
 		if len(notification.CloudFunctionConfigurations) == 0 {
-			logger.Infof("No configuration found fo raw payload: %s", string(payload))
-			logger.Infof("Query params: %v", request.URL.RawQuery)
-			http.Error(w, "No configuration functions", http.StatusBadRequest)
+			log.Infof("No configuration found fo raw payload: %s", string(payload))
+			log.Infof("Query params: %v", request.URL.RawQuery)
+			writeS3Error(w, request, invalidArgument("No CloudFunctionConfiguration was provided"))
 			return
 		}
 
 		_, err = h.notificationService.Save(bucket, notification)
 		if err != nil {
-			logger.Errorf("Unable to save notification for bucket %s", bucket)
-			http.Error(w, "Unable to save notification for bucket "+bucket, http.StatusInternalServerError)
+			log.Errorf("Unable to save notification for bucket %s: %v", bucket, err)
+			writeS3Error(w, request, err)
 			return
 		}
 
@@ -150,6 +176,14 @@ func (h MinioHandler) PutNotifications(next http.Handler) http.Handler {
 	return http.HandlerFunc(f)
 }
 
+// notifyingOperations are the operations that finish creating an object. Starting a multipart upload and
+// uploading its parts don't send notifications, only completing it does.
+var notifyingOperations = map[string]bool{
+	"CompleteMultipartUpload": true,
+	"CopyObject":              true,
+	"PutObject":               true,
+}
+
 func (h MinioHandler) SendNotifications(next http.Handler) http.Handler {
 	f := func(w http.ResponseWriter, request *http.Request) {
 		wrapped := ResponseWriter{
@@ -159,159 +193,178 @@ func (h MinioHandler) SendNotifications(next http.Handler) http.Handler {
 
 		next.ServeHTTP(wrapped, request)
 
-		bucket := chi.URLParam(request, "bucket")
-		key := chi.URLParam(request, "*")
-
-		if key == "" {
-			return
-		}
-
-		// Example finished uploads:
-		// PUT /myaws-files/AWSLogs/small.log
-		// POST /myaws-files/AWSLogs/test.log?uploadId=1bc7323b-ad52-4ca5-9606-e1e22c38cbbd
-
-		// Example start of uploading parts
-		// POST http://localhost:9000/myaws-files/AWSLogs/test.log?uploads
-		// don't send notification yet
-		if request.Method == http.MethodPost && request.URL.Query().Has("uploads") {
-			return
-		}
-
-		// Example part upload:
-		// PUT http://localhost:9000/myaws-files/AWSLogs/test.log?uploadId=956d38ed-a2ef-4149-9382-3f4a819e503d&partNumber=2
-		// don't send notifications for parts yet
-		if request.Method == http.MethodPut && request.URL.Query().Has("uploadId") {
+		operation := getOperation(request)
+		if !notifyingOperations[operation.Name] {
 			return
 		}
 
+		log := requestLogger(request)
+		bucket, key := operation.Bucket, operation.Key
 		if *wrapped.Code != http.StatusOK {
-			logger.Warnf("Multipart upload for key %s in bucket %s did not finish correctly", key, bucket)
+			log.Warnf("%s for key %s in bucket %s did not finish correctly", operation.Name, key, bucket)
 			return
 		}
 
-		logger.Infof("Completed upload for key %s in bucket %s", key, bucket)
+		log.Infof("Completed upload for key %s in bucket %s", key, bucket)
+		requestId, hostId := getRequestIds(request)
 		event := domain.NotificationEvent{
-			Bucket:   bucket,
-			Key:      key,
-			Event:    domain.ObjectCreatedEvent,
-			SourceIp: request.RemoteAddr,
-			Size:     h.getObjectSize(bucket, key),
+			Bucket:    bucket,
+			Key:       key,
+			Event:     domain.ObjectCreatedEvent,
+			SourceIp:  request.RemoteAddr,
+			Size:      h.getObjectSize(request, operation),
+			RequestId: requestId,
+			HostId:    hostId,
 		}
 
 		err := h.notificationService.ProcessEvent(event)
 		if err != nil {
-			logger.Warnf("Unable to send event for key %s in bucket %s: %v", key, bucket, err)
+			log.Warnf("Unable to send event for key %s in bucket %s: %v", key, bucket, err)
 		}
 	}
 
 	return http.HandlerFunc(f)
 }
 
-func (h MinioHandler) getObjectSize(bucket, key string) int64 {
-	objectPath := filepath.Join(h.cfg.DataPath(), "buckets", bucket, key)
-	stats, err := os.Stat(objectPath)
+// getObjectSize returns the size of an object that was just created. Uploads have it in the request, otherwise it
+// is asked from Minio, since its data may not be on this machine.
+func (h MinioHandler) getObjectSize(request *http.Request, operation Operation) int64 {
+	if operation.Name == "PutObject" && request.ContentLength >= 0 {
+		return request.ContentLength
+	}
+
+	size, err := h.headObjectSize(request.Context(), operation.Bucket, operation.Key)
 	if err != nil {
-		logger.Warnf("Cannot get filesize of %s: %v", objectPath, err)
+		requestLogger(request).Warnf("Cannot get size of %s in bucket %s: %v", operation.Key, operation.Bucket, err)
 		return -1
 	}
 
-	return stats.Size()
+	return size
 }
 
-func (h MinioHandler) Proxy(w http.ResponseWriter, request *http.Request) {
-	url := h.cfg.MinioUrl() + request.URL.Path + "?" + request.URL.RawQuery
-	logger.Infof("Forwarding %s to %s", request.Method, url)
+func (h MinioHandler) headObjectSize(ctx context.Context, bucket string, key string) (int64, error) {
+	resp, err := h.headBackend(ctx, "/"+bucket+"/"+key)
+	if err != nil {
+		return -1, err
+	}
 
-	payload, err := io.ReadAll(request.Body)
-	request.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return -1, fmt.Errorf("minio returned %d", resp.StatusCode)
+	}
 
-	reader := bytes.NewReader(payload)
-	proxyReq, _ := http.NewRequest(request.Method, url, reader)
+	return resp.ContentLength, nil
+}
 
-	credentials := aws.Credentials{AccessKeyID: "minio", SecretAccessKey: "miniosecret"}
+// bucketExists asks Minio whether a bucket exists, for requests that are answered without forwarding them.
+func (h MinioHandler) bucketExists(ctx context.Context, bucket string) (bool, error) {
+	resp, err := h.headBackend(ctx, "/"+bucket)
+	if err != nil {
+		return false, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("minio returned %d", resp.StatusCode)
+	}
+}
 
-	signer := v4.NewSigner()
-	err = signer.SignHTTP(request.Context(), credentials, proxyReq,
-		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
-		"s3", h.cfg.Region, time.Now())
+// checkBucketExists answers NoSuchBucket for configuration of a bucket that doesn't exist, since those requests
+// aren't forwarded to Minio. It returns whether the request can go on.
+func (h MinioHandler) checkBucketExists(w http.ResponseWriter, request *http.Request, bucket string) bool {
+	log := requestLogger(request)
 
+	exists, err := h.bucketExists(request.Context(), bucket)
 	if err != nil {
-		msg := fmt.Sprintf("Unable to sign request to Minio: %v", err)
-		logger.Error(msg)
-		http.Error(w, msg, http.StatusInternalServerError)
-		return
+		msg := fmt.Sprintf("Unable to check that bucket %s exists: %v", bucket, err)
+		log.Error(msg)
+		writeS3Error(w, request, serviceUnavailable(msg))
+		return false
+	}
+
+	if !exists {
+		log.Infof("Bucket %s doesn't exist", bucket)
+		writeS3Error(w, request, noSuchBucket())
+		return false
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(proxyReq)
+	return true
+}
+
+// headBackend sends a HEAD request for a path to Minio. The body of the response is already closed.
+func (h MinioHandler) headBackend(ctx context.Context, path string) (*http.Response, error) {
+	target, err := h.backendUrl(path, "")
 	if err != nil {
-		msg := fmt.Sprintf("Unable to proxy to Minio: %v", err)
-		logger.Error(msg)
-		http.Error(w, msg, http.StatusInternalServerError)
-		return
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	for key, value := range resp.Header {
-		for _, v := range value {
-			w.Header().Add(key, v)
-		}
+	head, err := http.NewRequestWithContext(ctx, http.MethodHead, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
 
+	err = h.signBackendRequest(head, sigv4.EmptyPayload)
+	if err != nil {
+		return nil, err
 	}
 
-	var response strings.Builder
-	body := io.TeeReader(resp.Body, &response)
+	resp, err := h.client.Do(head)
+	if err != nil {
+		return nil, err
+	}
 
-	w.WriteHeader(resp.StatusCode)
-	io.Copy(w, body)
+	return resp, resp.Body.Close()
+}
 
-	if resp.StatusCode != 200 {
-		logger.Infof("Response (%d): %s", resp.StatusCode, response.String())
-		logger.Infof("Request payload: %s", payload)
+// configSubresource returns the bucket subresource of the request if it is configuration stored by rainbow,
+// rather than by Minio.
+func configSubresource(request *http.Request) (Operation, SetDefaultFunc, bool) {
+	operation := getOperation(request)
+	if operation.Key != "" || operation.Subresource == "" {
+		return operation, nil, false
 	}
 
-	return
+	buildDefaultResponse, ok := supportedQueries[operation.Subresource]
+	return operation, buildDefaultResponse, ok
 }
 
 func (h MinioHandler) GetConfig(next http.Handler) http.Handler {
 	f := func(w http.ResponseWriter, request *http.Request) {
-		queries, ok := getQueryKeys(request)
+		operation, buildDefaultResponse, ok := configSubresource(request)
 		if !ok {
-			logger.Infof("unable to get queries from request context, continuing to next handler")
 			next.ServeHTTP(w, request)
 			return
 		}
 
-		if len(queries) != 1 {
-			logger.Infof("number of queries != 1: %d, continuing to next handler", len(queries))
-			next.ServeHTTP(w, request)
+		log := requestLogger(request)
+		bucket, query := operation.Bucket, operation.Subresource
+		if !h.checkBucketExists(w, request, bucket) {
 			return
 		}
 
-		query := queries[0]
-		buildDefaultResponse, ok := supportedQueries[query]
-		if !ok {
-			logger.Infof("%s is not a supported query, continuing to next handler", query)
-			next.ServeHTTP(w, request)
+		log.Infof("Loading %s config for bucket %s", query, bucket)
+
+		config, err := h.configurationService.LoadConfiguration(bucket, storedConfigType(query))
+		if err != nil {
+			log.Errorf("unable to load %s configuration for bucket %s: %v", query, bucket, err)
+			writeS3Error(w, request, err)
 			return
 		}
 
-		bucket := chi.URLParam(request, "bucket")
-		logger.Infof("Loading %s config for bucket %s", query, bucket)
-
-		config, err := h.configurationService.LoadConfiguration(bucket, query)
+		config, err = buildDefaultResponse(config)
 		if err != nil {
-			msg := fmt.Sprintf("unable to load %s configuration for bucket %s: %v", query, bucket, err)
-			logger.Error(msg)
-			http.Error(w, msg, http.StatusInternalServerError)
+			log.Infof("%s config for bucket %s is not available: %v", query, bucket, err)
+			writeS3Error(w, request, err)
 			return
 		}
 
-		config = buildDefaultResponse(config)
-
+		w.Header().Set("Content-Type", configContentType(query))
 		_, err = w.Write(config)
 		if err != nil {
-			logger.Warnf("unable to write %s configuration for bucket %s to response: %v", query, bucket, err)
+			log.Warnf("unable to write %s configuration for bucket %s to response: %v", query, bucket, err)
 		}
 	}
 
@@ -320,42 +373,45 @@ func (h MinioHandler) GetConfig(next http.Handler) http.Handler {
 
 func (h MinioHandler) PutConfig(next http.Handler) http.Handler {
 	f := func(w http.ResponseWriter, request *http.Request) {
-		queries, ok := getQueryKeys(request)
-		if !ok {
-			logger.Infof("unable to get queries from request context, continuing to next handler")
-			next.ServeHTTP(w, request)
+		if query, ok := derivedSubresource(request); ok {
+			requestLogger(request).Infof("%s config can't be changed", query)
+			writeS3Error(w, request, methodNotAllowed())
 			return
 		}
 
-		if len(queries) != 1 {
-			logger.Infof("number of queries != 1: %d, continuing to next handler", len(queries))
+		operation, _, ok := configSubresource(request)
+		if !ok {
 			next.ServeHTTP(w, request)
 			return
 		}
 
-		query := queries[0]
-		_, ok = supportedQueries[query]
-		if !ok {
-			logger.Infof("%s is not a supported query, continuing to next handler", query)
-			next.ServeHTTP(w, request)
+		log := requestLogger(request)
+		bucket, query := operation.Bucket, operation.Subresource
+		if !h.checkBucketExists(w, request, bucket) {
 			return
 		}
 
-		bucket := chi.URLParam(request, "bucket")
-		logger.Infof("saving %s config for bucket %s", query, bucket)
+		log.Infof("saving %s config for bucket %s", query, bucket)
 
 		payload, _ := io.ReadAll(request.Body)
 		request.Body.Close()
 
+		payload, err := normalizeConfiguration(bucket, query, payload)
+		if err != nil {
+			log.Warnf("rejecting %s config for bucket %s: %v", query, bucket, err)
+			writeS3Error(w, request, err)
+			return
+		}
+
+		defer h.configurationChanged(bucket, query)
 		path, err := h.configurationService.SaveConfiguration(bucket, query, payload)
 		if err != nil {
-			msg := fmt.Sprintf("unable to save %s configuration for bucket %s: %v", query, bucket, err)
-			logger.Error(msg)
-			http.Error(w, msg, http.StatusInternalServerError)
+			log.Errorf("unable to save %s configuration for bucket %s: %v", query, bucket, err)
+			writeS3Error(w, request, err)
 			return
 		}
 
-		logger.Infof("saved %s config for bucket %s to %s", query, bucket, path)
+		log.Infof("saved %s config for bucket %s to %s", query, bucket, path)
 
 		w.WriteHeader(http.StatusOK)
 	}
@@ -363,14 +419,84 @@ func (h MinioHandler) PutConfig(next http.Handler) http.Handler {
 	return http.HandlerFunc(f)
 }
 
+// DeleteConfig handles deleting a single type of configuration stored by rainbow, like DELETE ?cors,
+// without passing the request to Minio.
+func (h MinioHandler) DeleteConfig(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, request *http.Request) {
+		if query, ok := derivedSubresource(request); ok {
+			requestLogger(request).Infof("%s config can't be changed", query)
+			writeS3Error(w, request, methodNotAllowed())
+			return
+		}
+
+		operation, _, ok := configSubresource(request)
+		if !ok {
+			next.ServeHTTP(w, request)
+			return
+		}
+
+		log := requestLogger(request)
+		bucket, query := operation.Bucket, operation.Subresource
+
+		if !h.checkBucketExists(w, request, bucket) {
+			return
+		}
+
+		log.Infof("deleting %s config for bucket %s", query, bucket)
+
+		defer h.configurationChanged(bucket, query)
+		path, err := h.configurationService.DeleteConfiguration(bucket, query)
+		if err != nil {
+			log.Errorf("unable to delete %s configuration for bucket %s: %v", query, bucket, err)
+			writeS3Error(w, request, err)
+			return
+		}
+
+		log.Infof("deleted %s config for bucket %s from %s", query, bucket, path)
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	return http.HandlerFunc(f)
+}
+
+// CleanupConfig removes all configuration of a bucket once Minio has deleted it. Deleting a subresource,
+// like DELETE ?cors, doesn't remove anything else.
 func (h MinioHandler) CleanupConfig(next http.Handler) http.Handler {
 	f := func(w http.ResponseWriter, request *http.Request) {
-		bucket := chi.URLParam(request, "bucket")
-		logger.Infof("cleaning up config for bucket %s", bucket)
+		operation := getOperation(request)
+		if operation.Name != "DeleteBucket" {
+			next.ServeHTTP(w, request)
+			return
+		}
+
+		wrapped := ResponseWriter{
+			ResponseWriter: w,
+			Code:           new(int),
+		}
+
+		next.ServeHTTP(wrapped, request)
+
+		if *wrapped.Code >= 300 {
+			return
+		}
 
-		h.configurationService.CleanupAllConfiguration(bucket)
-		next.ServeHTTP(w, request)
+		requestLogger(request).Infof("cleaning up config for bucket %s", operation.Bucket)
+		h.configurationService.CleanupAllConfiguration(operation.Bucket)
+		for _, configType := range []string{"policy", "logging"} {
+			h.configurationChanged(operation.Bucket, configType)
+		}
 	}
 
 	return http.HandlerFunc(f)
 }
+
+// configurationChanged forgets what is cached about a type of configuration of the bucket.
+func (h MinioHandler) configurationChanged(bucket string, configType string) {
+	switch {
+	case configType == "policy":
+		h.policies.invalidate(bucket)
+	case configType == "logging" && h.accessLogService != nil:
+		h.accessLogService.LoggingChanged(bucket)
+	}
+}
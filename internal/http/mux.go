@@ -1,59 +1,59 @@
 package http
 
 import (
-	"context"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"net/http"
 )
 
 type RainbowContextKey string
 
-const queriesContextKey = RainbowContextKey("queries")
-
-func storeQueryKeys(next http.Handler) http.Handler {
-	f := func(w http.ResponseWriter, request *http.Request) {
-		var queries []string
-		for key := range request.URL.Query() {
-			queries = append(queries, key)
-		}
+func NewChiMux(minio MinioHandler, admin AdminHandler, auth AuthHandler) *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(assignRequestIds, middleware.Logger, minio.Websites, minio.VirtualHosts)
 
-		ctx := context.WithValue(request.Context(), queriesContextKey, queries)
-		r := request.Clone(ctx)
+	// bucket names cannot start with an underscore, so admin routes can't collide with them
+	r.Route("/_rainbow", func(r chi.Router) {
+		r.Get("/ca.pem", admin.DownloadCA)
 
-		next.ServeHTTP(w, r)
-	}
+		r.Route("/notifications/{bucket}", func(r chi.Router) {
+			r.Get("/explain", admin.ExplainNotifications)
+			r.Get("/status", admin.NotificationStatus)
+			r.With(auth.VerifySignatures).Post("/pause", admin.PauseNotifications)
+			r.With(auth.VerifySignatures).Post("/resume", admin.ResumeNotifications)
+		})
 
-	return http.HandlerFunc(f)
-}
+		// running lifecycle rules deletes objects, so it must be signed like any S3 request when signatures are verified
+		r.With(auth.VerifySignatures).Post("/lifecycle/run", admin.RunLifecycle)
+		r.With(auth.VerifySignatures).Post("/lifecycle/{bucket}/run", admin.RunLifecycle)
 
-func getQueryKeys(request *http.Request) ([]string, bool) {
-	ctx := request.Context()
-	queries, ok := ctx.Value(queriesContextKey).([]string)
-	return queries, ok
-}
+		r.With(auth.VerifySignatures).Post("/logging/flush", admin.FlushAccessLogs)
+	})
 
-func NewChiMux(minio MinioHandler) *chi.Mux {
-	r := chi.NewRouter()
-	r.Use(middleware.Logger, storeQueryKeys)
+	r.Group(func(r chi.Router) {
+		// preflight requests and CORS headers don't depend on authentication, like in S3, and access logs
+		// include requests that are denied
+		r.Use(identifyOperation, minio.AccessLog, minio.Preflight, minio.CorsHeaders)
+		r.Use(auth.VerifySignatures, auth.PresignedRequests, minio.EnforcePolicies, decodeChunkedUploads)
 
-	// list buckets
-	r.Get("/", minio.Proxy)
+		// list buckets
+		r.Get("/", minio.Proxy)
 
-	r.Route("/{bucket}", func(r chi.Router) {
-		r.Head("/*", minio.Proxy)
+		r.Route("/{bucket}", func(r chi.Router) {
+			r.With(minio.ReplicationStatus).
+				Head("/*", minio.Proxy)
 
-		r.With(minio.GetNotifications, minio.GetConfig).
-			Get("/*", minio.Proxy)
+			r.With(minio.GetNotifications, minio.GetConfig, minio.ReplicationStatus).
+				Get("/*", minio.Proxy)
 
-		r.With(minio.SendNotifications).
-			Post("/*", minio.Proxy)
+			r.With(minio.SendNotifications, minio.Replicate).
+				Post("/*", minio.Proxy)
 
-		r.With(minio.PutNotifications, minio.SendNotifications, minio.PutConfig).
-			Put("/*", minio.Proxy)
+			r.With(minio.PutNotifications, minio.SendNotifications, minio.Replicate, minio.PutConfig).
+				Put("/*", minio.Proxy)
 
-		r.With(minio.CleanupConfig).
-			Delete("/*", minio.Proxy)
+			r.With(minio.DeleteConfig, minio.CleanupConfig, minio.Replicate).
+				Delete("/*", minio.Proxy)
+		})
 	})
 
 	return r
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"github.com/ATenderholt/rainbow-storage/internal/settings"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testConfig creates the configuration of a test from flags, keeping its data in a temporary directory.
+func testConfig(t *testing.T, args ...string) *settings.Config {
+	cfg, _, err := settings.FromFlags("test", append([]string{"-data-path", t.TempDir()}, args...))
+	if err != nil {
+		t.Fatalf("Unable to create configuration: %v", err)
+	}
+
+	return cfg
+}
+
+// bucketServer starts a fake Minio where only the given buckets exist, for requests that rainbow answers itself.
+func bucketServer(t *testing.T, buckets ...string) *httptest.Server {
+	exists := make(map[string]bool)
+	for _, bucket := range buckets {
+		exists[bucket] = true
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !exists[strings.TrimPrefix(r.URL.Path, "/")] {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// testServices are the services used by the handlers of a test. Like in the application, the middleware of
+// services that are nil is disabled.
+type testServices struct {
+	notifications  NotificationService
+	configurations ConfigurationService
+	lifecycle      LifecycleService
+	replication    ReplicationService
+	accessLogs     AccessLogService
+}
+
+func (s testServices) minioHandler(cfg *settings.Config) MinioHandler {
+	return NewMinioHandler(cfg, NewBackendClient(), s.notifications, s.configurations, s.replication, s.accessLogs)
+}
+
+func newTestMux(cfg *settings.Config, services testServices) *chi.Mux {
+	admin := NewAdminHandler(cfg, services.notifications, services.lifecycle, services.accessLogs)
+	return NewChiMux(services.minioHandler(cfg), admin, NewAuthHandler(cfg))
+}
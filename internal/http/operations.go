@@ -0,0 +1,289 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const operationContextKey = RainbowContextKey("operation")
+
+const unknownOperation = "Unknown"
+
+// Operation is the S3 API operation of a request, named as in the S3 API reference.
+type Operation struct {
+	Name        string
+	Bucket      string
+	Key         string
+	Subresource string // query parameter selecting the subresource, i.e. cors for GetBucketCors
+}
+
+// bucketSubresources maps query parameters selecting a subresource of a bucket to the operation for each method.
+var bucketSubresources = map[string]map[string]string{
+	"accelerate": {
+		http.MethodGet: "GetBucketAccelerateConfiguration",
+		http.MethodPut: "PutBucketAccelerateConfiguration",
+	},
+	"acl": {
+		http.MethodGet: "GetBucketAcl",
+		http.MethodPut: "PutBucketAcl",
+	},
+	"analytics": {
+		http.MethodGet:    "GetBucketAnalyticsConfiguration",
+		http.MethodPut:    "PutBucketAnalyticsConfiguration",
+		http.MethodDelete: "DeleteBucketAnalyticsConfiguration",
+	},
+	"cors": {
+		http.MethodGet:    "GetBucketCors",
+		http.MethodPut:    "PutBucketCors",
+		http.MethodDelete: "DeleteBucketCors",
+	},
+	"delete": {
+		http.MethodPost: "DeleteObjects",
+	},
+	"encryption": {
+		http.MethodGet:    "GetBucketEncryption",
+		http.MethodPut:    "PutBucketEncryption",
+		http.MethodDelete: "DeleteBucketEncryption",
+	},
+	"intelligent-tiering": {
+		http.MethodGet:    "GetBucketIntelligentTieringConfiguration",
+		http.MethodPut:    "PutBucketIntelligentTieringConfiguration",
+		http.MethodDelete: "DeleteBucketIntelligentTieringConfiguration",
+	},
+	"inventory": {
+		http.MethodGet:    "GetBucketInventoryConfiguration",
+		http.MethodPut:    "PutBucketInventoryConfiguration",
+		http.MethodDelete: "DeleteBucketInventoryConfiguration",
+	},
+	"lifecycle": {
+		http.MethodGet:    "GetBucketLifecycleConfiguration",
+		http.MethodPut:    "PutBucketLifecycleConfiguration",
+		http.MethodDelete: "DeleteBucketLifecycle",
+	},
+	"location": {
+		http.MethodGet: "GetBucketLocation",
+	},
+	"logging": {
+		http.MethodGet: "GetBucketLogging",
+		http.MethodPut: "PutBucketLogging",
+	},
+	"metrics": {
+		http.MethodGet:    "GetBucketMetricsConfiguration",
+		http.MethodPut:    "PutBucketMetricsConfiguration",
+		http.MethodDelete: "DeleteBucketMetricsConfiguration",
+	},
+	"notification": {
+		http.MethodGet: "GetBucketNotificationConfiguration",
+		http.MethodPut: "PutBucketNotificationConfiguration",
+	},
+	"object-lock": {
+		http.MethodGet: "GetObjectLockConfiguration",
+		http.MethodPut: "PutObjectLockConfiguration",
+	},
+	"ownershipControls": {
+		http.MethodGet:    "GetBucketOwnershipControls",
+		http.MethodPut:    "PutBucketOwnershipControls",
+		http.MethodDelete: "DeleteBucketOwnershipControls",
+	},
+	"policy": {
+		http.MethodGet:    "GetBucketPolicy",
+		http.MethodPut:    "PutBucketPolicy",
+		http.MethodDelete: "DeleteBucketPolicy",
+	},
+	"policyStatus": {
+		http.MethodGet: "GetBucketPolicyStatus",
+	},
+	"publicAccessBlock": {
+		http.MethodGet:    "GetPublicAccessBlock",
+		http.MethodPut:    "PutPublicAccessBlock",
+		http.MethodDelete: "DeletePublicAccessBlock",
+	},
+	"replication": {
+		http.MethodGet:    "GetBucketReplication",
+		http.MethodPut:    "PutBucketReplication",
+		http.MethodDelete: "DeleteBucketReplication",
+	},
+	"requestPayment": {
+		http.MethodGet: "GetBucketRequestPayment",
+		http.MethodPut: "PutBucketRequestPayment",
+	},
+	"tagging": {
+		http.MethodGet:    "GetBucketTagging",
+		http.MethodPut:    "PutBucketTagging",
+		http.MethodDelete: "DeleteBucketTagging",
+	},
+	"uploads": {
+		http.MethodGet: "ListMultipartUploads",
+	},
+	"versioning": {
+		http.MethodGet: "GetBucketVersioning",
+		http.MethodPut: "PutBucketVersioning",
+	},
+	"versions": {
+		http.MethodGet: "ListObjectVersions",
+	},
+	"website": {
+		http.MethodGet:    "GetBucketWebsite",
+		http.MethodPut:    "PutBucketWebsite",
+		http.MethodDelete: "DeleteBucketWebsite",
+	},
+}
+
+// objectSubresources maps query parameters selecting a subresource of an object to the operation for each method.
+var objectSubresources = map[string]map[string]string{
+	"acl": {
+		http.MethodGet: "GetObjectAcl",
+		http.MethodPut: "PutObjectAcl",
+	},
+	"attributes": {
+		http.MethodGet: "GetObjectAttributes",
+	},
+	"legal-hold": {
+		http.MethodGet: "GetObjectLegalHold",
+		http.MethodPut: "PutObjectLegalHold",
+	},
+	"restore": {
+		http.MethodPost: "RestoreObject",
+	},
+	"retention": {
+		http.MethodGet: "GetObjectRetention",
+		http.MethodPut: "PutObjectRetention",
+	},
+	"select": {
+		http.MethodPost: "SelectObjectContent",
+	},
+	"tagging": {
+		http.MethodGet:    "GetObjectTagging",
+		http.MethodPut:    "PutObjectTagging",
+		http.MethodDelete: "DeleteObjectTagging",
+	},
+	"torrent": {
+		http.MethodGet: "GetObjectTorrent",
+	},
+	"uploadId": {
+		http.MethodGet:    "ListParts",
+		http.MethodPut:    "UploadPart",
+		http.MethodPost:   "CompleteMultipartUpload",
+		http.MethodDelete: "AbortMultipartUpload",
+	},
+	"uploads": {
+		http.MethodPost: "CreateMultipartUpload",
+	},
+}
+
+var bucketOperations = map[string]string{
+	http.MethodDelete: "DeleteBucket",
+	http.MethodGet:    "ListObjects",
+	http.MethodHead:   "HeadBucket",
+	http.MethodPost:   "PostObject",
+	http.MethodPut:    "CreateBucket",
+}
+
+var objectOperations = map[string]string{
+	http.MethodDelete: "DeleteObject",
+	http.MethodGet:    "GetObject",
+	http.MethodHead:   "HeadObject",
+	http.MethodPut:    "PutObject",
+}
+
+// splitPath splits a path-style request path into its bucket and key.
+func splitPath(path string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+
+	return parts[0], parts[1]
+}
+
+// findSubresource returns the first query parameter, in sorted order, that selects one of the subresources.
+func findSubresource(request *http.Request, subresources map[string]map[string]string) (string, map[string]string) {
+	query := request.URL.Query()
+
+	var keys []string
+	for key := range query {
+		if _, ok := subresources[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+
+	if len(keys) == 0 {
+		return "", nil
+	}
+
+	sort.Strings(keys)
+	return keys[0], subresources[keys[0]]
+}
+
+// classifyOperation identifies the S3 operation of a path-style request from its method, path, query
+// parameters and headers.
+func classifyOperation(request *http.Request) Operation {
+	bucket, key := splitPath(request.URL.Path)
+	operation := Operation{Name: unknownOperation, Bucket: bucket, Key: key}
+	isCopy := request.Header.Get("X-Amz-Copy-Source") != ""
+
+	switch {
+	case bucket == "":
+		if request.Method == http.MethodGet {
+			operation.Name = "ListBuckets"
+		}
+
+	case key == "":
+		subresource, operations := findSubresource(request, bucketSubresources)
+		if operations == nil {
+			operations = bucketOperations
+		}
+
+		if name, ok := operations[request.Method]; ok {
+			operation.Name = name
+			operation.Subresource = subresource
+		}
+
+		if operation.Name == "ListObjects" && request.URL.Query().Get("list-type") == "2" {
+			operation.Name = "ListObjectsV2"
+		}
+
+	default:
+		subresource, operations := findSubresource(request, objectSubresources)
+		if operations == nil {
+			operations = objectOperations
+		}
+
+		if name, ok := operations[request.Method]; ok {
+			operation.Name = name
+			operation.Subresource = subresource
+		}
+
+		switch {
+		case operation.Name == "PutObject" && isCopy:
+			operation.Name = "CopyObject"
+		case operation.Name == "UploadPart" && isCopy:
+			operation.Name = "UploadPartCopy"
+		}
+	}
+
+	return operation
+}
+
+// identifyOperation stores the S3 operation of each request in its context, for handlers and logging.
+func identifyOperation(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, request *http.Request) {
+		operation := classifyOperation(request)
+		ctx := context.WithValue(request.Context(), operationContextKey, operation)
+
+		next.ServeHTTP(w, request.WithContext(ctx))
+	}
+
+	return http.HandlerFunc(f)
+}
+
+func getOperation(request *http.Request) Operation {
+	operation, ok := request.Context().Value(operationContextKey).(Operation)
+	if !ok {
+		return Operation{Name: unknownOperation}
+	}
+
+	return operation
+}
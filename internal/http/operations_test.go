@@ -0,0 +1,127 @@
+package http
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClassifyOperation(t *testing.T) {
+	tests := []struct {
+		method      string
+		target      string
+		copySource  string
+		name        string
+		bucket      string
+		key         string
+		subresource string
+	}{
+		{http.MethodGet, "/", "", "ListBuckets", "", "", ""},
+		{http.MethodPut, "/bucket", "", "CreateBucket", "bucket", "", ""},
+		{http.MethodHead, "/bucket", "", "HeadBucket", "bucket", "", ""},
+		{http.MethodDelete, "/bucket", "", "DeleteBucket", "bucket", "", ""},
+		{http.MethodGet, "/bucket?prefix=dir/", "", "ListObjects", "bucket", "", ""},
+		{http.MethodGet, "/bucket/?list-type=2&prefix=dir/", "", "ListObjectsV2", "bucket", "", ""},
+		{http.MethodGet, "/bucket?versions", "", "ListObjectVersions", "bucket", "", "versions"},
+		{http.MethodGet, "/bucket?uploads", "", "ListMultipartUploads", "bucket", "", "uploads"},
+		{http.MethodGet, "/bucket?cors", "", "GetBucketCors", "bucket", "", "cors"},
+		{http.MethodPut, "/bucket?cors", "", "PutBucketCors", "bucket", "", "cors"},
+		{http.MethodDelete, "/bucket?cors", "", "DeleteBucketCors", "bucket", "", "cors"},
+		{http.MethodDelete, "/bucket?lifecycle", "", "DeleteBucketLifecycle", "bucket", "", "lifecycle"},
+		{http.MethodDelete, "/bucket?versioning", "", unknownOperation, "bucket", "", ""},
+		{http.MethodGet, "/bucket?notification", "", "GetBucketNotificationConfiguration", "bucket", "", "notification"},
+		{http.MethodPost, "/bucket?delete", "", "DeleteObjects", "bucket", "", "delete"},
+		{http.MethodGet, "/bucket/dir/key.txt", "", "GetObject", "bucket", "dir/key.txt", ""},
+		{http.MethodGet, "/bucket/key.txt?acl", "", "GetObjectAcl", "bucket", "key.txt", "acl"},
+		{http.MethodHead, "/bucket/key.txt", "", "HeadObject", "bucket", "key.txt", ""},
+		{http.MethodPut, "/bucket/key.txt", "", "PutObject", "bucket", "key.txt", ""},
+		{http.MethodPut, "/bucket/key.txt", "/other/key.txt", "CopyObject", "bucket", "key.txt", ""},
+		{http.MethodPut, "/bucket/key.txt?tagging", "", "PutObjectTagging", "bucket", "key.txt", "tagging"},
+		{http.MethodDelete, "/bucket/key.txt", "", "DeleteObject", "bucket", "key.txt", ""},
+		{http.MethodPost, "/bucket/key.txt?uploads", "", "CreateMultipartUpload", "bucket", "key.txt", "uploads"},
+		{http.MethodPut, "/bucket/key.txt?partNumber=2&uploadId=abc", "", "UploadPart", "bucket", "key.txt", "uploadId"},
+		{http.MethodPut, "/bucket/key.txt?partNumber=2&uploadId=abc", "/other/key.txt", "UploadPartCopy", "bucket", "key.txt", "uploadId"},
+		{http.MethodPost, "/bucket/key.txt?uploadId=abc", "", "CompleteMultipartUpload", "bucket", "key.txt", "uploadId"},
+		{http.MethodDelete, "/bucket/key.txt?uploadId=abc", "", "AbortMultipartUpload", "bucket", "key.txt", "uploadId"},
+		{http.MethodGet, "/bucket/key.txt?uploadId=abc", "", "ListParts", "bucket", "key.txt", "uploadId"},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(test.method, "http://localhost:9000"+test.target, nil)
+		if test.copySource != "" {
+			request.Header.Set("X-Amz-Copy-Source", test.copySource)
+		}
+
+		operation := classifyOperation(request)
+		description := test.method + " " + test.target
+		assert.Equal(t, test.name, operation.Name, description)
+		assert.Equal(t, test.bucket, operation.Bucket, description)
+		assert.Equal(t, test.key, operation.Key, description)
+		assert.Equal(t, test.subresource, operation.Subresource, description)
+	}
+}
+
+type cleanupRecorder struct {
+	storedConfigs
+	cleaned []string
+	deleted []string
+}
+
+func (r *cleanupRecorder) DeleteConfiguration(bucket string, configType string) (string, error) {
+	r.deleted = append(r.deleted, bucket+"?"+configType)
+	return "", nil
+}
+
+func (r *cleanupRecorder) CleanupAllConfiguration(bucket string) {
+	r.cleaned = append(r.cleaned, bucket)
+}
+
+func TestDeleteConfigOnlyDeleteBucketCleansUpEverything(t *testing.T) {
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := testConfig(t, "-backend-url", server.URL)
+
+	configs := &cleanupRecorder{}
+	mux := newTestMux(cfg, testServices{configurations: configs})
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "http://localhost:9000/bucket?cors", nil))
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Equal(t, []string{"bucket?cors"}, configs.deleted)
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "http://localhost:9000/bucket/key.txt?tagging", nil))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "http://localhost:9000/bucket/key.txt", nil))
+	assert.Equal(t, []string{"bucket?cors"}, configs.deleted)
+	assert.Empty(t, configs.cleaned)
+
+	status = http.StatusConflict
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "http://localhost:9000/bucket", nil))
+	assert.Empty(t, configs.cleaned)
+
+	status = http.StatusNoContent
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "http://localhost:9000/bucket", nil))
+	assert.Equal(t, []string{"bucket"}, configs.cleaned)
+}
+
+func TestDeleteConfigOfMissingBucket(t *testing.T) {
+	cfg := testConfig(t, "-backend-url", bucketServer(t).URL)
+
+	configs := &cleanupRecorder{}
+	mux := newTestMux(cfg, testServices{configurations: configs})
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "http://localhost:9000/missing?cors", nil))
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "<Code>NoSuchBucket</Code>")
+	assert.Empty(t, configs.deleted)
+}
@@ -0,0 +1,252 @@
+package http
+
+import (
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/ATenderholt/rainbow-storage/internal/sigv4"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// policyActions are the IAM actions of operations that aren't named s3:<operation>.
+var policyActions = map[string]string{
+	"AbortMultipartUpload":                        "s3:AbortMultipartUpload",
+	"CompleteMultipartUpload":                     "s3:PutObject",
+	"CopyObject":                                  "s3:PutObject",
+	"CreateMultipartUpload":                       "s3:PutObject",
+	"DeleteBucketCors":                            "s3:PutBucketCORS",
+	"DeleteBucketEncryption":                      "s3:PutEncryptionConfiguration",
+	"DeleteBucketLifecycle":                       "s3:PutLifecycleConfiguration",
+	"DeleteBucketOwnershipControls":               "s3:PutBucketOwnershipControls",
+	"DeleteBucketReplication":                     "s3:PutReplicationConfiguration",
+	"DeleteBucketTagging":                         "s3:PutBucketTagging",
+	"DeleteBucketAnalyticsConfiguration":          "s3:PutAnalyticsConfiguration",
+	"DeleteBucketIntelligentTieringConfiguration": "s3:PutIntelligentTieringConfiguration",
+	"DeleteBucketInventoryConfiguration":          "s3:PutInventoryConfiguration",
+	"DeleteBucketMetricsConfiguration":            "s3:PutMetricsConfiguration",
+	"DeleteObjects":                               "s3:DeleteObject",
+	"DeletePublicAccessBlock":                     "s3:PutBucketPublicAccessBlock",
+	"GetBucketAccelerateConfiguration":            "s3:GetAccelerateConfiguration",
+	"GetBucketAnalyticsConfiguration":             "s3:GetAnalyticsConfiguration",
+	"GetBucketCors":                               "s3:GetBucketCORS",
+	"GetBucketEncryption":                         "s3:GetEncryptionConfiguration",
+	"GetBucketIntelligentTieringConfiguration":    "s3:GetIntelligentTieringConfiguration",
+	"GetBucketInventoryConfiguration":             "s3:GetInventoryConfiguration",
+	"GetBucketLifecycleConfiguration":             "s3:GetLifecycleConfiguration",
+	"GetBucketMetricsConfiguration":               "s3:GetMetricsConfiguration",
+	"GetBucketNotificationConfiguration":          "s3:GetBucketNotification",
+	"GetBucketReplication":                        "s3:GetReplicationConfiguration",
+	"GetObjectAttributes":                         "s3:GetObject",
+	"GetObjectLockConfiguration":                  "s3:GetBucketObjectLockConfiguration",
+	"GetPublicAccessBlock":                        "s3:GetBucketPublicAccessBlock",
+	"HeadBucket":                                  "s3:ListBucket",
+	"HeadObject":                                  "s3:GetObject",
+	"ListMultipartUploads":                        "s3:ListBucketMultipartUploads",
+	"ListObjects":                                 "s3:ListBucket",
+	"ListObjectsV2":                               "s3:ListBucket",
+	"ListObjectVersions":                          "s3:ListBucketVersions",
+	"ListParts":                                   "s3:ListMultipartUploadParts",
+	"PostObject":                                  "s3:PutObject",
+	"PutBucketAccelerateConfiguration":            "s3:PutAccelerateConfiguration",
+	"PutBucketAnalyticsConfiguration":             "s3:PutAnalyticsConfiguration",
+	"PutBucketCors":                               "s3:PutBucketCORS",
+	"PutBucketEncryption":                         "s3:PutEncryptionConfiguration",
+	"PutBucketIntelligentTieringConfiguration":    "s3:PutIntelligentTieringConfiguration",
+	"PutBucketInventoryConfiguration":             "s3:PutInventoryConfiguration",
+	"PutBucketLifecycleConfiguration":             "s3:PutLifecycleConfiguration",
+	"PutBucketMetricsConfiguration":               "s3:PutMetricsConfiguration",
+	"PutBucketNotificationConfiguration":          "s3:PutBucketNotification",
+	"PutBucketReplication":                        "s3:PutReplicationConfiguration",
+	"PutObjectLockConfiguration":                  "s3:PutBucketObjectLockConfiguration",
+	"PutPublicAccessBlock":                        "s3:PutBucketPublicAccessBlock",
+	"SelectObjectContent":                         "s3:GetObject",
+	"UploadPart":                                  "s3:PutObject",
+	"UploadPartCopy":                              "s3:PutObject",
+}
+
+// policyManagementOperations are always allowed for signed requests, like S3 does for the bucket owner, so that
+// a policy can't lock everyone out of changing it.
+var policyManagementOperations = map[string]bool{
+	"DeleteBucketPolicy":    true,
+	"GetBucketPolicy":       true,
+	"GetBucketPolicyStatus": true,
+	"PutBucketPolicy":       true,
+}
+
+func policyAction(operation Operation, request *http.Request) string {
+	if operation.Name == "GetObject" && request.URL.Query().Get("versionId") != "" {
+		return "s3:GetObjectVersion"
+	}
+
+	if action, ok := policyActions[operation.Name]; ok {
+		return action
+	}
+
+	return "s3:" + operation.Name
+}
+
+func policyResource(operation Operation) string {
+	if operation.Key == "" {
+		return "arn:aws:s3:::" + operation.Bucket
+	}
+
+	return "arn:aws:s3:::" + operation.Bucket + "/" + operation.Key
+}
+
+// callerAccessKey returns the access key of a verified signature or, if signatures aren't verified, the
+// access key the request claims to be signed with. It is empty for anonymous requests.
+func callerAccessKey(request *http.Request) string {
+	if signature, ok := getSignature(request); ok {
+		return signature.AccessKey
+	}
+
+	accessKey, _ := sigv4.AccessKey(originalRequest(request))
+	return accessKey
+}
+
+func policyConditions(request *http.Request) map[string]string {
+	conditions := map[string]string{
+		"aws:SecureTransport": strconv.FormatBool(request.TLS != nil),
+	}
+
+	if host, _, err := net.SplitHostPort(request.RemoteAddr); err == nil {
+		conditions["aws:SourceIp"] = host
+	}
+
+	query := request.URL.Query()
+	if _, ok := query["prefix"]; ok {
+		conditions["s3:prefix"] = query.Get("prefix")
+	}
+
+	return conditions
+}
+
+// policyCache keeps the parsed policy of each bucket, so that requests don't load and parse it again. Buckets
+// without a policy are cached as nil. Entries are removed whenever the policy of a bucket changes.
+type policyCache struct {
+	lock     *sync.RWMutex
+	policies map[string]*domain.BucketPolicy
+}
+
+func newPolicyCache() policyCache {
+	return policyCache{
+		lock:     &sync.RWMutex{},
+		policies: make(map[string]*domain.BucketPolicy),
+	}
+}
+
+func (c policyCache) get(bucket string) (*domain.BucketPolicy, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	policy, ok := c.policies[bucket]
+	return policy, ok
+}
+
+func (c policyCache) put(bucket string, policy *domain.BucketPolicy) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.policies[bucket] = policy
+}
+
+func (c policyCache) invalidate(bucket string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.policies, bucket)
+}
+
+// loadPolicy returns the parsed policy of the bucket, or nil if it doesn't have one.
+func (h MinioHandler) loadPolicy(request *http.Request, bucket string) (*domain.BucketPolicy, error) {
+	if policy, ok := h.policies.get(bucket); ok {
+		return policy, nil
+	}
+
+	log := requestLogger(request)
+	config, err := h.configurationService.LoadConfiguration(bucket, "policy")
+	if err != nil {
+		log.Errorf("unable to load policy for bucket %s: %v", bucket, err)
+		return nil, err
+	}
+
+	var policy *domain.BucketPolicy
+	if len(config) > 0 {
+		parsed, err := domain.ParseBucketPolicy(config)
+		if err != nil {
+			log.Errorf("unable to parse policy for bucket %s: %v", bucket, err)
+			return nil, internalError("Unable to decode stored bucket policy")
+		}
+		policy = &parsed
+	}
+
+	h.policies.put(bucket, policy)
+	return policy, nil
+}
+
+// EnforcePolicies evaluates the policy of the bucket before a request is proxied. Requests are rejected if
+// a statement denies them or, for anonymous requests, if no statement allows them. In log-only mode the
+// decision is only logged.
+func (h MinioHandler) EnforcePolicies(next http.Handler) http.Handler {
+	if h.configurationService == nil {
+		return next
+	}
+
+	f := func(w http.ResponseWriter, request *http.Request) {
+		operation := getOperation(request)
+		accessKey := callerAccessKey(request)
+		if operation.Bucket == "" || (accessKey != "" && policyManagementOperations[operation.Name]) {
+			next.ServeHTTP(w, request)
+			return
+		}
+
+		err := h.checkPolicy(request, operation, accessKey)
+		if err != nil {
+			writeS3Error(w, request, err)
+			return
+		}
+
+		next.ServeHTTP(w, request)
+	}
+
+	return http.HandlerFunc(f)
+}
+
+// checkPolicy returns an error if the policy of the bucket doesn't allow the operation by the caller, who is
+// anonymous if the access key is empty. Buckets without a policy allow everything.
+func (h MinioHandler) checkPolicy(request *http.Request, operation Operation, accessKey string) error {
+	if h.configurationService == nil {
+		return nil
+	}
+
+	policy, err := h.loadPolicy(request, operation.Bucket)
+	if err != nil || policy == nil {
+		return err
+	}
+
+	log := requestLogger(request)
+	policyRequest := domain.PolicyRequest{
+		Action:     policyAction(operation, request),
+		Resource:   policyResource(operation),
+		Account:    h.cfg.AccountNumber,
+		AccessKey:  accessKey,
+		Conditions: policyConditions(request),
+	}
+
+	decision, statement := policy.Evaluate(policyRequest)
+	denied := decision == domain.PolicyDenied || (policyRequest.IsAnonymous() && decision != domain.PolicyAllowed)
+	if !denied {
+		return nil
+	}
+
+	if h.cfg.PolicyLogOnly {
+		log.Warnf("Policy of bucket %s would deny %s on %s by %q (statement %q, decision %s)",
+			operation.Bucket, policyRequest.Action, policyRequest.Resource, accessKey, statement.Sid, decision)
+		return nil
+	}
+
+	log.Warnf("Policy of bucket %s denies %s on %s by %q (statement %q, decision %s)",
+		operation.Bucket, policyRequest.Action, policyRequest.Resource, accessKey, statement.Sid, decision)
+	return accessDenied("Access Denied")
+}
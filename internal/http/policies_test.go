@@ -0,0 +1,100 @@
+package http
+
+import (
+	"crypto/tls"
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// signedBy adds an Authorization header for the access key, which is trusted when signatures aren't verified
+func signedBy(accessKey string, request *http.Request) *http.Request {
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/20261019/us-west-2/s3/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, Signature=0123456789abcdef")
+	return request
+}
+
+const testPolicy = `{"Statement": [
+	{"Sid": "PublicRead", "Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/public/*"},
+	{"Sid": "OnlyHttps", "Effect": "Deny", "Principal": "*", "Action": "s3:PutObject", "Resource": "arn:aws:s3:::bucket/*",
+		"Condition": {"Bool": {"aws:SecureTransport": "false"}}},
+	{"Sid": "NoListingSecrets", "Effect": "Deny", "Principal": {"AWS": "arn:aws:iam::271828182845:user/intern"},
+		"Action": "s3:ListBucket", "Resource": "arn:aws:s3:::bucket", "Condition": {"StringLike": {"s3:prefix": "secret/*"}}}
+]}`
+
+func TestEnforcePolicies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	for _, logOnly := range []bool{false, true} {
+		args := []string{"-backend-url", server.URL, "-data-path", t.TempDir()}
+		if logOnly {
+			args = append(args, "-policy-log-only")
+		}
+
+		cfg := testConfig(t, args...)
+
+		configs := storedConfigs{configs: map[string][]byte{"bucket?policy": []byte(testPolicy)}}
+		events := eventRecorder{events: make(chan domain.NotificationEvent, 10)}
+		mux := newTestMux(cfg, testServices{notifications: events, configurations: configs})
+
+		https := httptest.NewRequest(http.MethodPut, "http://localhost:9000/bucket/key.txt", nil)
+		https.TLS = &tls.ConnectionState{}
+
+		tests := []struct {
+			name    string
+			request *http.Request
+			denied  bool
+		}{
+			{"anonymous public read", httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket/public/a.txt", nil), false},
+			{"anonymous public version read", httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket/public/a.txt?versionId=v1", nil), true},
+			{"anonymous private read", httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket/private/a.txt", nil), true},
+			{"signed private read", signedBy("owner", httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket/private/a.txt", nil)), false},
+			{"put over http", signedBy("owner", httptest.NewRequest(http.MethodPut, "http://localhost:9000/bucket/key.txt", nil)), true},
+			{"put over https", signedBy("owner", https), false},
+			{"intern lists secrets", signedBy("intern", httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket?prefix=secret/", nil)), true},
+			{"intern lists others", signedBy("intern", httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket?prefix=public/", nil)), false},
+			{"owner lists secrets", signedBy("owner", httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket?prefix=secret/", nil)), false},
+			{"other bucket", httptest.NewRequest(http.MethodGet, "http://localhost:9000/other/a.txt", nil), false},
+			{"owner reads policy", signedBy("owner", httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket?policy", nil)), false},
+		}
+
+		for _, test := range tests {
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, test.request)
+
+			if test.denied && !logOnly {
+				assert.Equal(t, http.StatusForbidden, recorder.Code, test.name)
+				assert.Contains(t, recorder.Body.String(), "<Code>AccessDenied</Code>", test.name)
+			} else {
+				assert.Equal(t, http.StatusOK, recorder.Code, test.name)
+			}
+		}
+	}
+}
+
+func TestEnforcePoliciesAfterPolicyChanges(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	cfg := testConfig(t, "-backend-url", server.URL)
+
+	configs := storedConfigs{configs: map[string][]byte{"bucket?policy": []byte(testPolicy)}}
+	mux := newTestMux(cfg, testServices{configurations: configs})
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket/private/a.txt", nil))
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+
+	policy := `{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}]}`
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, signedBy("owner", httptest.NewRequest(http.MethodPut, "http://localhost:9000/bucket?policy", strings.NewReader(policy))))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket/private/a.txt", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
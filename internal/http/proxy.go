@@ -0,0 +1,172 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/ATenderholt/rainbow-storage/internal/sigv4"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxLoggedResponse is how much of an error response from Minio is kept for logging
+const maxLoggedResponse = 4096
+
+// limitedBuffer keeps the first max bytes written to it and silently discards the rest.
+type limitedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	remaining := b.max - b.Len()
+	if remaining > 0 {
+		if len(p) > remaining {
+			b.Buffer.Write(p[:remaining])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+
+	return len(p), nil
+}
+
+// payloadHash decides the x-amz-content-sha256 used when re-signing a request for Minio. Bodies aren't
+// buffered to hash them, so the client's hash is reused if it sent one, otherwise the payload is unsigned.
+func payloadHash(request *http.Request) string {
+	if request.ContentLength == 0 {
+		return sigv4.EmptyPayload
+	}
+
+	hash := strings.ToLower(request.Header.Get("X-Amz-Content-Sha256"))
+	if isSha256Hex(hash) {
+		return hash
+	}
+
+	return sigv4.UnsignedPayload
+}
+
+func isSha256Hex(value string) bool {
+	if len(value) != sha256.Size*2 {
+		return false
+	}
+
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
+// backendUrl returns the URL of a path-style request to Minio.
+func (h MinioHandler) backendUrl(path string, rawQuery string) (*url.URL, error) {
+	target, err := url.Parse(h.cfg.MinioUrl())
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse backend URL %s: %v", h.cfg.MinioUrl(), err)
+	}
+
+	target.Path = path
+	target.RawPath = sigv4.EscapePath(path)
+	target.RawQuery = rawQuery
+	return target, nil
+}
+
+// signBackendRequest signs a request to Minio with the backend credentials.
+func (h MinioHandler) signBackendRequest(proxyReq *http.Request, hash string) error {
+	proxyReq.Header.Set("X-Amz-Content-Sha256", hash)
+	credentials := aws.Credentials{AccessKeyID: h.cfg.BackendAccessKey, SecretAccessKey: h.cfg.BackendSecretKey}
+
+	signer := v4.NewSigner(func(options *v4.SignerOptions) {
+		options.DisableURIPathEscaping = true
+	})
+	return signer.SignHTTP(proxyReq.Context(), credentials, proxyReq, hash, "s3", h.cfg.BackendRegion, time.Now())
+}
+
+func (h MinioHandler) Proxy(w http.ResponseWriter, request *http.Request) {
+	log := requestLogger(request)
+
+	target, err := h.backendUrl(request.URL.Path, request.URL.RawQuery)
+	if err != nil {
+		log.Error(err)
+		writeS3Error(w, request, internalError(err.Error()))
+		return
+	}
+
+	log.Infof("Forwarding %s to %s", request.Method, target)
+
+	var body io.Reader = request.Body
+	if request.ContentLength == 0 {
+		body = http.NoBody
+	}
+
+	// the request to Minio is cancelled if the client goes away, so that transfers don't keep running
+	proxyReq, err := http.NewRequestWithContext(request.Context(), request.Method, target.String(), body)
+	if err != nil {
+		msg := fmt.Sprintf("Unable to create request to Minio: %v", err)
+		log.Error(msg)
+		writeS3Error(w, request, internalError(msg))
+		return
+	}
+
+	forwardRequestHeaders(proxyReq.Header, request.Header)
+	proxyReq.ContentLength = request.ContentLength
+
+	hash := payloadHash(request)
+	if h.cfg.ForwardChecksums && encodeTrailingChecksum(proxyReq, request) {
+		hash = sigv4.StreamingUnsignedPayload
+	}
+
+	err = h.signBackendRequest(proxyReq, hash)
+	if err != nil {
+		msg := fmt.Sprintf("Unable to sign request to Minio: %v", err)
+		log.Error(msg)
+		writeS3Error(w, request, internalError(msg))
+		return
+	}
+
+	resp, err := h.client.Do(proxyReq)
+
+	// problems decoding the body, like a chunk signature that doesn't match, are returned to the client
+	var sigErr sigv4.Error
+	if errors.As(err, &sigErr) {
+		log.Warnf("Unable to read body of %s %s: %v", request.Method, request.URL.Path, err)
+		writeS3Error(w, request, sigErr)
+		return
+	}
+
+	if err != nil && request.Context().Err() != nil {
+		log.Infof("Client went away during %s %s: %v", request.Method, request.URL.Path, err)
+		return
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("Unable to proxy to Minio: %v", err)
+		log.Error(msg)
+		writeS3Error(w, request, serviceUnavailable(msg))
+		return
+	}
+	defer resp.Body.Close()
+
+	forwardResponseHeaders(w.Header(), resp.Header)
+	w.WriteHeader(resp.StatusCode)
+
+	if resp.StatusCode < 300 {
+		_, err = io.Copy(w, resp.Body)
+		if err != nil {
+			log.Warnf("Unable to copy response from Minio for %s %s: %v", request.Method, request.URL.Path, err)
+		}
+		return
+	}
+
+	response := limitedBuffer{max: maxLoggedResponse}
+	_, err = io.Copy(w, io.TeeReader(resp.Body, &response))
+	if err != nil {
+		log.Warnf("Unable to copy response from Minio for %s %s: %v", request.Method, request.URL.Path, err)
+	}
+
+	log.Infof("Response (%d): %s", resp.StatusCode, response.String())
+}
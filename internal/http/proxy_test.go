@@ -0,0 +1,284 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/ATenderholt/rainbow-storage/internal/settings"
+	"github.com/ATenderholt/rainbow-storage/internal/sigv4"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type receivedRequest struct {
+	Path   string
+	Query  string
+	Hash   string
+	Body   string
+	Header http.Header
+}
+
+// newVerifyingBackend starts a fake Minio that rejects requests unless both their signature and the
+// x-amz-content-sha256 of their body are correct.
+func newVerifyingBackend(t *testing.T, received chan receivedRequest) *settings.Config {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		hash := r.Header.Get("X-Amz-Content-Sha256")
+
+		sum := sha256.Sum256(body)
+		if hash != sigv4.UnsignedPayload && hash != sigv4.StreamingUnsignedPayload && hash != hex.EncodeToString(sum[:]) {
+			http.Error(w, "XAmzContentSHA256Mismatch", http.StatusBadRequest)
+			return
+		}
+
+		authorization := r.Header.Get("Authorization")
+		signedHeaders := authorization[strings.Index(authorization, "SignedHeaders=")+len("SignedHeaders="):]
+		signedHeaders = signedHeaders[:strings.Index(signedHeaders, ",")]
+
+		check, _ := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+		check.ContentLength = r.ContentLength
+		for _, name := range strings.Split(signedHeaders, ";") {
+			if name != "host" && name != "content-length" {
+				check.Header[http.CanonicalHeaderKey(name)] = r.Header.Values(name)
+			}
+		}
+
+		date, _ := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+		credentials := aws.Credentials{AccessKeyID: "backend", SecretAccessKey: "backendsecret"}
+		signer := v4.NewSigner(func(options *v4.SignerOptions) {
+			options.DisableURIPathEscaping = true
+		})
+		_ = signer.SignHTTP(r.Context(), credentials, check, hash, "s3", "eu-central-1", date)
+
+		if check.Header.Get("Authorization") != authorization {
+			http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+			return
+		}
+
+		received <- receivedRequest{Path: r.URL.Path, Query: r.URL.RawQuery, Hash: hash, Body: string(body), Header: r.Header}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	t.Cleanup(server.Close)
+
+	cfg := testConfig(t, "-backend-url", server.URL, "-backend-access-key", "backend", "-backend-secret-key", "backendsecret", "-backend-region", "eu-central-1")
+
+	return cfg
+}
+
+func TestProxySignsPayloadForMinio(t *testing.T) {
+	received := make(chan receivedRequest, 1)
+	cfg := newVerifyingBackend(t, received)
+	handler := testServices{}.minioHandler(cfg)
+
+	content := "some file contents"
+	sum := sha256.Sum256([]byte(content))
+	contentHash := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		hash   string
+		want   string
+	}{
+		{"empty body", http.MethodGet, "/bucket/key.txt", "", "", sigv4.EmptyPayload},
+		{"body with client hash", http.MethodPut, "/bucket/key.txt", content, contentHash, contentHash},
+		{"body without client hash", http.MethodPut, "/bucket/key.txt", content, "", sigv4.UnsignedPayload},
+		{"body with streaming hash", http.MethodPut, "/bucket/key.txt", content, "STREAMING-AWS4-HMAC-SHA256-PAYLOAD", sigv4.UnsignedPayload},
+		{"key needing escapes", http.MethodPut, "/bucket/dir/some key+(1).txt", content, contentHash, contentHash},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, "http://localhost:9000/bucket/x", strings.NewReader(test.body))
+			request.URL.Path = test.path
+			request.URL.RawPath = ""
+			if test.hash != "" {
+				request.Header.Set("X-Amz-Content-Sha256", test.hash)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.Proxy(recorder, request)
+
+			if !assert.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String()) {
+				return
+			}
+
+			result := <-received
+			assert.Equal(t, test.path, result.Path)
+			assert.Equal(t, test.want, result.Hash)
+			assert.Equal(t, test.body, result.Body)
+		})
+	}
+}
+
+func TestProxyCancelsBackendRequestWhenClientGoesAway(t *testing.T) {
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-r.Context().Done():
+			close(cancelled)
+		case <-time.After(5 * time.Second):
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := testConfig(t, "-backend-url", server.URL)
+
+	handler := testServices{}.minioHandler(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	request := httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket/key.txt", nil).WithContext(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		handler.Proxy(httptest.NewRecorder(), request)
+		close(done)
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Request to backend was not cancelled")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Proxy did not return after the client went away")
+	}
+}
+
+func TestProxyReturnsRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://elsewhere/bucket/key.txt", http.StatusTemporaryRedirect)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := testConfig(t, "-backend-url", server.URL)
+
+	handler := testServices{}.minioHandler(cfg)
+	recorder := httptest.NewRecorder()
+	handler.Proxy(recorder, httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket/key.txt", nil))
+
+	assert.Equal(t, http.StatusTemporaryRedirect, recorder.Code)
+	assert.Equal(t, "http://elsewhere/bucket/key.txt", recorder.Header().Get("Location"))
+}
+
+func TestNotificationSizeFromMinio(t *testing.T) {
+	server := objectServer(t, map[string]string{"/bucket/copy.txt": "hello"}, nil)
+	cfg := testConfig(t, "-backend-url", server.URL)
+
+	events := make(chan domain.NotificationEvent, 1)
+	mux := newTestMux(cfg, testServices{notifications: eventRecorder{events: events}})
+
+	request := httptest.NewRequest(http.MethodPut, "http://localhost:9000/bucket/copy.txt", nil)
+	request.Header.Set("X-Amz-Copy-Source", "/bucket/original.txt")
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, request)
+
+	if !assert.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String()) {
+		return
+	}
+
+	event := <-events
+	assert.Equal(t, "copy.txt", event.Key)
+	assert.Equal(t, int64(5), event.Size)
+}
+
+func TestProxyStreamsLargeBodies(t *testing.T) {
+	const chunkSize = 1 << 20
+	const chunkCount = 32
+
+	chunk := []byte(strings.Repeat("x", chunkSize))
+	firstChunk := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadFull(r.Body, make([]byte, chunkSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		close(firstChunk)
+
+		rest, _ := io.Copy(io.Discard, r.Body)
+		_, _ = w.Write([]byte(strconv.FormatInt(chunkSize+rest, 10)))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := testConfig(t, "-backend-url", server.URL)
+	handler := testServices{}.minioHandler(cfg)
+
+	// the rest of the body is only written once Minio received the first chunk, which can't happen if the proxy
+	// buffers the body before sending it on
+	body, writer := io.Pipe()
+	go func() {
+		_, _ = writer.Write(chunk)
+
+		select {
+		case <-firstChunk:
+		case <-time.After(5 * time.Second):
+			_ = writer.CloseWithError(io.ErrUnexpectedEOF)
+			return
+		}
+
+		for i := 1; i < chunkCount; i++ {
+			_, _ = writer.Write(chunk)
+		}
+		_ = writer.Close()
+	}()
+
+	request := httptest.NewRequest(http.MethodPut, "http://localhost:9000/bucket/large.bin", body)
+	request.ContentLength = chunkSize * chunkCount
+
+	recorder := httptest.NewRecorder()
+	handler.Proxy(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, strconv.Itoa(chunkSize*chunkCount), recorder.Body.String())
+}
+
+func TestProxyReturnsBackendErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>"))
+	}))
+
+	cfg := testConfig(t, "-backend-url", server.URL)
+	handler := testServices{}.minioHandler(cfg)
+
+	recorder := httptest.NewRecorder()
+	handler.Proxy(recorder, httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket/missing.txt", nil))
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, "application/xml", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "<Code>NoSuchKey</Code>")
+
+	// once Minio is gone, clients get an error rather than a dropped connection
+	server.Close()
+
+	recorder = httptest.NewRecorder()
+	handler.Proxy(recorder, httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket/missing.txt", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "<Code>ServiceUnavailable</Code>")
+}
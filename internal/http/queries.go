@@ -3,41 +3,150 @@ package http
 import (
 	"encoding/xml"
 	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"net/http"
 )
 
-type SetDefaultFunc func([]byte) []byte
+// SetDefaultFunc builds the response for stored configuration, which is empty if it was never set.
+type SetDefaultFunc func([]byte) ([]byte, error)
+
+// Documents returned by S3 for subresources that were never configured
+const (
+	defaultAcl = `<AccessControlPolicy xmlns="http://s3.amazonaws.com/doc/2006-03-01/">` +
+		`<Owner><ID>` + defaultOwnerId + `</ID><DisplayName>rainbow</DisplayName></Owner>` +
+		`<AccessControlList><Grant>` +
+		`<Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="CanonicalUser">` +
+		`<ID>` + defaultOwnerId + `</ID><DisplayName>rainbow</DisplayName></Grantee>` +
+		`<Permission>FULL_CONTROL</Permission></Grant></AccessControlList></AccessControlPolicy>`
+	defaultLogging        = `<BucketLoggingStatus xmlns="http://s3.amazonaws.com/doc/2006-03-01/"></BucketLoggingStatus>`
+	defaultRequestPayment = `<RequestPaymentConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Payer>BucketOwner</Payer></RequestPaymentConfiguration>`
+	defaultVersioning     = `<VersioningConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"></VersioningConfiguration>`
+
+	defaultOwnerId = "75aa57f09aa0c8caeab4f8c24e99d10f8e7faeebf76c078efc7c6caea54ba06a"
+)
 
 var supportedQueries map[string]SetDefaultFunc
 
 func init() {
 	supportedQueries = make(map[string]SetDefaultFunc)
 	supportedQueries["accelerate"] = defaultAccelerationConfiguration
-	supportedQueries["acl"] = bytesPassThrough
-	supportedQueries["cors"] = bytesPassThrough
-	supportedQueries["encryption"] = bytesPassThrough
-	supportedQueries["lifecycle"] = bytesPassThrough
-	supportedQueries["logging"] = bytesPassThrough
-	supportedQueries["object-lock"] = bytesPassThrough
-	supportedQueries["policy"] = bytesPassThrough
-	supportedQueries["replication"] = bytesPassThrough
-	supportedQueries["requestPayment"] = bytesPassThrough
-	supportedQueries["tagging"] = bytesPassThrough
-	supportedQueries["versioning"] = bytesPassThrough
-	supportedQueries["website"] = bytesPassThrough
+	supportedQueries["acl"] = defaultDocument(defaultAcl)
+	supportedQueries["cors"] = notConfigured("NoSuchCORSConfiguration", "The CORS configuration does not exist")
+	supportedQueries["encryption"] = notConfigured("ServerSideEncryptionConfigurationNotFoundError", "The server side encryption configuration was not found")
+	supportedQueries["lifecycle"] = notConfigured("NoSuchLifecycleConfiguration", "The lifecycle configuration does not exist")
+	supportedQueries["logging"] = defaultDocument(defaultLogging)
+	supportedQueries["object-lock"] = notConfigured("ObjectLockConfigurationNotFoundError", "Object Lock configuration does not exist for this bucket")
+	supportedQueries["policy"] = notConfigured("NoSuchBucketPolicy", "The bucket policy does not exist")
+	supportedQueries["policyStatus"] = policyStatus
+	supportedQueries["replication"] = notConfigured("ReplicationConfigurationNotFoundError", "The replication configuration was not found")
+	supportedQueries["requestPayment"] = defaultDocument(defaultRequestPayment)
+	supportedQueries["tagging"] = notConfigured("NoSuchTagSet", "The TagSet does not exist")
+	supportedQueries["versioning"] = defaultDocument(defaultVersioning)
+	supportedQueries["website"] = notConfigured("NoSuchWebsiteConfiguration", "The specified bucket does not have a website configuration")
+}
+
+// derivedConfiguration are subresources that are computed from the stored configuration of another one.
+var derivedConfiguration = map[string]string{
+	"policyStatus": "policy",
+}
+
+// derivedSubresource returns the subresource of a bucket request if it is computed from other configuration. S3
+// only allows reading them, so other methods aren't classified as operations and have to be caught by the query.
+func derivedSubresource(request *http.Request) (string, bool) {
+	if getOperation(request).Key != "" {
+		return "", false
+	}
+
+	query := request.URL.Query()
+	for subresource := range derivedConfiguration {
+		if _, ok := query[subresource]; ok {
+			return subresource, true
+		}
+	}
+
+	return "", false
+}
+
+// storedConfigType returns the type of configuration that a subresource is loaded from.
+func storedConfigType(query string) string {
+	if configType, ok := derivedConfiguration[query]; ok {
+		return configType
+	}
+
+	return query
+}
+
+// configurationContentTypes are the content types of configuration that isn't XML.
+var configurationContentTypes = map[string]string{
+	"policy": "application/json",
+}
+
+func configContentType(query string) string {
+	if contentType, ok := configurationContentTypes[query]; ok {
+		return contentType
+	}
+
+	return "application/xml"
+}
+
+// configurationDocuments create the typed documents that configuration is validated and normalized with when
+// it is saved. Configuration without an entry, like acl, is stored as it was received.
+var configurationDocuments = map[string]func() domain.BucketConfiguration{
+	"accelerate":     func() domain.BucketConfiguration { return &domain.AccelerateConfiguration{} },
+	"cors":           func() domain.BucketConfiguration { return &domain.CORSConfiguration{} },
+	"encryption":     func() domain.BucketConfiguration { return &domain.ServerSideEncryptionConfiguration{} },
+	"lifecycle":      func() domain.BucketConfiguration { return &domain.LifecycleConfiguration{} },
+	"logging":        func() domain.BucketConfiguration { return &domain.BucketLoggingStatus{} },
+	"object-lock":    func() domain.BucketConfiguration { return &domain.ObjectLockConfiguration{} },
+	"replication":    func() domain.BucketConfiguration { return &domain.ReplicationConfiguration{} },
+	"requestPayment": func() domain.BucketConfiguration { return &domain.RequestPaymentConfiguration{} },
+	"tagging":        func() domain.BucketConfiguration { return &domain.Tagging{} },
+	"versioning":     func() domain.BucketConfiguration { return &domain.VersioningConfiguration{} },
+	"website":        func() domain.BucketConfiguration { return &domain.WebsiteConfiguration{} },
+}
+
+// normalizeConfiguration validates configuration before it is saved, returning it in normalized form.
+func normalizeConfiguration(bucket string, configType string, payload []byte) ([]byte, error) {
+	if configType == "policy" {
+		return domain.NormalizeBucketPolicy(payload, bucket)
+	}
+
+	newDocument, ok := configurationDocuments[configType]
+	if !ok {
+		return payload, nil
+	}
+
+	return domain.NormalizeBucketConfiguration(payload, newDocument())
 }
 
-func bytesPassThrough(config []byte) []byte {
-	return config
+// notConfigured returns stored configuration as is, or the 404 error used by S3 when it was never set.
+func notConfigured(code string, message string) SetDefaultFunc {
+	return func(config []byte) ([]byte, error) {
+		if len(config) == 0 {
+			return nil, S3Error{Code: code, Message: message, StatusCode: http.StatusNotFound}
+		}
+
+		return config, nil
+	}
 }
 
-func defaultAccelerationConfiguration(config []byte) []byte {
+// defaultDocument returns stored configuration as is, or the document returned by S3 when it was never set.
+func defaultDocument(document string) SetDefaultFunc {
+	return func(config []byte) ([]byte, error) {
+		if len(config) == 0 {
+			return []byte(document), nil
+		}
+
+		return config, nil
+	}
+}
+
+func defaultAccelerationConfiguration(config []byte) ([]byte, error) {
 	var accel domain.AccelerateConfiguration
-	if len(config) == 0 {
-		accel.Status = "Disabled"
-	} else {
+	if len(config) > 0 {
 		err := xml.Unmarshal(config, &accel)
 		if err != nil {
-			logger.Panicf("unable to unmarshal %s: %v", string(config), err)
+			logger.Errorf("unable to unmarshal %s: %v", string(config), err)
+			return nil, internalError("Unable to decode stored AccelerateConfiguration")
 		}
 	}
 
@@ -47,8 +156,34 @@ func defaultAccelerationConfiguration(config []byte) []byte {
 
 	result, err := xml.Marshal(accel)
 	if err != nil {
-		logger.Panicf("unable to marshal %+v: %v", accel, err)
+		logger.Errorf("unable to marshal %+v: %v", accel, err)
+		return nil, internalError("Unable to encode AccelerateConfiguration")
+	}
+
+	return result, nil
+}
+
+// policyStatus reports whether the stored bucket policy makes the bucket public.
+func policyStatus(config []byte) ([]byte, error) {
+	if len(config) == 0 {
+		return nil, S3Error{
+			Code:       "NoSuchBucketPolicy",
+			Message:    "The bucket policy does not exist",
+			StatusCode: http.StatusNotFound,
+		}
+	}
+
+	policy, err := domain.ParseBucketPolicy(config)
+	if err != nil {
+		logger.Errorf("unable to parse stored policy %s: %v", string(config), err)
+		return nil, internalError("Unable to decode stored bucket policy")
+	}
+
+	result, err := xml.Marshal(domain.PolicyStatus{IsPublic: policy.IsPublic()})
+	if err != nil {
+		logger.Errorf("unable to marshal policy status: %v", err)
+		return nil, internalError("Unable to encode PolicyStatus")
 	}
 
-	return result
+	return result, nil
 }
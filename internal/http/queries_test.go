@@ -0,0 +1,181 @@
+package http
+
+import (
+	"encoding/xml"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type storedConfigs struct {
+	ConfigurationService
+	configs map[string][]byte
+}
+
+func (s storedConfigs) LoadConfiguration(bucket string, configType string) ([]byte, error) {
+	return s.configs[bucket+"?"+configType], nil
+}
+
+func TestGetConfigNotConfigured(t *testing.T) {
+	cfg := testConfig(t, "-backend-url", bucketServer(t, "bucket", "configured").URL)
+
+	configs := storedConfigs{configs: map[string][]byte{
+		"configured?cors": []byte("<CORSConfiguration></CORSConfiguration>"),
+	}}
+	mux := newTestMux(cfg, testServices{configurations: configs})
+
+	tests := []struct {
+		query  string
+		status int
+		code   string
+		body   string
+	}{
+		{"cors", http.StatusNotFound, "NoSuchCORSConfiguration", ""},
+		{"encryption", http.StatusNotFound, "ServerSideEncryptionConfigurationNotFoundError", ""},
+		{"lifecycle", http.StatusNotFound, "NoSuchLifecycleConfiguration", ""},
+		{"object-lock", http.StatusNotFound, "ObjectLockConfigurationNotFoundError", ""},
+		{"policy", http.StatusNotFound, "NoSuchBucketPolicy", ""},
+		{"replication", http.StatusNotFound, "ReplicationConfigurationNotFoundError", ""},
+		{"tagging", http.StatusNotFound, "NoSuchTagSet", ""},
+		{"website", http.StatusNotFound, "NoSuchWebsiteConfiguration", ""},
+		{"accelerate", http.StatusOK, "", "<AccelerateConfiguration><Status>Disabled</Status></AccelerateConfiguration>"},
+		{"logging", http.StatusOK, "", defaultLogging},
+		{"requestPayment", http.StatusOK, "", defaultRequestPayment},
+		{"versioning", http.StatusOK, "", defaultVersioning},
+		{"acl", http.StatusOK, "", defaultAcl},
+	}
+
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket?"+test.query, nil))
+
+		assert.Equal(t, test.status, recorder.Code, test.query)
+		if test.code == "" {
+			assert.Equal(t, test.body, recorder.Body.String(), test.query)
+			continue
+		}
+
+		var s3Err S3Error
+		err := xml.Unmarshal(recorder.Body.Bytes(), &s3Err)
+		if assert.NoError(t, err, test.query) {
+			assert.Equal(t, test.code, s3Err.Code, test.query)
+			assert.Equal(t, "/bucket", s3Err.Resource, test.query)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost:9000/configured?cors", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/xml", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "<CORSConfiguration></CORSConfiguration>", recorder.Body.String())
+}
+
+func (s storedConfigs) SaveConfiguration(bucket string, configType string, config []byte) (string, error) {
+	s.configs[bucket+"?"+configType] = config
+	return "", nil
+}
+
+func (s storedConfigs) DeleteConfiguration(bucket string, configType string) (string, error) {
+	delete(s.configs, bucket+"?"+configType)
+	return "", nil
+}
+
+func TestPutConfigValidatesAndNormalizes(t *testing.T) {
+	cfg := testConfig(t, "-backend-url", bucketServer(t, "bucket").URL)
+
+	configs := storedConfigs{configs: map[string][]byte{}}
+	mux := newTestMux(cfg, testServices{configurations: configs})
+
+	recorder := httptest.NewRecorder()
+	body := strings.NewReader("<VersioningConfiguration>\n  <Status>Enabled</Status>\n</VersioningConfiguration>")
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "http://localhost:9000/bucket?versioning", body))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `<VersioningConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Status>Enabled</Status></VersioningConfiguration>`,
+		string(configs.configs["bucket?versioning"]))
+
+	recorder = httptest.NewRecorder()
+	body = strings.NewReader("<Tagging><TagSet><Tag><Key>a</Key></Tag><Tag><Key>a</Key></Tag></TagSet></Tagging>")
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "http://localhost:9000/bucket?tagging", body))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "<Code>InvalidTag</Code>")
+	assert.NotContains(t, configs.configs, "bucket?tagging")
+}
+
+func TestBucketPolicy(t *testing.T) {
+	cfg := testConfig(t, "-backend-url", bucketServer(t, "bucket").URL)
+
+	configs := storedConfigs{configs: map[string][]byte{}}
+	mux := newTestMux(cfg, testServices{configurations: configs})
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, signedBy("AKIDEXAMPLE", httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket?policyStatus", nil)))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "<Code>NoSuchBucketPolicy</Code>")
+
+	recorder = httptest.NewRecorder()
+	body := strings.NewReader(`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Principal": "*", ` +
+		`"Action": ["s3:GetObject"], "Resource": ["arn:aws:s3:::bucket/*"]}]}`)
+	mux.ServeHTTP(recorder, signedBy("AKIDEXAMPLE", httptest.NewRequest(http.MethodPut, "http://localhost:9000/bucket?policy", body)))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, signedBy("AKIDEXAMPLE", httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket?policy", nil)))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*",`+
+		`"Action":"s3:GetObject","Resource":"arn:aws:s3:::bucket/*"}]}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, signedBy("AKIDEXAMPLE", httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket?policyStatus", nil)))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/xml", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `<PolicyStatus xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><IsPublic>true</IsPublic></PolicyStatus>`,
+		recorder.Body.String())
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		recorder = httptest.NewRecorder()
+		mux.ServeHTTP(recorder, signedBy("AKIDEXAMPLE", httptest.NewRequest(method, "http://localhost:9000/bucket?policyStatus", strings.NewReader("{}"))))
+		assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code, method)
+		assert.Contains(t, recorder.Body.String(), "<Code>MethodNotAllowed</Code>", method)
+	}
+	assert.NotContains(t, configs.configs, "bucket?policyStatus")
+	assert.Contains(t, configs.configs, "bucket?policy")
+
+	recorder = httptest.NewRecorder()
+	body = strings.NewReader(`{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:*", "Resource": "arn:aws:s3:::other"}]}`)
+	mux.ServeHTTP(recorder, signedBy("AKIDEXAMPLE", httptest.NewRequest(http.MethodPut, "http://localhost:9000/bucket?policy", body)))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "<Code>MalformedPolicy</Code>")
+}
+
+func TestGetConfigOfMissingBucket(t *testing.T) {
+	cfg := testConfig(t, "-backend-url", bucketServer(t).URL)
+
+	configs := storedConfigs{configs: map[string][]byte{"missing?cors": []byte("<CORSConfiguration></CORSConfiguration>")}}
+	mux := newTestMux(cfg, testServices{configurations: configs})
+
+	for _, query := range []string{"cors", "versioning"} {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost:9000/missing?"+query, nil))
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code, query)
+		assert.Contains(t, recorder.Body.String(), "<Code>NoSuchBucket</Code>", query)
+	}
+}
+
+func TestPutConfigOfMissingBucket(t *testing.T) {
+	cfg := testConfig(t, "-backend-url", bucketServer(t).URL)
+
+	configs := storedConfigs{configs: map[string][]byte{}}
+	mux := newTestMux(cfg, testServices{configurations: configs})
+
+	recorder := httptest.NewRecorder()
+	body := strings.NewReader("<VersioningConfiguration><Status>Enabled</Status></VersioningConfiguration>")
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "http://localhost:9000/missing?versioning", body))
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "<Code>NoSuchBucket</Code>")
+	assert.Empty(t, configs.configs)
+}
@@ -0,0 +1,107 @@
+package http
+
+import (
+	"net/http"
+)
+
+// beforeHeaderWriter calls beforeHeader once, right before the status code is written, when the headers of
+// the response from Minio are known but can still be changed.
+type beforeHeaderWriter struct {
+	http.ResponseWriter
+	beforeHeader func(code int)
+	wroteHeader  bool
+}
+
+func (w *beforeHeaderWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.beforeHeader(code)
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *beforeHeaderWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+// Replicate queues new object versions and deletes for replication to the destinations of the bucket's
+// replication configuration. Queueing doesn't block the response, since objects are matched with the rules
+// by the replication worker.
+func (h MinioHandler) Replicate(next http.Handler) http.Handler {
+	if h.replicationService == nil {
+		return next
+	}
+
+	f := func(w http.ResponseWriter, request *http.Request) {
+		operation := getOperation(request)
+		bucket, key := operation.Bucket, operation.Key
+
+		var beforeHeader func(code int)
+		switch {
+		case notifyingOperations[operation.Name]:
+			beforeHeader = func(code int) {
+				if code == http.StatusOK {
+					versionId := w.Header().Get("X-Amz-Version-Id")
+					h.replicationService.ObjectCreated(request.Context(), bucket, key, versionId)
+				}
+			}
+		case operation.Name == "DeleteObject" && request.URL.Query().Get("versionId") == "":
+			// deleting a specific version isn't replicated, like in S3. The backend isn't versioned, so deleting
+			// the current version replaces the delete marker S3 would create.
+			beforeHeader = func(code int) {
+				if code == http.StatusNoContent {
+					h.replicationService.DeleteMarkerCreated(request.Context(), bucket, key)
+				}
+			}
+		default:
+			next.ServeHTTP(w, request)
+			return
+		}
+
+		next.ServeHTTP(&beforeHeaderWriter{ResponseWriter: w, beforeHeader: beforeHeader}, request)
+	}
+
+	return http.HandlerFunc(f)
+}
+
+// ReplicationStatus adds x-amz-replication-status to GetObject and HeadObject responses for objects that are
+// replicated or are replicas, and the storage class requested by the replication rule for replicas.
+func (h MinioHandler) ReplicationStatus(next http.Handler) http.Handler {
+	if h.replicationService == nil {
+		return next
+	}
+
+	f := func(w http.ResponseWriter, request *http.Request) {
+		operation := getOperation(request)
+		if operation.Name != "GetObject" && operation.Name != "HeadObject" {
+			next.ServeHTTP(w, request)
+			return
+		}
+
+		beforeHeader := func(code int) {
+			if code >= http.StatusMultipleChoices && code != http.StatusNotModified {
+				return
+			}
+
+			versionId := w.Header().Get("X-Amz-Version-Id")
+			replication, ok := h.replicationService.Status(operation.Bucket, operation.Key, versionId)
+			if !ok {
+				return
+			}
+
+			w.Header().Set("X-Amz-Replication-Status", replication.Status)
+			if replication.StorageClass != "" {
+				w.Header().Set("X-Amz-Storage-Class", replication.StorageClass)
+			}
+		}
+
+		next.ServeHTTP(&beforeHeaderWriter{ResponseWriter: w, beforeHeader: beforeHeader}, request)
+	}
+
+	return http.HandlerFunc(f)
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"context"
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type replicationRecorder struct {
+	created []string
+	markers []string
+}
+
+func (r *replicationRecorder) ObjectCreated(_ context.Context, bucket string, key string, versionId string) {
+	r.created = append(r.created, bucket+"/"+key+"?"+versionId)
+}
+
+func (r *replicationRecorder) DeleteMarkerCreated(_ context.Context, bucket string, key string) {
+	r.markers = append(r.markers, bucket+"/"+key)
+}
+
+func (r *replicationRecorder) Status(bucket string, key string, versionId string) (domain.ObjectReplication, bool) {
+	if bucket == "backup" {
+		return domain.ObjectReplication{Status: domain.ReplicationReplica, StorageClass: "STANDARD_IA"}, true
+	}
+
+	return domain.ObjectReplication{Status: domain.ReplicationPending}, versionId == "v1"
+}
+
+func TestReplication(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodDelete:
+			if r.URL.Path == "/bucket/missing.txt" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodPut:
+			w.Header().Set("X-Amz-Version-Id", "v1")
+		default:
+			w.Header().Set("X-Amz-Version-Id", r.URL.Query().Get("versionId"))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := testConfig(t, "-backend-url", server.URL)
+
+	replication := &replicationRecorder{}
+	events := eventRecorder{events: make(chan domain.NotificationEvent, 10)}
+	mux := newTestMux(cfg, testServices{notifications: events, replication: replication})
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "http://localhost:9000/bucket/a.txt", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, []string{"bucket/a.txt?v1"}, replication.created)
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodHead, "http://localhost:9000/bucket/a.txt?versionId=v1", nil))
+	assert.Equal(t, "PENDING", recorder.Header().Get("X-Amz-Replication-Status"))
+	assert.Empty(t, recorder.Header().Get("X-Amz-Storage-Class"))
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodHead, "http://localhost:9000/bucket/a.txt?versionId=v0", nil))
+	assert.Empty(t, recorder.Header().Get("X-Amz-Replication-Status"))
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost:9000/backup/a.txt", nil))
+	assert.Equal(t, "REPLICA", recorder.Header().Get("X-Amz-Replication-Status"))
+	assert.Equal(t, "STANDARD_IA", recorder.Header().Get("X-Amz-Storage-Class"))
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "http://localhost:9000/bucket/a.txt?versionId=v1", nil))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "http://localhost:9000/bucket/a.txt", nil))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "http://localhost:9000/bucket/missing.txt", nil))
+	assert.Equal(t, []string{"bucket/a.txt"}, replication.markers)
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+)
+
+const hostIdContextKey = RainbowContextKey("hostId")
+
+// newRequestIds generates ids in the same format as S3: 16 upper-case hex characters for x-amz-request-id
+// and a longer base64 string for x-amz-id-2.
+func newRequestIds() (string, string) {
+	buf := make([]byte, 8+48)
+	_, err := rand.Read(buf)
+	if err != nil {
+		panic(err)
+	}
+
+	return strings.ToUpper(hex.EncodeToString(buf[:8])), base64.StdEncoding.EncodeToString(buf[8:])
+}
+
+// assignRequestIds gives each request ids that are returned in x-amz-request-id and x-amz-id-2, whether the
+// response comes from Minio or from rainbow. The request id is stored where chi's Logger finds it.
+func assignRequestIds(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, request *http.Request) {
+		requestId, hostId := newRequestIds()
+
+		w.Header().Set("X-Amz-Request-Id", requestId)
+		w.Header().Set("X-Amz-Id-2", hostId)
+
+		ctx := context.WithValue(request.Context(), middleware.RequestIDKey, requestId)
+		ctx = context.WithValue(ctx, hostIdContextKey, hostId)
+
+		next.ServeHTTP(w, request.WithContext(ctx))
+	}
+
+	return http.HandlerFunc(f)
+}
+
+func getRequestIds(request *http.Request) (string, string) {
+	hostId, _ := request.Context().Value(hostIdContextKey).(string)
+	return middleware.GetReqID(request.Context()), hostId
+}
+
+// requestLogger returns a logger that includes the request id and S3 operation, once known, in each line.
+func requestLogger(request *http.Request) *zap.SugaredLogger {
+	var fields []interface{}
+	if requestId := middleware.GetReqID(request.Context()); requestId != "" {
+		fields = append(fields, "requestId", requestId)
+	}
+
+	if operation, ok := request.Context().Value(operationContextKey).(Operation); ok {
+		fields = append(fields, "operation", operation.Name)
+	}
+
+	if len(fields) == 0 {
+		return logger
+	}
+
+	return logger.With(fields...)
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"encoding/xml"
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestRequestIdsReplaceMinioIds(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Amz-Request-Id", "MINIO")
+		w.Header().Set("X-Amz-Id-2", "MINIO2")
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := testConfig(t, "-backend-url", server.URL)
+
+	events := make(chan domain.NotificationEvent, 1)
+	mux := newTestMux(cfg, testServices{notifications: eventRecorder{events: events}})
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "http://localhost:9000/bucket/key.txt", nil))
+
+	requestId := recorder.Header().Get("X-Amz-Request-Id")
+	hostId := recorder.Header().Get("X-Amz-Id-2")
+	assert.Regexp(t, regexp.MustCompile("^[0-9A-F]{16}$"), requestId)
+	assert.NotEmpty(t, hostId)
+	assert.NotEqual(t, "MINIO2", hostId)
+	assert.Len(t, recorder.Header().Values("X-Amz-Request-Id"), 1)
+
+	event := <-events
+	assert.Equal(t, requestId, event.RequestId)
+	assert.Equal(t, hostId, event.HostId)
+}
+
+func TestRequestIdsInErrors(t *testing.T) {
+	cfg := testConfig(t, "-verify-signatures")
+
+	mux := newTestMux(cfg, testServices{})
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket/key.txt", nil))
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+
+	var s3Err S3Error
+	err := xml.Unmarshal(recorder.Body.Bytes(), &s3Err)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, recorder.Header().Get("X-Amz-Request-Id"), s3Err.RequestId)
+	assert.Equal(t, recorder.Header().Get("X-Amz-Id-2"), s3Err.HostId)
+	assert.NotEmpty(t, s3Err.RequestId)
+}
@@ -0,0 +1,338 @@
+package http
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/ATenderholt/rainbow-storage/internal/sigv4"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// websiteRequestHeaders are the headers of a website request that are passed on when getting the object
+var websiteRequestHeaders = []string{
+	"If-Match",
+	"If-Modified-Since",
+	"If-None-Match",
+	"If-Unmodified-Since",
+	"Range",
+}
+
+// websiteBucket returns the bucket of a website request, which is addressed by host: either a subdomain of the
+// website domain on any port, or any host name on the website port, like a CNAME to a website endpoint.
+func (h MinioHandler) websiteBucket(request *http.Request) (string, bool) {
+	if bucket, ok := bucketFromHost(request.Host, h.cfg.WebsiteDomain); ok {
+		return bucket, true
+	}
+
+	if h.cfg.WebsitePort == 0 {
+		return "", false
+	}
+
+	addr, ok := request.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if !ok {
+		return "", false
+	}
+
+	_, port, err := net.SplitHostPort(addr.String())
+	if err != nil || port != strconv.Itoa(h.cfg.WebsitePort) {
+		return "", false
+	}
+
+	host := request.Host
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+
+	return strings.ToLower(host), true
+}
+
+// Websites serves buckets as static websites from their website configuration, like the website endpoints
+// of S3. Website requests are anonymous, so objects must be allowed by the bucket policy if there is one.
+func (h MinioHandler) Websites(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, request *http.Request) {
+		bucket, ok := h.websiteBucket(request)
+		if !ok {
+			next.ServeHTTP(w, request)
+			return
+		}
+
+		key := strings.TrimPrefix(request.URL.Path, "/")
+		operation := Operation{Name: "GetObject", Bucket: bucket, Key: key}
+		if request.Method == http.MethodHead {
+			operation.Name = "HeadObject"
+		}
+
+		serve := func(w http.ResponseWriter, request *http.Request) {
+			h.serveWebsite(w, request, bucket, key)
+		}
+
+		ctx := context.WithValue(request.Context(), operationContextKey, operation)
+		h.AccessLog(http.HandlerFunc(serve)).ServeHTTP(w, request.WithContext(ctx))
+	}
+
+	return http.HandlerFunc(f)
+}
+
+func (h MinioHandler) serveWebsite(w http.ResponseWriter, request *http.Request, bucket string, key string) {
+	log := requestLogger(request)
+
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		writeWebsiteError(w, request, methodNotAllowed())
+		return
+	}
+
+	website, ok, err := h.loadWebsite(bucket)
+	if err != nil {
+		log.Errorf("unable to load website configuration for bucket %s: %v", bucket, err)
+		writeWebsiteError(w, request, toS3Error(err))
+		return
+	}
+
+	if !ok {
+		writeWebsiteError(w, request, S3Error{
+			Code:       "NoSuchWebsiteConfiguration",
+			Message:    "The specified bucket does not have a website configuration",
+			Resource:   bucket,
+			StatusCode: http.StatusNotFound,
+		})
+		return
+	}
+
+	if redirectAll := website.RedirectAllRequestsTo; redirectAll != nil {
+		location := url.URL{Scheme: redirectAll.Protocol, Host: redirectAll.HostName, Path: request.URL.Path}
+		if location.Scheme == "" {
+			location.Scheme = requestScheme(request)
+		}
+
+		log.Infof("Redirecting all requests for bucket %s to %s", bucket, location.String())
+		w.Header().Set("Location", location.String())
+		w.WriteHeader(http.StatusMovedPermanently)
+		return
+	}
+
+	if rule, ok := website.MatchRoutingRule(key, 0); ok {
+		websiteRedirect(w, request, rule, key)
+		return
+	}
+
+	objectKey := website.IndexKey(key)
+	resp, err := h.getWebsiteObject(request, request.Method, bucket, objectKey, true)
+	if err != nil {
+		h.websiteError(w, request, website, bucket, key, toS3Error(err))
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		s3Err := backendError(resp)
+		if s3Err.StatusCode == http.StatusNotFound && objectKey == key && website.IndexDocument != nil && key != "" {
+			if h.isWebsiteFolder(request, bucket, key, website) {
+				log.Infof("Redirecting %s to its folder in bucket %s", key, bucket)
+				w.Header().Set("Location", "/"+key+"/")
+				w.WriteHeader(http.StatusFound)
+				return
+			}
+		}
+
+		h.websiteError(w, request, website, bucket, key, s3Err)
+		return
+	}
+
+	if location := resp.Header.Get("X-Amz-Website-Redirect-Location"); location != "" {
+		log.Infof("Redirecting %s in bucket %s to %s", objectKey, bucket, location)
+		w.Header().Set("Location", location)
+		w.WriteHeader(http.StatusMovedPermanently)
+		return
+	}
+
+	forwardResponseHeaders(w.Header(), resp.Header)
+	w.WriteHeader(resp.StatusCode)
+
+	_, err = io.Copy(w, resp.Body)
+	if err != nil {
+		log.Warnf("Unable to copy %s in bucket %s to website response: %v", objectKey, bucket, err)
+	}
+}
+
+// loadWebsite returns the website configuration of the bucket, if it has one.
+func (h MinioHandler) loadWebsite(bucket string) (domain.WebsiteConfiguration, bool, error) {
+	var website domain.WebsiteConfiguration
+
+	config, err := h.configurationService.LoadConfiguration(bucket, "website")
+	if err != nil || len(config) == 0 {
+		return website, false, err
+	}
+
+	err = xml.Unmarshal(config, &website)
+	if err != nil {
+		logger.Errorf("unable to unmarshal website configuration %s: %v", string(config), err)
+		return website, false, internalError("Unable to decode stored WebsiteConfiguration")
+	}
+
+	return website, true, nil
+}
+
+// getWebsiteObject gets an object from Minio for a website request, once the bucket policy allows it.
+func (h MinioHandler) getWebsiteObject(request *http.Request, method string, bucket string, key string, conditional bool) (*http.Response, error) {
+	err := h.checkPolicy(request, Operation{Name: "GetObject", Bucket: bucket, Key: key}, "")
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := h.backendUrl("/"+bucket+"/"+key, "")
+	if err != nil {
+		return nil, internalError(err.Error())
+	}
+
+	proxyReq, err := http.NewRequestWithContext(request.Context(), method, target.String(), http.NoBody)
+	if err != nil {
+		return nil, internalError(fmt.Sprintf("Unable to create request to Minio: %v", err))
+	}
+
+	if conditional {
+		for _, name := range websiteRequestHeaders {
+			if value := request.Header.Get(name); value != "" {
+				proxyReq.Header.Set(name, value)
+			}
+		}
+	}
+
+	err = h.signBackendRequest(proxyReq, sigv4.EmptyPayload)
+	if err != nil {
+		return nil, internalError(fmt.Sprintf("Unable to sign request to Minio: %v", err))
+	}
+
+	resp, err := h.client.Do(proxyReq)
+	if err != nil {
+		return nil, serviceUnavailable(fmt.Sprintf("Unable to get %s from Minio: %v", key, err))
+	}
+
+	return resp, nil
+}
+
+// isWebsiteFolder returns whether a key that doesn't exist has an index document when it is treated as a folder.
+func (h MinioHandler) isWebsiteFolder(request *http.Request, bucket string, key string, website domain.WebsiteConfiguration) bool {
+	resp, err := h.getWebsiteObject(request, http.MethodHead, bucket, website.IndexKey(key+"/"), false)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode == http.StatusOK
+}
+
+// websiteError responds to a failed website request with a matching routing rule, the error document or the
+// default error page, in that order.
+func (h MinioHandler) websiteError(w http.ResponseWriter, request *http.Request, website domain.WebsiteConfiguration, bucket string, key string, s3Err S3Error) {
+	log := requestLogger(request)
+
+	if rule, ok := website.MatchRoutingRule(key, s3Err.StatusCode); ok {
+		websiteRedirect(w, request, rule, key)
+		return
+	}
+
+	if website.ErrorDocument == nil || request.Method != http.MethodGet {
+		writeWebsiteError(w, request, s3Err)
+		return
+	}
+
+	resp, err := h.getWebsiteObject(request, http.MethodGet, bucket, website.ErrorDocument.Key, false)
+	if err != nil {
+		log.Warnf("Unable to get error document %s of bucket %s: %v", website.ErrorDocument.Key, bucket, err)
+		writeWebsiteError(w, request, s3Err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Warnf("Unable to get error document %s of bucket %s: status %d", website.ErrorDocument.Key, bucket, resp.StatusCode)
+		writeWebsiteError(w, request, s3Err)
+		return
+	}
+
+	forwardResponseHeaders(w.Header(), resp.Header)
+	w.WriteHeader(s3Err.StatusCode)
+
+	_, err = io.Copy(w, resp.Body)
+	if err != nil {
+		log.Warnf("Unable to copy error document of bucket %s to website response: %v", bucket, err)
+	}
+}
+
+func websiteRedirect(w http.ResponseWriter, request *http.Request, rule domain.RoutingRule, key string) {
+	location := url.URL{
+		Scheme: rule.Redirect.Protocol,
+		Host:   rule.Redirect.HostName,
+		Path:   "/" + rule.RedirectKey(key),
+	}
+
+	if location.Scheme == "" {
+		location.Scheme = requestScheme(request)
+	}
+
+	if location.Host == "" {
+		location.Host = request.Host
+	}
+
+	requestLogger(request).Infof("Redirecting %s to %s with routing rule", key, location.String())
+	w.Header().Set("Location", location.String())
+	w.WriteHeader(rule.RedirectCode())
+}
+
+func requestScheme(request *http.Request) string {
+	if request.TLS != nil {
+		return "https"
+	}
+
+	return "http"
+}
+
+// backendError reads the S3 error returned by Minio, keeping its status code.
+func backendError(resp *http.Response) S3Error {
+	var s3Err S3Error
+
+	payload, _ := io.ReadAll(io.LimitReader(resp.Body, maxLoggedResponse))
+	if xml.Unmarshal(payload, &s3Err) != nil || s3Err.Code == "" {
+		s3Err.Code = strings.ReplaceAll(http.StatusText(resp.StatusCode), " ", "")
+		s3Err.Message = http.StatusText(resp.StatusCode)
+	}
+
+	s3Err.Resource = ""
+	s3Err.StatusCode = resp.StatusCode
+	return s3Err
+}
+
+// writeWebsiteError writes the HTML error page returned by website endpoints, rather than an XML error.
+func writeWebsiteError(w http.ResponseWriter, request *http.Request, s3Err S3Error) {
+	requestId, hostId := getRequestIds(request)
+	status := fmt.Sprintf("%d %s", s3Err.StatusCode, http.StatusText(s3Err.StatusCode))
+
+	var page strings.Builder
+	page.WriteString("<html>\n<head><title>" + status + "</title></head>\n<body>\n<h1>" + status + "</h1>\n<ul>\n")
+	page.WriteString("<li>Code: " + html.EscapeString(s3Err.Code) + "</li>\n")
+	page.WriteString("<li>Message: " + html.EscapeString(s3Err.Message) + "</li>\n")
+	if s3Err.Resource != "" {
+		page.WriteString("<li>BucketName: " + html.EscapeString(s3Err.Resource) + "</li>\n")
+	}
+	page.WriteString("<li>RequestId: " + requestId + "</li>\n")
+	page.WriteString("<li>HostId: " + hostId + "</li>\n")
+	page.WriteString("</ul>\n<hr/>\n</body>\n</html>\n")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(s3Err.StatusCode)
+	if request.Method == http.MethodHead {
+		return
+	}
+
+	_, err := io.WriteString(w, page.String())
+	if err != nil {
+		requestLogger(request).Warnf("Unable to write website error page: %v", err)
+	}
+}
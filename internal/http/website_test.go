@@ -0,0 +1,156 @@
+package http
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testWebsite = `<WebsiteConfiguration>` +
+	`<IndexDocument><Suffix>index.html</Suffix></IndexDocument>` +
+	`<ErrorDocument><Key>error.html</Key></ErrorDocument>` +
+	`<RoutingRules>` +
+	`<RoutingRule><Condition><KeyPrefixEquals>docs/</KeyPrefixEquals></Condition>` +
+	`<Redirect><ReplaceKeyPrefixWith>documents/</ReplaceKeyPrefixWith></Redirect></RoutingRule>` +
+	`<RoutingRule><Condition><KeyPrefixEquals>app/</KeyPrefixEquals><HttpErrorCodeReturnedEquals>404</HttpErrorCodeReturnedEquals></Condition>` +
+	`<Redirect><HostName>example.com</HostName><Protocol>https</Protocol><ReplaceKeyWith>app/index.html</ReplaceKeyWith><HttpRedirectCode>302</HttpRedirectCode></Redirect></RoutingRule>` +
+	`</RoutingRules></WebsiteConfiguration>`
+
+// objectServer serves objects like Minio, returning NoSuchKey for anything else
+func objectServer(t *testing.T, objects map[string]string, headers map[string]http.Header) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// buckets exist if they have objects
+		if r.Method == http.MethodHead && strings.Count(r.URL.Path, "/") == 1 {
+			for path := range objects {
+				if strings.HasPrefix(path, r.URL.Path+"/") {
+					return
+				}
+			}
+		}
+
+		body, ok := objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>"))
+			return
+		}
+
+		for name, values := range headers[r.URL.Path] {
+			w.Header()[name] = values
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestWebsites(t *testing.T) {
+	server := objectServer(t, map[string]string{
+		"/site/index.html":        "home",
+		"/site/about/index.html":  "about",
+		"/site/error.html":        "oops",
+		"/site/old.html":          "",
+		"/site/documents/a.html":  "a",
+		"/redirect/anything.html": "never served",
+	}, map[string]http.Header{
+		"/site/old.html": {"X-Amz-Website-Redirect-Location": {"/about/"}},
+	})
+
+	cfg := testConfig(t, "-backend-url", server.URL)
+
+	configs := storedConfigs{configs: map[string][]byte{
+		"site?website":     []byte(testWebsite),
+		"redirect?website": []byte(`<WebsiteConfiguration><RedirectAllRequestsTo><HostName>example.com</HostName></RedirectAllRequestsTo></WebsiteConfiguration>`),
+	}}
+	mux := newTestMux(cfg, testServices{configurations: configs})
+
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		status   int
+		body     string
+		location string
+	}{
+		{"index", http.MethodGet, "http://site.s3-website.localhost:9000/", http.StatusOK, "home", ""},
+		{"folder index", http.MethodGet, "http://site.s3-website.localhost:9000/about/", http.StatusOK, "about", ""},
+		{"folder without slash", http.MethodGet, "http://site.s3-website.localhost:9000/about", http.StatusFound, "", "/about/"},
+		{"error document", http.MethodGet, "http://site.s3-website.localhost:9000/missing.html", http.StatusNotFound, "oops", ""},
+		{"object redirect", http.MethodGet, "http://site.s3-website.localhost:9000/old.html", http.StatusMovedPermanently, "", "/about/"},
+		{"prefix rule", http.MethodGet, "http://site.s3-website.localhost:9000/docs/a.html", http.StatusMovedPermanently, "", "http://site.s3-website.localhost:9000/documents/a.html"},
+		{"error code rule", http.MethodGet, "http://site.s3-website.localhost:9000/app/route", http.StatusFound, "", "https://example.com/app/index.html"},
+		{"redirect all", http.MethodGet, "http://redirect.s3-website.localhost:9000/anything.html", http.StatusMovedPermanently, "", "http://example.com/anything.html"},
+	}
+
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(test.method, test.url, nil))
+
+		assert.Equal(t, test.status, recorder.Code, test.name)
+		assert.Equal(t, test.location, recorder.Header().Get("Location"), test.name)
+		if test.body != "" {
+			assert.Equal(t, test.body, recorder.Body.String(), test.name)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://other.s3-website.localhost:9000/", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "<li>Code: NoSuchWebsiteConfiguration</li>")
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "http://site.s3-website.localhost:9000/index.html", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+
+	// anonymous website requests need to be allowed by a bucket policy, if there is one
+	policy := `{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::site/about/*"}]}`
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, signedBy("owner", httptest.NewRequest(http.MethodPut, "http://localhost:9000/site?policy", strings.NewReader(policy))))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://site.s3-website.localhost:9000/about/", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://site.s3-website.localhost:9000/", nil))
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "<li>Code: AccessDenied</li>")
+}
+
+func TestWebsitePort(t *testing.T) {
+	server := objectServer(t, map[string]string{
+		"/www.example.com/index.html": "home",
+		"/bucket/index.html":          "not a website",
+	}, nil)
+
+	cfg := testConfig(t, "-backend-url", server.URL, "-website-port", "8080")
+
+	configs := storedConfigs{configs: map[string][]byte{"www.example.com?website": []byte(testWebsite)}}
+	mux := newTestMux(cfg, testServices{configurations: configs})
+
+	// the server listening on the website port sets the local address of its requests
+	onPort := func(port int, request *http.Request) *http.Request {
+		addr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port}
+		return request.WithContext(context.WithValue(request.Context(), http.LocalAddrContextKey, addr))
+	}
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, onPort(8080, httptest.NewRequest(http.MethodGet, "http://WWW.Example.com:8080/", nil)))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "home", recorder.Body.String())
+
+	// the S3 port doesn't serve websites by host name
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, onPort(9000, httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket/index.html", nil)))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "not a website", recorder.Body.String())
+}
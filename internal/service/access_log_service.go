@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	loggingConfigType = "logging"
+
+	// maxBufferedRecords limits how many records are kept for a target that can't be written to
+	maxBufferedRecords = 100000
+)
+
+type logTarget struct {
+	bucket string
+	prefix string
+}
+
+// AccessLogService writes server access log records of buckets with logging enabled to their target bucket.
+// Records are buffered and written as a single object per target every flush interval, like S3 delivers logs.
+type AccessLogService struct {
+	configurations *ConfigurationService
+	store          domain.ObjectStore
+	lock           *sync.Mutex
+	records        map[logTarget][]string
+	targets        map[string]*logTarget // cached targets of buckets, nil if logging is disabled
+	started        bool
+	worker         worker
+}
+
+func NewAccessLogService(configurations *ConfigurationService, store domain.ObjectStore) *AccessLogService {
+	return &AccessLogService{
+		configurations: configurations,
+		store:          store,
+		lock:           &sync.Mutex{},
+		records:        make(map[logTarget][]string),
+		targets:        make(map[string]*logTarget),
+	}
+}
+
+// Start writes buffered records every interval until stopped. Records aren't buffered until started.
+func (service *AccessLogService) Start(interval time.Duration) {
+	logger.Infof("Writing server access logs every %v", interval)
+
+	service.lock.Lock()
+	service.started = true
+	service.lock.Unlock()
+
+	service.worker.start(every(interval, func(time.Time) {
+		err := service.Flush(context.Background())
+		if err != nil {
+			logger.Warnf("Problem writing server access logs: %v", err)
+		}
+	}))
+}
+
+// Stop writes the records that are still buffered before returning. Records logged afterwards are dropped.
+func (service *AccessLogService) Stop() {
+	if !service.worker.stopAndWait() {
+		return
+	}
+
+	service.lock.Lock()
+	service.started = false
+	service.lock.Unlock()
+
+	err := service.Flush(context.Background())
+	if err != nil {
+		logger.Warnf("Problem writing server access logs: %v", err)
+	}
+}
+
+// Log buffers a record if its bucket has logging enabled and the service is started.
+func (service *AccessLogService) Log(record domain.AccessLogRecord) {
+	service.lock.Lock()
+	started := service.started
+	service.lock.Unlock()
+
+	if !started {
+		return
+	}
+
+	target, ok := service.target(record.Bucket)
+	if !ok {
+		return
+	}
+
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	if !service.started {
+		return
+	}
+
+	if len(service.records[target]) >= maxBufferedRecords {
+		logger.Warnf("Dropping server access log record of bucket %s, too many are waiting for bucket %s", record.Bucket, target.bucket)
+		return
+	}
+
+	service.records[target] = append(service.records[target], record.String())
+}
+
+// LoggingChanged forgets the cached logging configuration of a bucket once it is changed or deleted.
+func (service *AccessLogService) LoggingChanged(bucket string) {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	delete(service.targets, bucket)
+}
+
+// target returns where the records of a bucket are written, if it has logging enabled. The logging configuration
+// is only loaded the first time, since every request to the bucket needs it.
+func (service *AccessLogService) target(bucket string) (logTarget, bool) {
+	service.lock.Lock()
+	target, cached := service.targets[bucket]
+	service.lock.Unlock()
+
+	if !cached {
+		var status domain.BucketLoggingStatus
+		ok, err := service.configurations.decodeConfiguration(bucket, loggingConfigType, &status)
+		if err != nil {
+			return logTarget{}, false
+		}
+
+		if ok && status.LoggingEnabled != nil {
+			target = &logTarget{bucket: status.LoggingEnabled.TargetBucket, prefix: status.LoggingEnabled.TargetPrefix}
+		}
+
+		service.lock.Lock()
+		service.targets[bucket] = target
+		service.lock.Unlock()
+	}
+
+	if target == nil {
+		return logTarget{}, false
+	}
+
+	return *target, true
+}
+
+// Flush writes the buffered records of each target to a new object in the target bucket, named like S3 names
+// log objects. Records that can't be written are kept for the next flush, and the first problem is returned.
+func (service *AccessLogService) Flush(ctx context.Context) error {
+	service.lock.Lock()
+	records := service.records
+	service.records = make(map[logTarget][]string)
+	service.lock.Unlock()
+
+	var first error
+	for target, lines := range records {
+		key := target.prefix + time.Now().UTC().Format("2006-01-02-15-04-05") + "-" + uniqueString()
+		body := []byte(strings.Join(lines, "\n") + "\n")
+
+		err := service.store.PutObject(ctx, target.bucket, key, body)
+		if err != nil {
+			logger.Warnf("Unable to write %d server access log records to bucket %s: %v", len(lines), target.bucket, err)
+			service.requeue(target, lines)
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+
+		logger.Infof("Wrote %d server access log records to %s in bucket %s", len(lines), key, target.bucket)
+	}
+
+	return first
+}
+
+func (service *AccessLogService) requeue(target logTarget, lines []string) {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	lines = append(lines, service.records[target]...)
+	if len(lines) > maxBufferedRecords {
+		lines = lines[len(lines)-maxBufferedRecords:]
+	}
+
+	service.records[target] = lines
+}
+
+// uniqueString is the random suffix of log object names, so that objects written in the same second don't collide.
+func uniqueString() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%016X", time.Now().UnixNano())
+	}
+
+	return strings.ToUpper(hex.EncodeToString(b))
+}
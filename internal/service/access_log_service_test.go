@@ -0,0 +1,99 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/ATenderholt/rainbow-storage/internal/service"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func (s *fakeStore) PutObject(_ context.Context, bucket string, key string, body []byte) error {
+	if s.failPuts {
+		return errors.New("ServiceUnavailable")
+	}
+
+	if s.objects == nil {
+		s.objects = make(map[string]string)
+	}
+
+	s.objects[bucket+"/"+key] = string(body)
+	return nil
+}
+
+func TestAccessLogService(t *testing.T) {
+	configurations := service.NewConfigurationService(dataPath(t.TempDir()))
+	_, err := configurations.SaveConfiguration("source", "logging", []byte(`<BucketLoggingStatus><LoggingEnabled>
+<TargetBucket>logs</TargetBucket><TargetPrefix>source/</TargetPrefix></LoggingEnabled></BucketLoggingStatus>`))
+	if err != nil {
+		t.Fatalf("Problem saving logging configuration: %v", err)
+	}
+
+	store := &fakeStore{}
+	s := service.NewAccessLogService(configurations, store)
+
+	record := domain.AccessLogRecord{Bucket: "source", Time: time.Date(2022, 2, 6, 0, 0, 38, 0, time.UTC), Status: 200}
+	s.Log(record)
+
+	s.Start(time.Hour)
+	s.Log(record)
+	s.Log(domain.AccessLogRecord{Bucket: "other", Status: 200})
+
+	store.failPuts = true
+	assert.Error(t, s.Flush(context.Background()))
+	assert.Empty(t, store.objects)
+
+	store.failPuts = false
+	s.Log(record)
+	s.Stop()
+
+	line := record.String() + "\n"
+	if assert.Len(t, store.objects, 1) {
+		for name, body := range store.objects {
+			assert.Regexp(t, regexp.MustCompile(`^logs/source/\d{4}-\d{2}-\d{2}-\d{2}-\d{2}-\d{2}-[0-9A-F]{16}$`), name)
+			assert.Equal(t, line+line, body)
+		}
+	}
+}
+
+func TestAccessLogServiceCachesConfiguration(t *testing.T) {
+	configurations := service.NewConfigurationService(dataPath(t.TempDir()))
+	saveLogging := func(target string) {
+		_, err := configurations.SaveConfiguration("source", "logging", []byte(`<BucketLoggingStatus><LoggingEnabled>
+<TargetBucket>`+target+`</TargetBucket><TargetPrefix></TargetPrefix></LoggingEnabled></BucketLoggingStatus>`))
+		if err != nil {
+			t.Fatalf("Problem saving logging configuration: %v", err)
+		}
+	}
+
+	store := &fakeStore{}
+	s := service.NewAccessLogService(configurations, store)
+	s.Start(time.Hour)
+
+	record := domain.AccessLogRecord{Bucket: "source", Status: 200}
+	saveLogging("first")
+	s.Log(record)
+
+	// the configuration is only loaded again once it is reported as changed
+	saveLogging("second")
+	s.Log(record)
+	assert.NoError(t, s.Flush(context.Background()))
+
+	s.LoggingChanged("source")
+	s.Log(record)
+	s.Stop()
+
+	// records logged after stopping would never be written
+	s.Log(record)
+	assert.NoError(t, s.Flush(context.Background()))
+
+	lines := make(map[string]int)
+	for name, body := range store.objects {
+		lines[strings.Split(name, "/")[0]] += strings.Count(body, "\n")
+	}
+	assert.Equal(t, map[string]int{"first": 2, "second": 1}, lines)
+}
@@ -1,14 +1,30 @@
 package service
 
 import (
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"io/fs"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
+// configExtensions are the file extensions of configuration that isn't stored as XML
+var configExtensions = map[string]string{
+	"policy": ".json",
+}
+
+func configFile(bucket string, configType string) string {
+	extension, ok := configExtensions[configType]
+	if !ok {
+		extension = ".xml"
+	}
+
+	return bucket + extension
+}
+
 type ConfigurationService struct {
 	cfg Config
 }
@@ -32,7 +48,7 @@ func (service ConfigurationService) SaveConfiguration(bucket string, configType
 		return basePath, err
 	}
 
-	path := filepath.Join(basePath, bucket+".xml")
+	path := filepath.Join(basePath, configFile(bucket, configType))
 	logger.Infof("Saving %s configuration for bucket %s to %s", configType, bucket, path)
 
 	file, err := os.Create(path)
@@ -62,7 +78,9 @@ func (service ConfigurationService) SaveConfiguration(bucket string, configType
 }
 
 func (service ConfigurationService) LoadConfiguration(bucket string, configType string) ([]byte, error) {
-	path := filepath.Join(service.cfg.DataPath(), configType, bucket+".xml")
+	path := filepath.Join(service.cfg.DataPath(), configType, configFile(bucket, configType))
+	service.migrateLegacyFile(bucket, configType, path)
+
 	file, err := os.Open(path)
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
@@ -90,22 +108,116 @@ func (service ConfigurationService) LoadConfiguration(bucket string, configType
 	return config, nil
 }
 
-func (service ConfigurationService) CleanupAllConfiguration(bucket string) {
-	path := filepath.Join(service.cfg.DataPath())
-	glob := fmt.Sprintf("%s/*/%s.xml", path, bucket)
-	logger.Infof("cleaning up config files using glob %s", glob)
+// migrateLegacyFile moves configuration that was stored as XML before its type had its own extension, like
+// bucket policies, to the file it is now stored in.
+func (service ConfigurationService) migrateLegacyFile(bucket string, configType string, path string) {
+	if _, ok := configExtensions[configType]; !ok {
+		return
+	}
 
-	files, err := filepath.Glob(glob)
-	if err != nil {
-		logger.Errorf("bad glob for cleaning up: %v", err)
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
 		return
 	}
 
-	for _, file := range files {
-		logger.Infof("removing config file %s", file)
-		e := os.Remove(file)
-		if e != nil {
-			logger.Warnf("unable to delete %s: %v", file, e)
+	legacyPath := filepath.Join(service.cfg.DataPath(), configType, bucket+".xml")
+	err := os.Rename(legacyPath, path)
+	switch {
+	case err == nil:
+		logger.Infof("Moved %s configuration for bucket %s from %s to %s", configType, bucket, legacyPath, path)
+	case !errors.Is(err, fs.ErrNotExist):
+		logger.Warnf("Unable to move %s configuration for bucket %s from %s to %s: %v", configType, bucket, legacyPath, path, err)
+	}
+}
+
+// DeleteConfiguration removes a single type of configuration for the bucket. It isn't an error if the
+// bucket doesn't have that configuration.
+func (service ConfigurationService) DeleteConfiguration(bucket string, configType string) (string, error) {
+	path := filepath.Join(service.cfg.DataPath(), configType, configFile(bucket, configType))
+	service.migrateLegacyFile(bucket, configType, path)
+	logger.Infof("Deleting %s configuration for bucket %s from %s", configType, bucket, path)
+
+	err := os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		err := DeleteError{
+			path:   path,
+			bucket: bucket,
+			base:   err,
 		}
+		logger.Error(err)
+		return path, err
+	}
+
+	return path, nil
+}
+
+// ConfiguredBuckets returns the buckets that have a type of configuration.
+func (service ConfigurationService) ConfiguredBuckets(configType string) ([]string, error) {
+	path := filepath.Join(service.cfg.DataPath(), configType)
+	entries, err := os.ReadDir(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
 	}
+
+	if err != nil {
+		err := DirError{
+			path: path,
+			base: err,
+		}
+		logger.Error(err)
+		return nil, err
+	}
+
+	var buckets []string
+	extension := configFile("", configType)
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == extension {
+			buckets = append(buckets, strings.TrimSuffix(entry.Name(), extension))
+		}
+	}
+
+	return buckets, nil
+}
+
+func (service ConfigurationService) CleanupAllConfiguration(bucket string) {
+	path := filepath.Join(service.cfg.DataPath())
+	globs := []string{fmt.Sprintf("%s/*/%s.xml", path, bucket)}
+	for configType, extension := range configExtensions {
+		globs = append(globs, filepath.Join(path, configType, bucket+extension))
+	}
+
+	for _, glob := range globs {
+		logger.Infof("cleaning up config files using glob %s", glob)
+
+		files, err := filepath.Glob(glob)
+		if err != nil {
+			logger.Errorf("bad glob for cleaning up: %v", err)
+			continue
+		}
+
+		for _, file := range files {
+			logger.Infof("removing config file %s", file)
+			e := os.Remove(file)
+			if e != nil {
+				logger.Warnf("unable to delete %s: %v", file, e)
+			}
+		}
+	}
+}
+
+// decodeConfiguration decodes the stored XML configuration of a type of a bucket into config, returning whether
+// the bucket has one.
+func (service ConfigurationService) decodeConfiguration(bucket string, configType string, config interface{}) (bool, error) {
+	payload, err := service.LoadConfiguration(bucket, configType)
+	if err != nil || len(payload) == 0 {
+		return false, err
+	}
+
+	err = xml.Unmarshal(payload, config)
+	if err != nil {
+		err := fmt.Errorf("unable to decode %s configuration of bucket %s: %v", configType, bucket, err)
+		logger.Error(err)
+		return false, err
+	}
+
+	return true, nil
 }
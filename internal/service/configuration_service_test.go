@@ -0,0 +1,93 @@
+package service_test
+
+import (
+	"github.com/ATenderholt/rainbow-storage/internal/service"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type dataPath string
+
+func (p dataPath) DataPath() string {
+	return string(p)
+}
+
+func TestConfigurationServiceDeleteConfiguration(t *testing.T) {
+	s := service.NewConfigurationService(dataPath(t.TempDir()))
+
+	for _, configType := range []string{"cors", "tagging"} {
+		_, err := s.SaveConfiguration("test", configType, []byte("<"+configType+"/>"))
+		if err != nil {
+			t.Fatalf("Problem saving %s configuration: %v", configType, err)
+		}
+	}
+
+	_, err := s.DeleteConfiguration("test", "cors")
+	assert.NoError(t, err)
+
+	cors, err := s.LoadConfiguration("test", "cors")
+	assert.NoError(t, err)
+	assert.Empty(t, cors)
+
+	tagging, err := s.LoadConfiguration("test", "tagging")
+	assert.NoError(t, err)
+	assert.Equal(t, "<tagging/>", string(tagging))
+
+	// deleting configuration that doesn't exist succeeds, like it does in S3
+	_, err = s.DeleteConfiguration("test", "website")
+	assert.NoError(t, err)
+}
+
+func TestConfigurationServiceStoresPolicyAsJson(t *testing.T) {
+	path := t.TempDir()
+	s := service.NewConfigurationService(dataPath(path))
+
+	saved, err := s.SaveConfiguration("test", "policy", []byte(`{"Statement":[]}`))
+	if err != nil {
+		t.Fatalf("Problem saving policy: %v", err)
+	}
+
+	assert.Equal(t, filepath.Join(path, "policy", "test.json"), saved)
+
+	_, err = s.SaveConfiguration("test", "cors", []byte("<cors/>"))
+	if err != nil {
+		t.Fatalf("Problem saving cors configuration: %v", err)
+	}
+
+	s.CleanupAllConfiguration("test")
+
+	for _, configType := range []string{"policy", "cors"} {
+		config, err := s.LoadConfiguration("test", configType)
+		assert.NoError(t, err)
+		assert.Empty(t, config, configType)
+	}
+}
+
+func TestConfigurationServiceMovesLegacyPolicy(t *testing.T) {
+	path := t.TempDir()
+	s := service.NewConfigurationService(dataPath(path))
+
+	// policies used to be stored like other configuration
+	err := os.MkdirAll(filepath.Join(path, "policy"), 0755)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(path, "policy", "test.xml"), []byte(`{"Statement":[]}`), 0644)
+	}
+	if err != nil {
+		t.Fatalf("Problem writing legacy policy: %v", err)
+	}
+
+	policy, err := s.LoadConfiguration("test", "policy")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Statement":[]}`, string(policy))
+	assert.FileExists(t, filepath.Join(path, "policy", "test.json"))
+	assert.NoFileExists(t, filepath.Join(path, "policy", "test.xml"))
+
+	_, err = s.DeleteConfiguration("test", "policy")
+	assert.NoError(t, err)
+
+	policy, err = s.LoadConfiguration("test", "policy")
+	assert.NoError(t, err)
+	assert.Empty(t, policy)
+}
@@ -23,6 +23,10 @@ func (e LoadError) Error() string {
 	return fmt.Sprintf("Unable to load NotificationConfiguration from %s: %v", e.path, e.base)
 }
 
+func (e LoadError) Unwrap() error {
+	return e.base
+}
+
 type SaveError struct {
 	path   string
 	bucket string
@@ -33,6 +37,20 @@ func (e SaveError) Error() string {
 	return fmt.Sprintf("Unable to save NotificationConfiguration for bucket %s to %s: %v", e.bucket, e.path, e.base)
 }
 
+type DeleteError struct {
+	path   string
+	bucket string
+	base   error
+}
+
+func (e DeleteError) Error() string {
+	return fmt.Sprintf("Unable to delete configuration for bucket %s at %s: %v", e.bucket, e.path, e.base)
+}
+
+func (e DeleteError) Unwrap() error {
+	return e.base
+}
+
 type DecodeError struct {
 	path string
 	base error
@@ -50,3 +68,20 @@ type EncodeError struct {
 func (e EncodeError) Error() string {
 	return fmt.Sprintf("Unable to encode %+v to yaml: %v", e.config, e.base)
 }
+
+type UnknownBucketError struct {
+	bucket string
+}
+
+func (e UnknownBucketError) Error() string {
+	return fmt.Sprintf("no NotificationConfiguration for bucket %s has been registered", e.bucket)
+}
+
+type UnknownConfigurationError struct {
+	bucket string
+	id     string
+}
+
+func (e UnknownConfigurationError) Error() string {
+	return fmt.Sprintf("no CloudFunctionConfiguration with id %s in bucket %s", e.id, e.bucket)
+}
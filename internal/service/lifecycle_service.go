@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"sync"
+	"time"
+)
+
+const lifecycleConfigType = "lifecycle"
+
+// LifecycleService applies the stored lifecycle configuration of buckets to their objects in the backend,
+// either periodically once started or when asked to with a simulated current time.
+type LifecycleService struct {
+	configurations *ConfigurationService
+	store          domain.ObjectStore
+	lock           *sync.Mutex
+	worker         worker
+}
+
+func NewLifecycleService(configurations *ConfigurationService, store domain.ObjectStore) *LifecycleService {
+	return &LifecycleService{
+		configurations: configurations,
+		store:          store,
+		lock:           &sync.Mutex{},
+	}
+}
+
+// Start evaluates lifecycle rules of all buckets every interval until stopped.
+func (service *LifecycleService) Start(interval time.Duration) {
+	logger.Infof("Evaluating lifecycle rules every %v", interval)
+
+	service.worker.start(every(interval, func(now time.Time) {
+		_, err := service.Run(context.Background(), now)
+		if err != nil {
+			logger.Warnf("Problem evaluating lifecycle rules: %v", err)
+		}
+	}))
+}
+
+// Stop waits for a run that is in progress before returning.
+func (service *LifecycleService) Stop() {
+	service.worker.stopAndWait()
+}
+
+// Run evaluates the lifecycle rules of every bucket with a lifecycle configuration as of now. Buckets that
+// can't be evaluated don't stop the others, and the first problem is returned once all have been evaluated.
+func (service *LifecycleService) Run(ctx context.Context, now time.Time) ([]domain.LifecycleAction, error) {
+	buckets, err := service.configurations.ConfiguredBuckets(lifecycleConfigType)
+	if err != nil {
+		return nil, err
+	}
+
+	var actions []domain.LifecycleAction
+	var first error
+	for _, bucket := range buckets {
+		bucketActions, err := service.RunBucket(ctx, bucket, now)
+		actions = append(actions, bucketActions...)
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return actions, first
+}
+
+// RunBucket evaluates the lifecycle rules of a bucket as of now, and applies the actions that are due. Actions
+// that fail are returned with their error.
+func (service *LifecycleService) RunBucket(ctx context.Context, bucket string, now time.Time) ([]domain.LifecycleAction, error) {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	var config domain.LifecycleConfiguration
+	ok, err := service.configurations.decodeConfiguration(bucket, lifecycleConfigType, &config)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	logger.Infof("Evaluating lifecycle rules of bucket %s as of %v", bucket, now)
+
+	versions, err := service.store.ListObjectVersions(ctx, bucket, "")
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	if config.NeedsTags() {
+		for i, version := range versions {
+			if version.IsDeleteMarker {
+				continue
+			}
+
+			versions[i].Tags, err = service.store.GetObjectTagging(ctx, bucket, version.Key, version.VersionId)
+			if err != nil {
+				logger.Error(err)
+				return nil, err
+			}
+		}
+	}
+
+	var uploads []domain.MultipartUpload
+	if config.NeedsUploads() {
+		uploads, err = service.store.ListMultipartUploads(ctx, bucket, "")
+		if err != nil {
+			logger.Error(err)
+			return nil, err
+		}
+	}
+
+	actions := config.Evaluate(bucket, versions, uploads, now)
+	for i, action := range actions {
+		err := service.apply(ctx, action)
+		if err != nil {
+			logger.Warnf("Unable to apply %s of rule %s to %s in bucket %s: %v", action.Action, action.Rule, action.Key, bucket, err)
+			actions[i].Error = err.Error()
+			continue
+		}
+
+		logger.Infof("Applied %s of rule %s to %s in bucket %s", action.Action, action.Rule, action.Key, bucket)
+	}
+
+	return actions, nil
+}
+
+func (service *LifecycleService) apply(ctx context.Context, action domain.LifecycleAction) error {
+	if action.Action == domain.AbortUpload {
+		return service.store.AbortMultipartUpload(ctx, action.Bucket, action.Key, action.UploadId)
+	}
+
+	return service.store.DeleteObject(ctx, action.Bucket, action.Key, action.VersionId)
+}
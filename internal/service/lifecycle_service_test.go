@@ -0,0 +1,81 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/ATenderholt/rainbow-storage/internal/service"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type fakeStore struct {
+	domain.ObjectStore
+	versions map[string][]domain.ObjectVersion
+	tags     map[string][]domain.Tag
+	deleted  []string
+	copied   []string
+	objects  map[string]string
+	failPuts bool
+}
+
+func (s *fakeStore) ListObjectVersions(_ context.Context, bucket string, _ string) ([]domain.ObjectVersion, error) {
+	return s.versions[bucket], nil
+}
+
+func (s *fakeStore) GetObjectTagging(_ context.Context, _ string, key string, _ string) ([]domain.Tag, error) {
+	return s.tags[key], nil
+}
+
+func (s *fakeStore) DeleteObject(_ context.Context, bucket string, key string, versionId string) error {
+	if key == "locked.txt" {
+		return errors.New("object is locked")
+	}
+
+	s.deleted = append(s.deleted, bucket+"/"+key+"?"+versionId)
+	return nil
+}
+
+func TestLifecycleServiceRun(t *testing.T) {
+	configurations := service.NewConfigurationService(dataPath(t.TempDir()))
+	_, err := configurations.SaveConfiguration("logs", "lifecycle", []byte(`<LifecycleConfiguration><Rule>
+<ID>expire</ID><Filter><Tag><Key>temporary</Key><Value>true</Value></Tag></Filter><Status>Enabled</Status>
+<Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`))
+	if err != nil {
+		t.Fatalf("Problem saving lifecycle configuration: %v", err)
+	}
+
+	created := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := &fakeStore{
+		versions: map[string][]domain.ObjectVersion{
+			"logs": {
+				{Key: "a.txt", VersionId: "null", IsLatest: true, LastModified: created},
+				{Key: "b.txt", VersionId: "null", IsLatest: true, LastModified: created},
+				{Key: "locked.txt", VersionId: "null", IsLatest: true, LastModified: created},
+			},
+			"other": {
+				{Key: "a.txt", VersionId: "null", IsLatest: true, LastModified: created},
+			},
+		},
+		tags: map[string][]domain.Tag{
+			"a.txt":      {{Key: "temporary", Value: "true"}},
+			"locked.txt": {{Key: "temporary", Value: "true"}},
+		},
+	}
+
+	s := service.NewLifecycleService(configurations, store)
+
+	actions, err := s.Run(context.Background(), created.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, actions)
+	assert.Empty(t, store.deleted)
+
+	actions, err = s.Run(context.Background(), time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.LifecycleAction{
+		{Bucket: "logs", Rule: "expire", Action: domain.ExpireCurrentVersion, Key: "a.txt"},
+		{Bucket: "logs", Rule: "expire", Action: domain.ExpireCurrentVersion, Key: "locked.txt", Error: "object is locked"},
+	}, actions)
+	assert.Equal(t, []string{"logs/a.txt?"}, store.deleted)
+}
@@ -1,12 +1,11 @@
 package service
 
 import (
-	"fmt"
 	"github.com/ATenderholt/rainbow-storage/internal/domain"
-	"github.com/reactivex/rxgo/v2"
 	"gopkg.in/yaml.v2"
 	"os"
 	"path/filepath"
+	"sync"
 )
 
 const notificationDir = "notifications"
@@ -14,14 +13,16 @@ const notificationDir = "notifications"
 type NotificationService struct {
 	cfg     Config
 	invoker domain.CloudFunctionInvoker
-	buckets map[string]chan rxgo.Item
+	lock    *sync.RWMutex
+	buckets map[string]*pipeline
 }
 
 func NewNotificationService(config Config, invoker domain.CloudFunctionInvoker) *NotificationService {
 	return &NotificationService{
 		cfg:     config,
 		invoker: invoker,
-		buckets: make(map[string]chan rxgo.Item),
+		lock:    &sync.RWMutex{},
+		buckets: make(map[string]*pipeline),
 	}
 }
 
@@ -73,11 +74,35 @@ func (service NotificationService) Save(bucket string, config domain.Notificatio
 func (service NotificationService) Start(bucket string, config domain.NotificationConfiguration) {
 	logger.Infof("Starting NotificationConfigurations for bucket %s", bucket)
 
-	ch, _ := config.Start(service.invoker)
-	service.buckets[bucket] = ch
+	p := newPipeline(bucket, config, service.invoker)
+
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	if replaced, ok := service.buckets[bucket]; ok {
+		p.takePauses(replaced)
+	}
+
+	service.buckets[bucket] = p
 }
 
-func (service NotificationService) Load(path string) error {
+func (service NotificationService) pipeline(bucket string) (*pipeline, error) {
+	service.lock.RLock()
+	defer service.lock.RUnlock()
+
+	p, ok := service.buckets[bucket]
+	if !ok {
+		err := UnknownBucketError{bucket: bucket}
+		logger.Error(err)
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (service NotificationService) read(path string) (domain.NotificationConfiguration, error) {
+	var config domain.NotificationConfiguration
+
 	file, err := os.Open(path)
 	if err != nil {
 		err := LoadError{
@@ -85,11 +110,10 @@ func (service NotificationService) Load(path string) error {
 			base: err,
 		}
 		logger.Error(err)
-		return err
+		return config, err
 	}
 	defer file.Close()
 
-	var config domain.NotificationConfiguration
 	err = yaml.NewDecoder(file).Decode(&config)
 	if err != nil {
 		err := DecodeError{
@@ -97,6 +121,20 @@ func (service NotificationService) Load(path string) error {
 			base: err,
 		}
 		logger.Error(err)
+		return config, err
+	}
+
+	return config, nil
+}
+
+// GetConfiguration loads the saved NotificationConfiguration for the bucket without starting it.
+func (service NotificationService) GetConfiguration(bucket string) (domain.NotificationConfiguration, error) {
+	return service.read(service.GetConfigurationPath(bucket))
+}
+
+func (service NotificationService) Load(path string) error {
+	config, err := service.read(path)
+	if err != nil {
 		return err
 	}
 
@@ -137,15 +175,49 @@ func (service NotificationService) LoadAll() error {
 }
 
 func (service NotificationService) ProcessEvent(event domain.NotificationEvent) error {
-	ch, ok := service.buckets[event.Bucket]
-	if !ok {
-		err := fmt.Errorf("no NotificationConfiguration for for bucket %s has been registered", event.Bucket)
-		logger.Error(err)
+	p, err := service.pipeline(event.Bucket)
+	if err != nil {
 		return err
 	}
 
-	item := rxgo.Item{V: event}
-	ch <- item
+	p.Send(event)
 
 	return nil
 }
+
+// Pause stops delivering events for the bucket, or only to the CloudFunctionConfiguration with
+// the provided id when it isn't empty. Events are buffered or dropped depending on the mode.
+func (service NotificationService) Pause(bucket string, id string, mode domain.PauseMode) error {
+	p, err := service.pipeline(bucket)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Pausing notifications for bucket %s (id: %q) with mode %s", bucket, id, mode)
+	return p.Pause(id, mode)
+}
+
+// Resume restarts delivery of events paused by Pause, and returns how many buffered events were flushed.
+func (service NotificationService) Resume(bucket string, id string) (int, error) {
+	p, err := service.pipeline(bucket)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := p.Resume(id)
+	if err != nil {
+		return count, err
+	}
+
+	logger.Infof("Resumed notifications for bucket %s (id: %q), flushed %d events", bucket, id, count)
+	return count, nil
+}
+
+func (service NotificationService) PauseStatus(bucket string) (domain.PauseStatus, error) {
+	p, err := service.pipeline(bucket)
+	if err != nil {
+		return domain.PauseStatus{}, err
+	}
+
+	return p.Status(), nil
+}
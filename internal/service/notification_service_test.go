@@ -80,3 +80,93 @@ func TestNotificationServiceReadAndWrite(t *testing.T) {
 
 	assert.Equal(t, testData[1], value)
 }
+
+func TestNotificationServicePauseAndResume(t *testing.T) {
+	ch := make(chan domain.NotificationEvent, 10)
+
+	cfg := TestHelper{ch}
+	s := service.NewNotificationService(cfg, cfg)
+	s.Start("test", domain.NotificationConfiguration{
+		CloudFunctionConfigurations: []domain.CloudFunctionConfiguration{
+			{
+				Events:        []string{domain.ObjectCreatedFilter},
+				Id:            "some-id",
+				CloudFunction: domain.CloudFunction("something"),
+			},
+		},
+	})
+
+	buffered := domain.NotificationEvent{Event: domain.ObjectCreatedEvent, Bucket: "test", Key: "buffered.bin"}
+	dropped := domain.NotificationEvent{Event: domain.ObjectCreatedEvent, Bucket: "test", Key: "dropped.bin"}
+	sent := domain.NotificationEvent{Event: domain.ObjectCreatedEvent, Bucket: "test", Key: "sent.bin"}
+
+	assert.NoError(t, s.Pause("test", "", domain.PauseBuffer))
+	assert.NoError(t, s.ProcessEvent(buffered))
+
+	assert.NoError(t, s.Pause("test", "some-id", domain.PauseDrop))
+	assert.NoError(t, s.Pause("test", "", domain.PauseBuffer))
+
+	status, err := s.PauseStatus("test")
+	assert.NoError(t, err)
+	assert.True(t, status.Paused)
+	assert.Equal(t, 1, status.Buffered)
+	assert.Equal(t, domain.PauseDrop, status.Configurations[0].Mode)
+
+	// flushed from bucket, but then dropped by the paused configuration
+	count, err := s.Resume("test", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	assert.NoError(t, s.ProcessEvent(dropped))
+
+	count, err = s.Resume("test", "some-id")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	assert.NoError(t, s.ProcessEvent(sent))
+	assert.Equal(t, sent, <-ch)
+
+	assert.Error(t, s.Pause("test", "unknown-id", domain.PauseDrop))
+	assert.Error(t, s.Pause("unknown", "", domain.PauseDrop))
+}
+
+func TestNotificationServiceKeepsPausesWhenRestarted(t *testing.T) {
+	ch := make(chan domain.NotificationEvent, 10)
+
+	cfg := TestHelper{ch}
+	s := service.NewNotificationService(cfg, cfg)
+
+	funcConfig := func(id string) domain.CloudFunctionConfiguration {
+		return domain.CloudFunctionConfiguration{
+			Events:        []string{domain.ObjectCreatedFilter},
+			Id:            id,
+			CloudFunction: domain.CloudFunction("something"),
+		}
+	}
+
+	s.Start("test", domain.NotificationConfiguration{
+		CloudFunctionConfigurations: []domain.CloudFunctionConfiguration{funcConfig("kept"), funcConfig("removed")},
+	})
+
+	buffered := domain.NotificationEvent{Event: domain.ObjectCreatedEvent, Bucket: "test", Key: "buffered.bin"}
+	assert.NoError(t, s.Pause("test", "kept", domain.PauseDrop))
+	assert.NoError(t, s.Pause("test", "removed", domain.PauseBuffer))
+	assert.NoError(t, s.Pause("test", "", domain.PauseBuffer))
+	for i := 0; i < 10001; i++ {
+		assert.NoError(t, s.ProcessEvent(buffered))
+	}
+
+	s.Start("test", domain.NotificationConfiguration{
+		CloudFunctionConfigurations: []domain.CloudFunctionConfiguration{funcConfig("kept"), funcConfig("added")},
+	})
+
+	status, err := s.PauseStatus("test")
+	assert.NoError(t, err)
+	assert.True(t, status.Paused)
+	assert.Equal(t, domain.PauseBuffer, status.Mode)
+	assert.Equal(t, 10000, status.Buffered, "buffer is limited")
+	assert.Equal(t, []domain.ConfigurationPauseStatus{
+		{Id: "kept", Paused: true, Mode: domain.PauseDrop},
+		{Id: "added"},
+	}, status.Configurations)
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/reactivex/rxgo/v2"
+	"sync"
+)
+
+// maxPausedEvents limits how many events are buffered by a single pause, so that a forgotten pause doesn't
+// keep every event in memory
+const maxPausedEvents = 10000
+
+type pause struct {
+	mode   domain.PauseMode
+	buffer []domain.NotificationEvent
+}
+
+// hold keeps the event if buffering and the buffer isn't full, and returns whether it was kept
+func (p *pause) hold(event domain.NotificationEvent) bool {
+	if p.mode == domain.PauseDrop || len(p.buffer) >= maxPausedEvents {
+		return false
+	}
+
+	p.buffer = append(p.buffer, event)
+	return true
+}
+
+type target struct {
+	id     string
+	ch     chan rxgo.Item
+	paused *pause
+}
+
+// pipeline fans events for a bucket out to one channel per CloudFunctionConfiguration, so that
+// delivery can be paused for the whole bucket or for a single configuration Id.
+type pipeline struct {
+	lock    sync.Mutex
+	bucket  string
+	paused  *pause
+	targets []*target
+}
+
+func newPipeline(bucket string, config domain.NotificationConfiguration, invoker domain.CloudFunctionInvoker) *pipeline {
+	p := pipeline{bucket: bucket}
+	for _, funcConfig := range config.CloudFunctionConfigurations {
+		single := domain.NotificationConfiguration{
+			CloudFunctionConfigurations: []domain.CloudFunctionConfiguration{funcConfig},
+		}
+
+		ch, _ := single.Start(invoker)
+		p.targets = append(p.targets, &target{id: funcConfig.Id, ch: ch})
+	}
+
+	return &p
+}
+
+// takePauses continues the pauses of the pipeline that is replaced, along with their buffered events. Pauses of
+// configurations that were removed are dropped.
+func (p *pipeline) takePauses(replaced *pipeline) {
+	replaced.lock.Lock()
+	defer replaced.lock.Unlock()
+
+	p.paused = replaced.paused
+	for _, old := range replaced.targets {
+		if old.paused == nil {
+			continue
+		}
+
+		t, ok := p.find(old.id)
+		if !ok {
+			logger.Warnf("Dropping %d events buffered for removed configuration %s in bucket %s", len(old.paused.buffer), old.id, p.bucket)
+			continue
+		}
+
+		t.paused = old.paused
+	}
+
+	// events still sent to the replaced pipeline stay paused, without sharing the buffers that were taken
+	replaced.paused = emptyPause(replaced.paused)
+	for _, old := range replaced.targets {
+		old.paused = emptyPause(old.paused)
+	}
+}
+
+func emptyPause(existing *pause) *pause {
+	if existing == nil {
+		return nil
+	}
+
+	return &pause{mode: existing.mode}
+}
+
+func (p *pipeline) find(id string) (*target, bool) {
+	for _, t := range p.targets {
+		if t.id == id {
+			return t, true
+		}
+	}
+
+	return nil, false
+}
+
+func (p *pipeline) send(event domain.NotificationEvent) {
+	if p.paused != nil {
+		if !p.paused.hold(event) {
+			logger.Infof("Dropping event for key %s in paused bucket %s", event.Key, p.bucket)
+		}
+		return
+	}
+
+	for _, t := range p.targets {
+		p.sendTo(t, event)
+	}
+}
+
+func (p *pipeline) sendTo(t *target, event domain.NotificationEvent) {
+	if t.paused != nil {
+		if !t.paused.hold(event) {
+			logger.Infof("Dropping event for key %s to paused configuration %s in bucket %s", event.Key, t.id, p.bucket)
+		}
+		return
+	}
+
+	t.ch <- rxgo.Item{V: event}
+}
+
+func (p *pipeline) Send(event domain.NotificationEvent) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.send(event)
+}
+
+func (p *pipeline) Pause(id string, mode domain.PauseMode) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if id == "" {
+		p.paused = updatePause(p.paused, mode)
+		return nil
+	}
+
+	t, ok := p.find(id)
+	if !ok {
+		return UnknownConfigurationError{bucket: p.bucket, id: id}
+	}
+
+	t.paused = updatePause(t.paused, mode)
+	return nil
+}
+
+// updatePause keeps anything already buffered when a pause is changed to another mode
+func updatePause(existing *pause, mode domain.PauseMode) *pause {
+	if existing == nil {
+		return &pause{mode: mode}
+	}
+
+	existing.mode = mode
+	return existing
+}
+
+// Resume restarts delivery and flushes any buffered events, returning how many were flushed.
+func (p *pipeline) Resume(id string) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if id == "" {
+		if p.paused == nil {
+			return 0, nil
+		}
+
+		buffer := p.paused.buffer
+		p.paused = nil
+		for _, event := range buffer {
+			p.send(event)
+		}
+
+		return len(buffer), nil
+	}
+
+	t, ok := p.find(id)
+	if !ok {
+		return 0, UnknownConfigurationError{bucket: p.bucket, id: id}
+	}
+
+	if t.paused == nil {
+		return 0, nil
+	}
+
+	buffer := t.paused.buffer
+	t.paused = nil
+	for _, event := range buffer {
+		p.sendTo(t, event)
+	}
+
+	return len(buffer), nil
+}
+
+func (p *pipeline) Status() domain.PauseStatus {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	status := domain.PauseStatus{
+		Bucket:         p.bucket,
+		Paused:         p.paused != nil,
+		Configurations: make([]domain.ConfigurationPauseStatus, 0, len(p.targets)),
+	}
+
+	if p.paused != nil {
+		status.Mode = p.paused.mode
+		status.Buffered = len(p.paused.buffer)
+	}
+
+	for _, t := range p.targets {
+		configStatus := domain.ConfigurationPauseStatus{
+			Id:     t.id,
+			Paused: t.paused != nil,
+		}
+
+		if t.paused != nil {
+			configStatus.Mode = t.paused.mode
+			configStatus.Buffered = len(t.paused.buffer)
+		}
+
+		status.Configurations = append(status.Configurations, configStatus)
+	}
+
+	return status
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"sync"
+)
+
+const (
+	replicationConfigType = "replication"
+	replicationQueueSize  = 1024
+
+	// maxReplicationStatuses limits how many statuses are kept, forgetting the oldest first
+	maxReplicationStatuses = 100000
+)
+
+// backendStorageClasses are the storage classes the backend accepts. Replicas of rules with another storage class
+// keep the class of their source, but report the one of the rule.
+var backendStorageClasses = map[string]bool{
+	"STANDARD":           true,
+	"REDUCED_REDUNDANCY": true,
+}
+
+type replicationJob struct {
+	bucket       string
+	key          string
+	versionId    string
+	deleteMarker bool
+}
+
+// ReplicationService copies new object versions, and optionally deletes, to the destination buckets of the stored
+// replication configuration. Objects are matched with the rules and copied in the order they were written by a
+// single worker, and the replication status of recent versions is kept in memory.
+type ReplicationService struct {
+	configurations *ConfigurationService
+	store          domain.ObjectStore
+	lock           *sync.RWMutex
+	statuses       map[string]domain.ObjectReplication
+	statusKeys     []string // keys of statuses, oldest first
+	jobs           chan replicationJob
+	worker         worker
+}
+
+func NewReplicationService(configurations *ConfigurationService, store domain.ObjectStore) *ReplicationService {
+	return &ReplicationService{
+		configurations: configurations,
+		store:          store,
+		lock:           &sync.RWMutex{},
+		statuses:       make(map[string]domain.ObjectReplication),
+		jobs:           make(chan replicationJob, replicationQueueSize),
+	}
+}
+
+func statusKey(bucket string, key string, versionId string) string {
+	return bucket + "/" + key + "?versionId=" + versionId
+}
+
+// Start replicates queued objects until stopped.
+func (service *ReplicationService) Start() {
+	logger.Info("Starting replication")
+
+	service.worker.start(func(stop <-chan struct{}) {
+		for {
+			select {
+			case job := <-service.jobs:
+				service.replicate(job)
+			case <-stop:
+				service.drain()
+				return
+			}
+		}
+	})
+}
+
+// drain replicates the objects that are already queued.
+func (service *ReplicationService) drain() {
+	for {
+		select {
+		case job := <-service.jobs:
+			service.replicate(job)
+		default:
+			return
+		}
+	}
+}
+
+// Stop replicates objects that are already queued before returning.
+func (service *ReplicationService) Stop() {
+	service.worker.stopAndWait()
+}
+
+// ObjectCreated queues a new version of an object, which is replicated if it matches any rule. Its status is
+// PENDING right away if a rule could match it, until the worker has replicated it or found that its tags don't match.
+func (service *ReplicationService) ObjectCreated(_ context.Context, bucket string, key string, versionId string) {
+	var config domain.ReplicationConfiguration
+	ok, err := service.configurations.decodeConfiguration(bucket, replicationConfigType, &config)
+	if err == nil && ok && config.MayReplicate(key) {
+		service.setStatus(bucket, key, versionId, domain.ObjectReplication{Status: domain.ReplicationPending})
+	}
+
+	service.queue(replicationJob{bucket: bucket, key: key, versionId: versionId})
+}
+
+// DeleteMarkerCreated queues the deletion of the current version of an object, which is replicated if it
+// matches a rule that replicates delete markers.
+func (service *ReplicationService) DeleteMarkerCreated(_ context.Context, bucket string, key string) {
+	service.queue(replicationJob{bucket: bucket, key: key, deleteMarker: true})
+}
+
+// Status returns the replication status of a version of an object, if it is replicated or a replica.
+func (service *ReplicationService) Status(bucket string, key string, versionId string) (domain.ObjectReplication, bool) {
+	service.lock.RLock()
+	defer service.lock.RUnlock()
+
+	status, ok := service.statuses[statusKey(bucket, key, versionId)]
+	return status, ok
+}
+
+func (service *ReplicationService) setStatus(bucket string, key string, versionId string, status domain.ObjectReplication) {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	k := statusKey(bucket, key, versionId)
+	if _, ok := service.statuses[k]; !ok {
+		service.statusKeys = append(service.statusKeys, k)
+		if len(service.statusKeys) > maxReplicationStatuses {
+			delete(service.statuses, service.statusKeys[0])
+			service.statusKeys = service.statusKeys[1:]
+		}
+	}
+
+	service.statuses[k] = status
+}
+
+func (service *ReplicationService) clearStatus(bucket string, key string, versionId string) {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	k := statusKey(bucket, key, versionId)
+	if _, ok := service.statuses[k]; !ok {
+		return
+	}
+
+	delete(service.statuses, k)
+
+	// the status was set recently, so it is near the end
+	for i := len(service.statusKeys) - 1; i >= 0; i-- {
+		if service.statusKeys[i] == k {
+			service.statusKeys = append(service.statusKeys[:i], service.statusKeys[i+1:]...)
+			break
+		}
+	}
+}
+
+func (service *ReplicationService) queue(job replicationJob) {
+	select {
+	case service.jobs <- job:
+	default:
+		logger.Warnf("Replication queue is full, not replicating %s in bucket %s", job.key, job.bucket)
+	}
+}
+
+// destinations matches an object with the replication rules of its bucket, loading its tags if rules need them.
+func (service *ReplicationService) destinations(ctx context.Context, job replicationJob) ([]domain.ReplicationDestination, error) {
+	var config domain.ReplicationConfiguration
+	ok, err := service.configurations.decodeConfiguration(job.bucket, replicationConfigType, &config)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	var tags []domain.Tag
+	if !job.deleteMarker && config.NeedsTags() {
+		tags, err = service.store.GetObjectTagging(ctx, job.bucket, job.key, job.versionId)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get tags: %v", err)
+		}
+	}
+
+	return config.Destinations(job.key, tags, job.deleteMarker), nil
+}
+
+func (service *ReplicationService) replicate(job replicationJob) {
+	ctx := context.Background()
+
+	destinations, err := service.destinations(ctx, job)
+	if err != nil {
+		logger.Warnf("Unable to match %s in bucket %s with replication rules: %v", job.key, job.bucket, err)
+		if !job.deleteMarker {
+			service.setStatus(job.bucket, job.key, job.versionId, domain.ObjectReplication{Status: domain.ReplicationFailed})
+		}
+		return
+	}
+
+	if len(destinations) == 0 {
+		if !job.deleteMarker {
+			service.clearStatus(job.bucket, job.key, job.versionId)
+		}
+		return
+	}
+
+	status := domain.ReplicationCompleted
+	for _, destination := range destinations {
+		bucket := destination.BucketName()
+
+		err := service.replicateTo(ctx, job, destination)
+		if err != nil {
+			logger.Warnf("Unable to replicate %s in bucket %s to bucket %s: %v", job.key, job.bucket, bucket, err)
+			status = domain.ReplicationFailed
+			continue
+		}
+
+		logger.Infof("Replicated %s in bucket %s to bucket %s", job.key, job.bucket, bucket)
+	}
+
+	if !job.deleteMarker {
+		service.setStatus(job.bucket, job.key, job.versionId, domain.ObjectReplication{Status: status})
+	}
+}
+
+func (service *ReplicationService) replicateTo(ctx context.Context, job replicationJob, destination domain.ReplicationDestination) error {
+	bucket := destination.BucketName()
+	if bucket == job.bucket {
+		return fmt.Errorf("destination is the source bucket")
+	}
+
+	if job.deleteMarker {
+		return service.store.DeleteObject(ctx, bucket, job.key, "")
+	}
+
+	var storageClass string
+	if backendStorageClasses[destination.StorageClass] {
+		storageClass = destination.StorageClass
+	}
+
+	versionId, err := service.store.CopyObject(ctx, job.bucket, job.key, job.versionId, bucket, storageClass)
+	if err != nil {
+		return err
+	}
+
+	service.setStatus(bucket, job.key, versionId, domain.ObjectReplication{
+		Status:       domain.ReplicationReplica,
+		StorageClass: destination.StorageClass,
+	})
+	return nil
+}
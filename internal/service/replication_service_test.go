@@ -0,0 +1,91 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"github.com/ATenderholt/rainbow-storage/internal/domain"
+	"github.com/ATenderholt/rainbow-storage/internal/service"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func (s *fakeStore) CopyObject(_ context.Context, bucket string, key string, versionId string, destination string, storageClass string) (string, error) {
+	if destination == "missing" {
+		return "", errors.New("NoSuchBucket")
+	}
+
+	copied := bucket + "/" + key + "?" + versionId + " -> " + destination
+	if storageClass != "" {
+		copied += " as " + storageClass
+	}
+
+	s.copied = append(s.copied, copied)
+	return "replica-" + versionId, nil
+}
+
+func TestReplicationService(t *testing.T) {
+	configurations := service.NewConfigurationService(dataPath(t.TempDir()))
+	_, err := configurations.SaveConfiguration("source", "replication", []byte(`<ReplicationConfiguration>
+<Role>arn:aws:iam::271828182845:role/replication</Role>
+<Rule><ID>docs</ID><Status>Enabled</Status><Filter><Prefix>docs/</Prefix></Filter>
+<Destination><Bucket>arn:aws:s3:::backup</Bucket><StorageClass>STANDARD_IA</StorageClass></Destination>
+<DeleteMarkerReplication><Status>Enabled</Status></DeleteMarkerReplication></Rule>
+<Rule><ID>tagged</ID><Status>Enabled</Status><Filter><And><Prefix>tagged/</Prefix><Tag><Key>replicate</Key><Value>true</Value></Tag></And></Filter>
+<Destination><Bucket>arn:aws:s3:::backup</Bucket><StorageClass>REDUCED_REDUNDANCY</StorageClass></Destination></Rule>
+<Rule><ID>broken</ID><Status>Enabled</Status><Filter><Prefix>broken/</Prefix></Filter>
+<Destination><Bucket>arn:aws:s3:::missing</Bucket></Destination></Rule>
+</ReplicationConfiguration>`))
+	if err != nil {
+		t.Fatalf("Problem saving replication configuration: %v", err)
+	}
+
+	store := &fakeStore{tags: map[string][]domain.Tag{"tagged/a.txt": {{Key: "replicate", Value: "true"}}}}
+	s := service.NewReplicationService(configurations, store)
+
+	ctx := context.Background()
+	s.ObjectCreated(ctx, "source", "docs/a.txt", "1")
+	s.ObjectCreated(ctx, "source", "broken/a.txt", "2")
+	s.ObjectCreated(ctx, "source", "other.txt", "3")
+	s.ObjectCreated(ctx, "source", "tagged/a.txt", "4")
+	s.ObjectCreated(ctx, "source", "tagged/b.txt", "5")
+	s.ObjectCreated(ctx, "unconfigured", "docs/a.txt", "6")
+	s.DeleteMarkerCreated(ctx, "source", "docs/b.txt")
+	s.DeleteMarkerCreated(ctx, "source", "other.txt")
+
+	// objects are pending until the worker matched them with the rules, including their tags
+	status, _ := s.Status("source", "docs/a.txt", "1")
+	assert.Equal(t, domain.ObjectReplication{Status: domain.ReplicationPending}, status)
+
+	status, _ = s.Status("source", "tagged/b.txt", "5")
+	assert.Equal(t, domain.ObjectReplication{Status: domain.ReplicationPending}, status)
+
+	_, ok := s.Status("source", "other.txt", "3")
+	assert.False(t, ok)
+
+	_, ok = s.Status("unconfigured", "docs/a.txt", "6")
+	assert.False(t, ok)
+
+	s.Start()
+	s.Stop()
+
+	assert.Equal(t, []string{"source/docs/a.txt?1 -> backup", "source/tagged/a.txt?4 -> backup as REDUCED_REDUNDANCY"}, store.copied)
+	assert.Equal(t, []string{"backup/docs/b.txt?"}, store.deleted)
+
+	_, ok = s.Status("source", "other.txt", "3")
+	assert.False(t, ok)
+
+	_, ok = s.Status("source", "tagged/b.txt", "5")
+	assert.False(t, ok)
+
+	status, _ = s.Status("backup", "tagged/a.txt", "replica-4")
+	assert.Equal(t, domain.ObjectReplication{Status: domain.ReplicationReplica, StorageClass: "REDUCED_REDUNDANCY"}, status)
+
+	status, _ = s.Status("source", "docs/a.txt", "1")
+	assert.Equal(t, domain.ObjectReplication{Status: domain.ReplicationCompleted}, status)
+
+	status, _ = s.Status("backup", "docs/a.txt", "replica-1")
+	assert.Equal(t, domain.ObjectReplication{Status: domain.ReplicationReplica, StorageClass: "STANDARD_IA"}, status)
+
+	status, _ = s.Status("source", "broken/a.txt", "2")
+	assert.Equal(t, domain.ObjectReplication{Status: domain.ReplicationFailed}, status)
+}
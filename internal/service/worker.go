@@ -0,0 +1,49 @@
+package service
+
+import "time"
+
+// worker runs the loop of a service in the background until it is stopped.
+type worker struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// start runs the loop, which has to return once stop is closed.
+func (w *worker) start(loop func(stop <-chan struct{})) {
+	w.stop = make(chan struct{})
+	w.done = make(chan struct{})
+
+	go func(stop chan struct{}, done chan struct{}) {
+		defer close(done)
+		loop(stop)
+	}(w.stop, w.done)
+}
+
+// stopAndWait stops the loop and waits for it to return, returning whether it was running.
+func (w *worker) stopAndWait() bool {
+	if w.stop == nil {
+		return false
+	}
+
+	close(w.stop)
+	<-w.done
+	w.stop = nil
+	return true
+}
+
+// every returns a loop that calls f with the current time every interval.
+func every(interval time.Duration, f func(now time.Time)) func(stop <-chan struct{}) {
+	return func(stop <-chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				f(now)
+			}
+		}
+	}
+}
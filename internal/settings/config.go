@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	"gopkg.in/yaml.v2"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 const (
@@ -19,6 +21,24 @@ const (
 	DefaultImage    = "bitnami/minio:2022.2.16"
 
 	DefaultNetworks = "rainbow"
+
+	DefaultVirtualHostDomain = "s3.localhost"
+	DefaultWebsiteDomain     = "s3-website.localhost"
+
+	// lifecycle rules delete data, so they are only applied on a schedule when asked to
+	DefaultLifecycleInterval time.Duration = 0
+	DefaultAccessLogInterval               = time.Minute
+
+	DefaultBackendAccessKey = "minio"
+	DefaultBackendSecretKey = "miniosecret"
+)
+
+// Environment variables used for backend settings that aren't provided as flags
+const (
+	BackendUrlEnv       = "RAINBOW_BACKEND_URL"
+	BackendAccessKeyEnv = "RAINBOW_BACKEND_ACCESS_KEY"
+	BackendSecretKeyEnv = "RAINBOW_BACKEND_SECRET_KEY"
+	BackendRegionEnv    = "RAINBOW_BACKEND_REGION"
 )
 
 type Config struct {
@@ -33,6 +53,29 @@ type Config struct {
 	Image    string
 
 	Networks []string
+
+	VerifySignatures bool
+	Credentials      map[string]string
+	PolicyLogOnly    bool
+
+	VirtualHostDomain string
+
+	WebsitePort   int
+	WebsiteDomain string
+
+	LifecycleInterval time.Duration
+	AccessLogInterval time.Duration
+
+	BackendUrl       string
+	BackendAccessKey string
+	BackendSecretKey string
+	BackendRegion    string
+	ForwardChecksums bool
+
+	TlsPort     int
+	TlsCertFile string
+	TlsKeyFile  string
+	tlsHosts    []string
 }
 
 func (config *Config) DataPath() string {
@@ -48,7 +91,36 @@ func (config *Config) DataPath() string {
 	return filepath.Join(cwd, config.dataPath)
 }
 
+// TlsPath is where the local CA and server certificate are persisted when no certificate is configured.
+func (config *Config) TlsPath() string {
+	return filepath.Join(config.DataPath(), "tls")
+}
+
+// TlsHosts are the names and addresses covered by a generated server certificate.
+func (config *Config) TlsHosts() []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if config.VirtualHostDomain != "" {
+		hosts = append(hosts, config.VirtualHostDomain, "*."+config.VirtualHostDomain)
+	}
+
+	if config.WebsiteDomain != "" {
+		hosts = append(hosts, config.WebsiteDomain, "*."+config.WebsiteDomain)
+	}
+
+	return append(hosts, config.tlsHosts...)
+}
+
+// IsExternalBackend returns whether requests are proxied to an S3 service that is already running,
+// rather than to the container started by the application.
+func (config *Config) IsExternalBackend() bool {
+	return config.BackendUrl != ""
+}
+
 func (config *Config) MinioUrl() string {
+	if config.IsExternalBackend() {
+		return config.BackendUrl
+	}
+
 	if config.IsLocal {
 		return fmt.Sprintf("http://localhost:%d", config.BasePort+1)
 	} else {
@@ -74,6 +146,16 @@ func DefaultConfig() *Config {
 		dataPath:       DefaultDataPath,
 		Image:          DefaultImage,
 		Networks:       []string{DefaultNetworks},
+		Credentials:    map[string]string{},
+
+		VirtualHostDomain: DefaultVirtualHostDomain,
+		WebsiteDomain:     DefaultWebsiteDomain,
+		LifecycleInterval: DefaultLifecycleInterval,
+		AccessLogInterval: DefaultAccessLogInterval,
+
+		BackendAccessKey: DefaultBackendAccessKey,
+		BackendSecretKey: DefaultBackendSecretKey,
+		BackendRegion:    DefaultRegion,
 	}
 }
 
@@ -94,6 +176,84 @@ func (v *NetworkValue) String() string {
 	return ""
 }
 
+// CredentialsValue parses a comma-separated list of ACCESS_KEY:SECRET pairs.
+type CredentialsValue struct {
+	credentials map[string]string
+}
+
+func (v *CredentialsValue) Set(s string) error {
+	v.credentials = make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("expected ACCESS_KEY:SECRET but got %s", pair)
+		}
+
+		v.credentials[parts[0]] = parts[1]
+	}
+
+	return nil
+}
+
+func (v *CredentialsValue) String() string {
+	var keys []string
+	for key := range v.credentials {
+		keys = append(keys, key+":****")
+	}
+
+	return strings.Join(keys, ",")
+}
+
+// fileConfig is the format of the file provided with -config. Settings in the file are overridden by
+// environment variables, which are overridden by flags.
+type fileConfig struct {
+	Backend struct {
+		Url       string `yaml:"url"`
+		AccessKey string `yaml:"accessKey"`
+		SecretKey string `yaml:"secretKey"`
+		Region    string `yaml:"region"`
+	} `yaml:"backend"`
+}
+
+func loadFileConfig(path string) (fileConfig, error) {
+	var config fileConfig
+	if path == "" {
+		return config, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("unable to read config file %s: %v", path, err)
+	}
+
+	err = yaml.UnmarshalStrict(content, &config)
+	if err != nil {
+		return config, fmt.Errorf("unable to parse config file %s: %v", path, err)
+	}
+
+	return config, nil
+}
+
+// overrides sets a value that wasn't provided as a flag from the environment, or else from the config file.
+type overrides struct {
+	flags map[string]bool
+}
+
+func (o overrides) apply(target *string, name string, env string, fileValue string) {
+	if o.flags[name] {
+		return
+	}
+
+	if value, ok := os.LookupEnv(env); ok && value != "" {
+		*target = value
+		return
+	}
+
+	if fileValue != "" {
+		*target = fileValue
+	}
+}
+
 func FromFlags(name string, args []string) (*Config, string, error) {
 	flags := flag.NewFlagSet(name, flag.ContinueOnError)
 
@@ -102,6 +262,7 @@ func FromFlags(name string, args []string) (*Config, string, error) {
 
 	var cfg Config
 	networks := NetworkValue{[]string{DefaultNetworks}}
+	credentials := CredentialsValue{map[string]string{}}
 	flags.StringVar(&cfg.AccountNumber, "account-number", DefaultAccountNumber, "Account number returned in ARNs")
 	flags.BoolVar(&cfg.IsDebug, "debug", false, "Enable debug logging")
 	flags.BoolVar(&cfg.IsLocal, "local", true, "Application should use localhost when routing to s3 service")
@@ -111,6 +272,28 @@ func FromFlags(name string, args []string) (*Config, string, error) {
 	flags.StringVar(&cfg.Image, "image", DefaultImage, "Image to use for backing storage")
 	flags.StringVar(&cfg.dataPath, "data-path", DefaultDataPath, "Path to persist data and s3 configuration")
 	flags.Var(&networks, "networks", "Comma-separated list of Networks for containers")
+	flags.BoolVar(&cfg.VerifySignatures, "verify-signatures", false, "Reject requests unless they are signed by one of the credentials")
+	flags.Var(&credentials, "credentials", "Comma-separated list of ACCESS_KEY:SECRET accepted when verifying signatures")
+	flags.BoolVar(&cfg.PolicyLogOnly, "policy-log-only", false, "Log requests denied by bucket policies instead of rejecting them")
+	flags.StringVar(&cfg.VirtualHostDomain, "virtual-host-domain", DefaultVirtualHostDomain, "Base domain for virtual-hosted-style requests (i.e. bucket.s3.localhost), empty to disable")
+	flags.IntVar(&cfg.WebsitePort, "website-port", 0, "Port serving buckets as static websites by host name, 0 to disable")
+	flags.StringVar(&cfg.WebsiteDomain, "website-domain", DefaultWebsiteDomain, "Base domain for website requests on any port (i.e. bucket.s3-website.localhost), empty to disable")
+	flags.DurationVar(&cfg.LifecycleInterval, "lifecycle-interval", DefaultLifecycleInterval, "How often lifecycle rules of buckets are applied, 0 to disable")
+	flags.DurationVar(&cfg.AccessLogInterval, "access-log-interval", DefaultAccessLogInterval, "How often server access logs are written to target buckets, 0 to disable server access logging")
+
+	var configPath string
+	flags.StringVar(&configPath, "config", "", "Path to YAML config file, overridden by environment variables and flags")
+	flags.StringVar(&cfg.BackendUrl, "backend-url", "", "URL of an existing S3 service to use instead of starting a container (env "+BackendUrlEnv+")")
+	flags.StringVar(&cfg.BackendAccessKey, "backend-access-key", DefaultBackendAccessKey, "Access key used to sign requests to the s3 service (env "+BackendAccessKeyEnv+")")
+	flags.StringVar(&cfg.BackendSecretKey, "backend-secret-key", DefaultBackendSecretKey, "Secret key used to sign requests to the s3 service (env "+BackendSecretKeyEnv+")")
+	flags.StringVar(&cfg.BackendRegion, "backend-region", DefaultRegion, "Region used to sign requests to the s3 service (env "+BackendRegionEnv+")")
+	flags.BoolVar(&cfg.ForwardChecksums, "forward-checksums", false, "Forward trailing checksums of uploads to the s3 service, which must support them")
+
+	var tlsHosts string
+	flags.IntVar(&cfg.TlsPort, "tls-port", 0, "Port used for HTTPS, 0 to disable")
+	flags.StringVar(&cfg.TlsCertFile, "tls-cert", "", "Certificate file for HTTPS, otherwise a certificate is generated by a local CA")
+	flags.StringVar(&cfg.TlsKeyFile, "tls-key", "", "Key file for the certificate provided with -tls-cert")
+	flags.StringVar(&tlsHosts, "tls-hosts", "", "Comma-separated list of additional names and addresses for a generated certificate")
 
 	err := flags.Parse(args)
 	if err != nil {
@@ -118,6 +301,32 @@ func FromFlags(name string, args []string) (*Config, string, error) {
 	}
 
 	cfg.Networks = networks.networks
+	cfg.Credentials = credentials.credentials
+
+	if (cfg.TlsCertFile == "") != (cfg.TlsKeyFile == "") {
+		return nil, buf.String(), fmt.Errorf("-tls-cert and -tls-key must be provided together")
+	}
+
+	for _, host := range strings.Split(tlsHosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			cfg.tlsHosts = append(cfg.tlsHosts, host)
+		}
+	}
+
+	file, err := loadFileConfig(configPath)
+	if err != nil {
+		return nil, buf.String(), err
+	}
+
+	set := overrides{flags: map[string]bool{}}
+	flags.Visit(func(f *flag.Flag) {
+		set.flags[f.Name] = true
+	})
+
+	set.apply(&cfg.BackendUrl, "backend-url", BackendUrlEnv, file.Backend.Url)
+	set.apply(&cfg.BackendAccessKey, "backend-access-key", BackendAccessKeyEnv, file.Backend.AccessKey)
+	set.apply(&cfg.BackendSecretKey, "backend-secret-key", BackendSecretKeyEnv, file.Backend.SecretKey)
+	set.apply(&cfg.BackendRegion, "backend-region", BackendRegionEnv, file.Backend.Region)
 
 	return &cfg, buf.String(), err
 }
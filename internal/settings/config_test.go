@@ -0,0 +1,64 @@
+package settings_test
+
+import (
+	"github.com/ATenderholt/rainbow-storage/internal/settings"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "rainbow.yaml")
+	err := os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatalf("Unable to write config file: %v", err)
+	}
+
+	return path
+}
+
+func TestBackendDefaults(t *testing.T) {
+	cfg, _, err := settings.FromFlags("test", []string{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.False(t, cfg.IsExternalBackend())
+	assert.Equal(t, "http://localhost:9001", cfg.MinioUrl())
+	assert.Equal(t, settings.DefaultBackendAccessKey, cfg.BackendAccessKey)
+	assert.Equal(t, settings.DefaultBackendSecretKey, cfg.BackendSecretKey)
+	assert.Equal(t, settings.DefaultRegion, cfg.BackendRegion)
+	assert.False(t, cfg.ForwardChecksums)
+}
+
+func TestBackendPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `
+backend:
+  url: http://file:9000
+  accessKey: file-key
+  secretKey: file-secret
+  region: file-region
+`)
+
+	t.Setenv(settings.BackendAccessKeyEnv, "env-key")
+	t.Setenv(settings.BackendSecretKeyEnv, "env-secret")
+
+	cfg, _, err := settings.FromFlags("test", []string{"-config", path, "-backend-secret-key", "flag-secret"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.True(t, cfg.IsExternalBackend())
+	assert.Equal(t, "http://file:9000", cfg.MinioUrl())
+	assert.Equal(t, "env-key", cfg.BackendAccessKey)
+	assert.Equal(t, "flag-secret", cfg.BackendSecretKey)
+	assert.Equal(t, "file-region", cfg.BackendRegion)
+}
+
+func TestBackendConfigFileUnknownField(t *testing.T) {
+	path := writeConfigFile(t, "backend:\n  endpoint: http://file:9000\n")
+
+	_, _, err := settings.FromFlags("test", []string{"-config", path})
+	assert.Error(t, err)
+}
@@ -0,0 +1,143 @@
+package sigv4
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	Algorithm       = "AWS4-HMAC-SHA256"
+	EmptyPayload    = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+	amzDateFormat   = "20060102T150405Z"
+	shortDateFormat = "20060102"
+	scopeTerminator = "aws4_request"
+)
+
+// EscapePath URI-encodes every byte of the path except unreserved characters and '/', which is how S3
+// expects the path to be encoded in the canonical request.
+func EscapePath(path string) string {
+	return escape(path, false)
+}
+
+func escape(value string, encodeSlash bool) string {
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+			builder.WriteByte(c)
+		case c == '-', c == '_', c == '.', c == '~':
+			builder.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			builder.WriteByte(c)
+		default:
+			fmt.Fprintf(&builder, "%%%02X", c)
+		}
+	}
+
+	return builder.String()
+}
+
+// canonicalQuery sorts and encodes the query parameters, leaving out any in skip
+func canonicalQuery(query url.Values, skip ...string) string {
+	var pairs []string
+	for key, values := range query {
+		if contains(skip, key) {
+			continue
+		}
+
+		for _, value := range values {
+			pairs = append(pairs, escape(key, true)+"="+escape(value, true))
+		}
+	}
+
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func canonicalHeaders(request *http.Request, signedHeaders []string) string {
+	var builder strings.Builder
+	for _, name := range signedHeaders {
+		var values []string
+		switch name {
+		case "host":
+			values = []string{request.Host}
+		case "content-length":
+			values = request.Header.Values(name)
+			if len(values) == 0 {
+				values = []string{strconv.FormatInt(request.ContentLength, 10)}
+			}
+		default:
+			values = request.Header.Values(name)
+		}
+
+		cleaned := make([]string, len(values))
+		for i, value := range values {
+			cleaned[i] = strings.Join(strings.Fields(value), " ")
+		}
+
+		builder.WriteString(name)
+		builder.WriteByte(':')
+		builder.WriteString(strings.Join(cleaned, ","))
+		builder.WriteByte('\n')
+	}
+
+	return builder.String()
+}
+
+func canonicalRequest(request *http.Request, query string, signedHeaders []string, payloadHash string) string {
+	path := request.URL.Path
+	if path == "" {
+		path = "/"
+	}
+
+	return strings.Join([]string{
+		request.Method,
+		EscapePath(path),
+		query,
+		canonicalHeaders(request, signedHeaders),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+func stringToSign(amzDate string, scope string, canonical string) string {
+	return strings.Join([]string{Algorithm, amzDate, scope, hashHex([]byte(canonical))}, "\n")
+}
+
+func signingKey(secret string, date string, region string, service string) []byte {
+	key := hmacSha256([]byte("AWS4"+secret), date)
+	key = hmacSha256(key, region)
+	key = hmacSha256(key, service)
+	return hmacSha256(key, scopeTerminator)
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,436 @@
+package sigv4
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Values of x-amz-content-sha256 for bodies using the aws-chunked content encoding
+const (
+	StreamingPayload         = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	StreamingPayloadTrailer  = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD-TRAILER"
+	StreamingUnsignedPayload = "STREAMING-UNSIGNED-PAYLOAD-TRAILER"
+)
+
+const (
+	chunkAlgorithm         = "AWS4-HMAC-SHA256-PAYLOAD"
+	trailerAlgorithm       = "AWS4-HMAC-SHA256-TRAILER"
+	chunkSignaturePrefix   = "chunk-signature="
+	checksumPrefix         = "x-amz-checksum-"
+	trailerSignatureHeader = "x-amz-trailer-signature"
+	maxChunkHeaderLength   = 4096
+)
+
+// IsChunked returns whether the body of a request uses the aws-chunked content encoding.
+func IsChunked(request *http.Request) bool {
+	if strings.HasPrefix(request.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return true
+	}
+
+	for _, encoding := range strings.Split(request.Header.Get("Content-Encoding"), ",") {
+		if strings.TrimSpace(encoding) == "aws-chunked" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DecodedContentLength returns the length of the body once aws-chunked encoding is removed.
+func DecodedContentLength(request *http.Request) (int64, error) {
+	value := request.Header.Get("X-Amz-Decoded-Content-Length")
+	length, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || length < 0 {
+		return 0, Error{
+			Code:       "MissingContentLength",
+			Message:    "You must provide the Content-Length HTTP header.",
+			StatusCode: http.StatusLengthRequired,
+		}
+	}
+
+	return length, nil
+}
+
+// chunkSigner verifies the signature of each chunk, seeded by the signature of the request.
+type chunkSigner struct {
+	amzDate  string
+	scope    string
+	key      []byte
+	previous string
+}
+
+func (s *chunkSigner) verifyChunk(dataHash string, signature string) error {
+	toSign := strings.Join([]string{chunkAlgorithm, s.amzDate, s.scope, s.previous, EmptyPayload, dataHash}, "\n")
+	return s.verify(toSign, signature)
+}
+
+func (s *chunkSigner) verifyTrailer(trailers string, signature string) error {
+	toSign := strings.Join([]string{trailerAlgorithm, s.amzDate, s.scope, s.previous, hashHex([]byte(trailers))}, "\n")
+	return s.verify(toSign, signature)
+}
+
+func (s *chunkSigner) verify(toSign string, signature string) error {
+	expected := hex.EncodeToString(hmacSha256(s.key, toSign))
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return signatureDoesNotMatch()
+	}
+
+	s.previous = expected
+	return nil
+}
+
+type trailingChecksum struct {
+	name     string
+	hash     hash.Hash
+	verified string
+}
+
+func newTrailingChecksum(name string) (*trailingChecksum, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	var h hash.Hash
+	switch name {
+	case "":
+		return nil, nil
+	case checksumPrefix + "crc32":
+		h = crc32.NewIEEE()
+	case checksumPrefix + "crc32c":
+		h = crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case checksumPrefix + "sha1":
+		h = sha1.New()
+	case checksumPrefix + "sha256":
+		h = sha256.New()
+	default:
+		return nil, invalidRequest("Unsupported trailing checksum " + name)
+	}
+
+	return &trailingChecksum{name: name, hash: h}, nil
+}
+
+func (c *trailingChecksum) verify(value string) error {
+	expected := base64.StdEncoding.EncodeToString(c.hash.Sum(nil))
+	if value != expected {
+		return Error{
+			Code:       "BadDigest",
+			Message:    "The " + c.name + " you specified did not match the calculated checksum.",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	c.verified = value
+	return nil
+}
+
+// ChunkedReader decodes a body using the aws-chunked content encoding as it is read. Chunk and trailer
+// signatures are verified if a Signature was provided, and a trailing checksum is always verified.
+// Errors are of type Error, so that they can be returned to the client.
+type ChunkedReader struct {
+	reader    *bufio.Reader
+	signed    bool
+	trailer   bool
+	signer    *chunkSigner
+	checksum  *trailingChecksum
+	chunkHash hash.Hash
+	signature string
+	remaining int64
+	expected  int64
+	decoded   int64
+	started   bool
+	err       error
+}
+
+// NewChunkedReader decodes the aws-chunked body of the request. Chunk signatures are only verified when
+// signature is not nil, in which case it must be the verified signature of the request.
+func NewChunkedReader(request *http.Request, signature *Signature) (*ChunkedReader, error) {
+	contentSha256 := request.Header.Get("X-Amz-Content-Sha256")
+
+	var signed, trailer bool
+	switch contentSha256 {
+	case StreamingPayload:
+		signed = true
+	case StreamingPayloadTrailer:
+		signed, trailer = true, true
+	case StreamingUnsignedPayload:
+		trailer = true
+	default:
+		return nil, invalidRequest("Unsupported x-amz-content-sha256 for aws-chunked body: " + contentSha256)
+	}
+
+	expected, err := DecodedContentLength(request)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, err := newTrailingChecksum(request.Header.Get("X-Amz-Trailer"))
+	if err != nil {
+		return nil, err
+	}
+
+	reader := ChunkedReader{
+		reader:    bufio.NewReaderSize(request.Body, maxChunkHeaderLength),
+		signed:    signed,
+		trailer:   trailer,
+		checksum:  checksum,
+		chunkHash: sha256.New(),
+		expected:  expected,
+	}
+
+	if signed && signature != nil {
+		reader.signer = &chunkSigner{
+			amzDate:  signature.Date.UTC().Format(amzDateFormat),
+			scope:    signature.Scope,
+			key:      signature.SigningKey,
+			previous: signature.Value,
+		}
+	}
+
+	return &reader, nil
+}
+
+// WithTrailingChecksum encodes the decoded body again as a single aws-chunked chunk, followed by the trailing
+// checksum once it is verified, so that the body can be sent on with STREAMING-UNSIGNED-PAYLOAD-TRAILER. It returns
+// the name of the checksum and the length of the encoded body, or false if the body has no trailing checksum.
+func (r *ChunkedReader) WithTrailingChecksum() (io.Reader, string, int64, bool) {
+	if r.checksum == nil {
+		return nil, "", 0, false
+	}
+
+	// the value of the checksum is base64 encoded, so its length is known before it is calculated
+	length := int64(len("0\r\n:\r\n\r\n") + len(r.checksum.name) + base64.StdEncoding.EncodedLen(r.checksum.hash.Size()))
+	readers := []io.Reader{r, &trailerReader{checksum: r.checksum}}
+
+	if r.expected > 0 {
+		header := strconv.FormatInt(r.expected, 16) + "\r\n"
+		length += int64(len(header)) + r.expected + 2
+		readers = []io.Reader{strings.NewReader(header), r, strings.NewReader("\r\n"), readers[1]}
+	}
+
+	return io.MultiReader(readers...), r.checksum.name, length, true
+}
+
+// trailerReader returns the final chunk and the trailing checksum, which is only known once the body was read.
+type trailerReader struct {
+	checksum *trailingChecksum
+	trailer  *strings.Reader
+}
+
+func (t *trailerReader) Read(p []byte) (int, error) {
+	if t.trailer == nil {
+		t.trailer = strings.NewReader("0\r\n" + t.checksum.name + ":" + t.checksum.verified + "\r\n\r\n")
+	}
+
+	return t.trailer.Read(p)
+}
+
+func incompleteBody() Error {
+	return Error{
+		Code:       "IncompleteBody",
+		Message:    "You did not provide the number of bytes specified by the Content-Length HTTP header.",
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+func invalidChunk(message string) Error {
+	return Error{Code: "InvalidRequest", Message: "Invalid aws-chunked body: " + message, StatusCode: http.StatusBadRequest}
+}
+
+func (r *ChunkedReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	if r.remaining == 0 {
+		r.err = r.nextChunk()
+		if r.err != nil {
+			return 0, r.err
+		}
+	}
+
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	r.decoded += int64(n)
+	r.chunkHash.Write(p[:n])
+	if r.checksum != nil {
+		r.checksum.hash.Write(p[:n])
+	}
+
+	if err == io.EOF {
+		err = incompleteBody()
+	}
+
+	if err != nil {
+		r.err = err
+	}
+
+	return n, err
+}
+
+// nextChunk finishes the current chunk, then reads the header of the next non-empty chunk. If the final
+// chunk is reached, the trailers are read and io.EOF is returned.
+func (r *ChunkedReader) nextChunk() error {
+	if r.started {
+		err := r.finishChunk()
+		if err != nil {
+			return err
+		}
+	}
+
+	r.started = true
+
+	line, err := r.readLine()
+	if err != nil {
+		return err
+	}
+
+	sizeValue := line
+	if r.signed {
+		parts := strings.SplitN(line, ";", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[1], chunkSignaturePrefix) {
+			return invalidChunk("missing chunk-signature")
+		}
+
+		sizeValue = parts[0]
+		r.signature = strings.TrimPrefix(parts[1], chunkSignaturePrefix)
+	}
+
+	size, err := strconv.ParseInt(sizeValue, 16, 64)
+	if err != nil || size < 0 {
+		return invalidChunk("bad chunk size " + sizeValue)
+	}
+
+	r.remaining = size
+	if size > 0 {
+		return nil
+	}
+
+	// the final chunk has no data, so its signature can be checked right away
+	err = r.verifyChunkSignature()
+	if err != nil {
+		return err
+	}
+
+	if r.decoded != r.expected {
+		return incompleteBody()
+	}
+
+	if r.trailer {
+		err = r.readTrailers()
+	} else {
+		err = r.expectLine("")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return io.EOF
+}
+
+func (r *ChunkedReader) finishChunk() error {
+	err := r.expectLine("")
+	if err != nil {
+		return err
+	}
+
+	return r.verifyChunkSignature()
+}
+
+func (r *ChunkedReader) verifyChunkSignature() error {
+	dataHash := hex.EncodeToString(r.chunkHash.Sum(nil))
+	r.chunkHash.Reset()
+
+	if r.signer == nil {
+		return nil
+	}
+
+	return r.signer.verifyChunk(dataHash, r.signature)
+}
+
+func (r *ChunkedReader) readTrailers() error {
+	var trailers bytes.Buffer
+	var checksumValue, trailerSignature string
+
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return err
+		}
+
+		if line == "" {
+			break
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return invalidChunk("bad trailer " + line)
+		}
+
+		name := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+
+		if name == trailerSignatureHeader {
+			trailerSignature = value
+			continue
+		}
+
+		trailers.WriteString(name + ":" + value + "\n")
+		if r.checksum != nil && name == r.checksum.name {
+			checksumValue = value
+		}
+	}
+
+	if r.signer != nil {
+		err := r.signer.verifyTrailer(trailers.String(), trailerSignature)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.checksum != nil {
+		return r.checksum.verify(checksumValue)
+	}
+
+	return nil
+}
+
+func (r *ChunkedReader) expectLine(expected string) error {
+	line, err := r.readLine()
+	if err != nil {
+		return err
+	}
+
+	if line != expected {
+		return invalidChunk("unexpected data " + strconv.Quote(line))
+	}
+
+	return nil
+}
+
+func (r *ChunkedReader) readLine() (string, error) {
+	line, err := r.reader.ReadSlice('\n')
+	switch {
+	case err == bufio.ErrBufferFull:
+		return "", invalidChunk("line is too long")
+	case err == io.EOF:
+		return "", incompleteBody()
+	case err != nil:
+		return "", err
+	}
+
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
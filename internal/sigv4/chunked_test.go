@@ -0,0 +1,203 @@
+package sigv4_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/ATenderholt/rainbow-storage/internal/sigv4"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/stretchr/testify/assert"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var chunks = []string{strings.Repeat("a", 65536), strings.Repeat("b", 1024)}
+
+// chunkedRequest creates a signed aws-chunked upload of chunks, like an SDK would. The returned Signature is
+// the verified seed signature.
+func chunkedRequest(t *testing.T, contentSha256 string, tamper bool) (*http.Request, sigv4.Signature) {
+	decoded := strings.Join(chunks, "")
+	date := time.Now().UTC()
+
+	request, _ := http.NewRequest(http.MethodPut, "http://localhost:9000/bucket/key.txt", nil)
+	request.Header.Set("Content-Encoding", "aws-chunked")
+	request.Header.Set("X-Amz-Content-Sha256", contentSha256)
+	request.Header.Set("X-Amz-Decoded-Content-Length", strconv.Itoa(len(decoded)))
+	if contentSha256 != sigv4.StreamingPayload {
+		request.Header.Set("X-Amz-Trailer", "x-amz-checksum-crc32")
+	}
+
+	creds := aws.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: credentials["AKIDEXAMPLE"]}
+	err := newSigner().SignHTTP(context.Background(), creds, request, contentSha256, "s3", "us-west-2", date)
+	if err != nil {
+		t.Fatalf("Unable to sign request: %v", err)
+	}
+
+	authorization := request.Header.Get("Authorization")
+	seed, _ := hex.DecodeString(authorization[strings.Index(authorization, "Signature=")+len("Signature="):])
+	chunkSigner := v4.NewStreamSigner(creds, "s3", "us-west-2", seed)
+
+	signed := contentSha256 != sigv4.StreamingUnsignedPayload
+	var body bytes.Buffer
+	var previous []byte
+	for _, chunk := range append(chunks, "") {
+		if !signed {
+			fmt.Fprintf(&body, "%x\r\n", len(chunk))
+		} else {
+			previous, _ = chunkSigner.GetSignature(context.Background(), nil, []byte(chunk), date)
+			fmt.Fprintf(&body, "%x;chunk-signature=%x\r\n", len(chunk), previous)
+		}
+
+		if tamper && chunk != "" {
+			chunk = "X" + chunk[1:]
+		}
+
+		if chunk != "" {
+			body.WriteString(chunk + "\r\n")
+		}
+	}
+
+	if contentSha256 == sigv4.StreamingPayload {
+		body.WriteString("\r\n")
+	} else {
+		checksum := make([]byte, 4)
+		sum := crc32.ChecksumIEEE([]byte(decoded))
+		checksum[0], checksum[1], checksum[2], checksum[3] = byte(sum>>24), byte(sum>>16), byte(sum>>8), byte(sum)
+		trailer := "x-amz-checksum-crc32:" + base64.StdEncoding.EncodeToString(checksum) + "\n"
+		body.WriteString(strings.Replace(trailer, "\n", "\r\n", 1))
+
+		if signed {
+			scope := date.Format("20060102") + "/us-west-2/s3/aws4_request"
+			trailerHash := sha256.Sum256([]byte(trailer))
+			toSign := strings.Join([]string{"AWS4-HMAC-SHA256-TRAILER", date.Format("20060102T150405Z"), scope,
+				hex.EncodeToString(previous), hex.EncodeToString(trailerHash[:])}, "\n")
+			fmt.Fprintf(&body, "x-amz-trailer-signature:%x\r\n", hmacSha256(signingKey(date), toSign))
+		}
+
+		body.WriteString("\r\n")
+	}
+
+	received := httptest.NewRequest(request.Method, request.URL.String(), &body)
+	received.Header = request.Header
+
+	signature, err := sigv4.NewVerifier(credentials, "us-west-2").Verify(received)
+	if err != nil {
+		t.Fatalf("Unable to verify seed signature: %v", err)
+	}
+
+	return received, signature
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func signingKey(date time.Time) []byte {
+	key := hmacSha256([]byte("AWS4"+credentials["AKIDEXAMPLE"]), date.Format("20060102"))
+	key = hmacSha256(key, "us-west-2")
+	key = hmacSha256(key, "s3")
+	return hmacSha256(key, "aws4_request")
+}
+
+func TestChunkedReader(t *testing.T) {
+	for _, contentSha256 := range []string{sigv4.StreamingPayload, sigv4.StreamingPayloadTrailer, sigv4.StreamingUnsignedPayload} {
+		t.Run(contentSha256, func(t *testing.T) {
+			request, signature := chunkedRequest(t, contentSha256, false)
+			assert.True(t, sigv4.IsChunked(request))
+
+			reader, err := sigv4.NewChunkedReader(request, &signature)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			decoded, err := io.ReadAll(reader)
+			assert.NoError(t, err)
+			assert.Equal(t, strings.Join(chunks, ""), string(decoded))
+		})
+	}
+}
+
+func TestChunkedReaderWithTrailingChecksum(t *testing.T) {
+	request, _ := chunkedRequest(t, sigv4.StreamingUnsignedPayload, false)
+
+	reader, err := sigv4.NewChunkedReader(request, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	encoded, name, length, ok := reader.WithTrailingChecksum()
+	if !assert.True(t, ok) {
+		return
+	}
+
+	body, err := io.ReadAll(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, "x-amz-checksum-crc32", name)
+	assert.Equal(t, int64(len(body)), length)
+
+	// the encoded body is decoded again, verifying the forwarded checksum
+	decoding := httptest.NewRequest(http.MethodPut, "/bucket/key", bytes.NewReader(body))
+	decoding.Header = request.Header.Clone()
+	decoded, err := sigv4.NewChunkedReader(decoding, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	data, err := io.ReadAll(decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Join(chunks, ""), string(data))
+}
+
+func TestChunkedReaderTampered(t *testing.T) {
+	request, signature := chunkedRequest(t, sigv4.StreamingPayload, true)
+
+	reader, err := sigv4.NewChunkedReader(request, &signature)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = io.ReadAll(reader)
+	assertErrorCode(t, "SignatureDoesNotMatch", err)
+
+	// without verifying signatures, the body still decodes
+	request, _ = chunkedRequest(t, sigv4.StreamingPayload, true)
+	reader, _ = sigv4.NewChunkedReader(request, nil)
+	decoded, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, len(strings.Join(chunks, "")), len(decoded))
+}
+
+func TestChunkedReaderBadChecksum(t *testing.T) {
+	request, _ := chunkedRequest(t, sigv4.StreamingUnsignedPayload, true)
+
+	reader, err := sigv4.NewChunkedReader(request, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = io.ReadAll(reader)
+	assertErrorCode(t, "BadDigest", err)
+}
+
+func TestChunkedReaderTruncated(t *testing.T) {
+	request, _ := chunkedRequest(t, sigv4.StreamingUnsignedPayload, false)
+	body, _ := io.ReadAll(request.Body)
+	request.Body = io.NopCloser(bytes.NewReader(body[:1000]))
+
+	reader, _ := sigv4.NewChunkedReader(request, nil)
+	_, err := io.ReadAll(reader)
+	assertErrorCode(t, "IncompleteBody", err)
+}
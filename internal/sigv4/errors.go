@@ -0,0 +1,65 @@
+package sigv4
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Error is an S3 error code describing why a request could not be authenticated.
+type Error struct {
+	Code       string
+	Message    string
+	StatusCode int
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func accessDenied(message string) Error {
+	return Error{Code: "AccessDenied", Message: message, StatusCode: http.StatusForbidden}
+}
+
+func invalidAccessKeyId() Error {
+	return Error{
+		Code:       "InvalidAccessKeyId",
+		Message:    "The AWS Access Key Id you provided does not exist in our records.",
+		StatusCode: http.StatusForbidden,
+	}
+}
+
+func signatureDoesNotMatch() Error {
+	return Error{
+		Code:       "SignatureDoesNotMatch",
+		Message:    "The request signature we calculated does not match the signature you provided. Check your key and signing method.",
+		StatusCode: http.StatusForbidden,
+	}
+}
+
+func malformedAuthorization(format string, args ...interface{}) Error {
+	return Error{
+		Code:       "AuthorizationHeaderMalformed",
+		Message:    fmt.Sprintf(format, args...),
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+func malformedQuery(format string, args ...interface{}) Error {
+	return Error{
+		Code:       "AuthorizationQueryParametersError",
+		Message:    fmt.Sprintf(format, args...),
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+func invalidRequest(message string) Error {
+	return Error{Code: "InvalidRequest", Message: message, StatusCode: http.StatusBadRequest}
+}
+
+func requestTimeTooSkewed() Error {
+	return Error{
+		Code:       "RequestTimeTooSkewed",
+		Message:    "The difference between the request time and the current time is too large.",
+		StatusCode: http.StatusForbidden,
+	}
+}
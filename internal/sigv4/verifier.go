@@ -0,0 +1,297 @@
+package sigv4
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	service = "s3"
+
+	// maxSkew is how far the time of a signed request may be from now, like AWS
+	maxSkew = 15 * time.Minute
+
+	// maxExpires is the longest that a presigned URL may be valid, in seconds
+	maxExpires = 7 * 24 * 60 * 60
+)
+
+// Signature is a verified SigV4 signature. It has everything needed to verify anything signed
+// after it, like the chunks of an aws-chunked body.
+type Signature struct {
+	AccessKey  string
+	Date       time.Time
+	Scope      string
+	SigningKey []byte
+	Value      string
+	Presigned  bool
+}
+
+// IsSigned returns whether a request has either an Authorization header or a presigned query string.
+func IsSigned(request *http.Request) bool {
+	return request.Header.Get("Authorization") != "" || IsPresigned(request)
+}
+
+// IsPresigned returns whether a request is authenticated by a presigned query string.
+func IsPresigned(request *http.Request) bool {
+	return request.URL.Query().Get("X-Amz-Algorithm") != ""
+}
+
+// Verifier checks SigV4 signatures of requests against a set of access keys and their secrets.
+type Verifier struct {
+	credentials map[string]string
+	region      string
+	now         func() time.Time
+}
+
+func NewVerifier(credentials map[string]string, region string) *Verifier {
+	return &Verifier{
+		credentials: credentials,
+		region:      region,
+		now:         time.Now,
+	}
+}
+
+// Verify checks the signature in either the Authorization header or the presigned query string of a
+// request. Errors are always of type Error.
+func (v Verifier) Verify(request *http.Request) (Signature, error) {
+	if request.Header.Get("Authorization") != "" {
+		return v.verifyHeader(request)
+	}
+
+	if IsPresigned(request) {
+		return v.verifyPresigned(request)
+	}
+
+	return Signature{}, accessDenied("Anonymous requests are not allowed.")
+}
+
+type credential struct {
+	accessKey string
+	date      string
+	region    string
+	service   string
+}
+
+func (c credential) scope() string {
+	return strings.Join([]string{c.date, c.region, c.service, scopeTerminator}, "/")
+}
+
+// AccessKey returns the access key that a request claims to be signed with, in either the Authorization
+// header or a presigned query string, without verifying the signature.
+func AccessKey(request *http.Request) (string, bool) {
+	value := request.URL.Query().Get("X-Amz-Credential")
+	if authorization := request.Header.Get("Authorization"); strings.HasPrefix(authorization, Algorithm+" ") {
+		value = authorizationFields(authorization)["Credential"]
+	}
+
+	c, ok := parseCredential(value)
+	return c.accessKey, ok
+}
+
+// authorizationFields splits the Credential, SignedHeaders and Signature fields of an Authorization header.
+func authorizationFields(authorization string) map[string]string {
+	fields := make(map[string]string)
+	for _, field := range strings.Split(strings.TrimPrefix(authorization, Algorithm+" "), ",") {
+		pair := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(pair) == 2 {
+			fields[pair[0]] = pair[1]
+		}
+	}
+
+	return fields
+}
+
+func parseCredential(value string) (credential, bool) {
+	parts := strings.Split(value, "/")
+	if len(parts) != 5 || parts[4] != scopeTerminator {
+		return credential{}, false
+	}
+
+	return credential{
+		accessKey: parts[0],
+		date:      parts[1],
+		region:    parts[2],
+		service:   parts[3],
+	}, true
+}
+
+// checkCredential returns the secret for a credential, making sure it is scoped to this region and service
+func (v Verifier) checkCredential(c credential, malformed func(string, ...interface{}) Error) (string, error) {
+	secret, ok := v.credentials[c.accessKey]
+	if !ok {
+		return "", invalidAccessKeyId()
+	}
+
+	if c.region != v.region {
+		return "", malformed("the region '%s' is wrong; expecting '%s'", c.region, v.region)
+	}
+
+	if c.service != service {
+		return "", malformed("the service '%s' is wrong; expecting '%s'", c.service, service)
+	}
+
+	return secret, nil
+}
+
+func (v Verifier) verifyHeader(request *http.Request) (Signature, error) {
+	authorization := request.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, Algorithm+" ") {
+		return Signature{}, invalidRequest("Please use " + Algorithm + ".")
+	}
+
+	fields := authorizationFields(authorization)
+	c, ok := parseCredential(fields["Credential"])
+	if !ok {
+		return Signature{}, malformedAuthorization("the Credential is mal-formed: %s", fields["Credential"])
+	}
+
+	signedHeaders := strings.Split(fields["SignedHeaders"], ";")
+	if !contains(signedHeaders, "host") {
+		return Signature{}, malformedAuthorization("SignedHeaders must include host")
+	}
+
+	secret, err := v.checkCredential(c, malformedAuthorization)
+	if err != nil {
+		return Signature{}, err
+	}
+
+	payloadHash := request.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		return Signature{}, invalidRequest("Missing required header for this request: x-amz-content-sha256")
+	}
+
+	amzDate := request.Header.Get("X-Amz-Date")
+	date, err := time.Parse(amzDateFormat, amzDate)
+	if amzDate == "" {
+		date, err = http.ParseTime(request.Header.Get("Date"))
+		amzDate = date.UTC().Format(amzDateFormat)
+	}
+
+	if err != nil {
+		return Signature{}, accessDenied("AWS authentication requires a valid Date or x-amz-date header")
+	}
+
+	if date.UTC().Format(shortDateFormat) != c.date {
+		return Signature{}, malformedAuthorization("the date in the Credential does not match the request date")
+	}
+
+	now := v.now()
+	if date.Before(now.Add(-maxSkew)) || date.After(now.Add(maxSkew)) {
+		return Signature{}, requestTimeTooSkewed()
+	}
+
+	canonical := canonicalRequest(request, canonicalQuery(request.URL.Query()), signedHeaders, payloadHash)
+	return v.check(c, secret, date, amzDate, canonical, fields["Signature"], false)
+}
+
+func (v Verifier) verifyPresigned(request *http.Request) (Signature, error) {
+	query := request.URL.Query()
+	if query.Get("X-Amz-Algorithm") != Algorithm {
+		return Signature{}, malformedQuery("X-Amz-Algorithm only supports \"%s\"", Algorithm)
+	}
+
+	c, ok := parseCredential(query.Get("X-Amz-Credential"))
+	if !ok {
+		return Signature{}, malformedQuery("the Credential is mal-formed: %s", query.Get("X-Amz-Credential"))
+	}
+
+	signedHeaders := strings.Split(query.Get("X-Amz-SignedHeaders"), ";")
+	if !contains(signedHeaders, "host") {
+		return Signature{}, malformedQuery("X-Amz-SignedHeaders must include host")
+	}
+
+	secret, err := v.checkCredential(c, malformedQuery)
+	if err != nil {
+		return Signature{}, err
+	}
+
+	amzDate := query.Get("X-Amz-Date")
+	date, err := time.Parse(amzDateFormat, amzDate)
+	if err != nil {
+		return Signature{}, malformedQuery("X-Amz-Date must be in the ISO8601 Long Format \"yyyyMMdd'T'HHmmss'Z'\"")
+	}
+
+	if date.Format(shortDateFormat) != c.date {
+		return Signature{}, malformedQuery("the date in the Credential does not match X-Amz-Date")
+	}
+
+	err = checkExpiry(query, date, v.now())
+	if err != nil {
+		return Signature{}, err
+	}
+
+	payloadHash := query.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		payloadHash = UnsignedPayload
+	}
+
+	canonical := canonicalRequest(request, canonicalQuery(query, "X-Amz-Signature"), signedHeaders, payloadHash)
+	return v.check(c, secret, date, amzDate, canonical, query.Get("X-Amz-Signature"), true)
+}
+
+// PresignedParameters are the query parameters that authenticate a presigned request.
+var PresignedParameters = []string{
+	"X-Amz-Algorithm",
+	"X-Amz-Credential",
+	"X-Amz-Date",
+	"X-Amz-Expires",
+	"X-Amz-Security-Token",
+	"X-Amz-Signature",
+	"X-Amz-SignedHeaders",
+}
+
+// CheckExpiry returns an Error if a presigned request has expired, without verifying its signature.
+func CheckExpiry(request *http.Request, now time.Time) error {
+	query := request.URL.Query()
+
+	date, err := time.Parse(amzDateFormat, query.Get("X-Amz-Date"))
+	if err != nil {
+		return malformedQuery("X-Amz-Date must be in the ISO8601 Long Format \"yyyyMMdd'T'HHmmss'Z'\"")
+	}
+
+	return checkExpiry(query, date, now)
+}
+
+func checkExpiry(query url.Values, date time.Time, now time.Time) error {
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil || expires < 0 {
+		return malformedQuery("X-Amz-Expires should be a number")
+	}
+
+	if expires > maxExpires {
+		return malformedQuery("X-Amz-Expires must be less than a week (in seconds) that is; the given X-Amz-Expires must be less than %d seconds", maxExpires)
+	}
+
+	if now.Before(date.Add(-maxSkew)) {
+		return accessDenied("Request is not valid yet")
+	}
+
+	if now.After(date.Add(time.Duration(expires) * time.Second)) {
+		return accessDenied("Request has expired")
+	}
+
+	return nil
+}
+
+func (v Verifier) check(c credential, secret string, date time.Time, amzDate string, canonical string, signature string, presigned bool) (Signature, error) {
+	key := signingKey(secret, c.date, c.region, c.service)
+	expected := hex.EncodeToString(hmacSha256(key, stringToSign(amzDate, c.scope(), canonical)))
+
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return Signature{}, signatureDoesNotMatch()
+	}
+
+	return Signature{
+		AccessKey:  c.accessKey,
+		Date:       date,
+		Scope:      c.scope(),
+		SigningKey: key,
+		Value:      expected,
+		Presigned:  presigned,
+	}, nil
+}
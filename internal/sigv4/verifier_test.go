@@ -0,0 +1,129 @@
+package sigv4_test
+
+import (
+	"context"
+	"errors"
+	"github.com/ATenderholt/rainbow-storage/internal/sigv4"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var credentials = map[string]string{"AKIDEXAMPLE": "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+func newSigner() *v4.Signer {
+	return v4.NewSigner(func(options *v4.SignerOptions) {
+		options.DisableURIPathEscaping = true
+	})
+}
+
+// signedRequest signs a request like an SDK would, then converts it to a request received by a server
+func signedRequest(t *testing.T, secret string, region string, date time.Time) *http.Request {
+	request, _ := http.NewRequest(http.MethodPut, "http://localhost:9000/bucket/dir/some%20key%2B%281%29.txt?tagging&versionId=a%2Fb", nil)
+	request.Header.Set("Content-Type", "text/plain")
+	request.Header.Set("X-Amz-Content-Sha256", sigv4.UnsignedPayload)
+	request.Header.Set("X-Amz-Meta-Owner", "  some   owner ")
+
+	creds := aws.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: secret}
+	err := newSigner().SignHTTP(context.Background(), creds, request, sigv4.UnsignedPayload, "s3", region, date)
+	if err != nil {
+		t.Fatalf("Unable to sign request: %v", err)
+	}
+
+	received := httptest.NewRequest(request.Method, request.URL.String(), nil)
+	received.Header = request.Header
+	return received
+}
+
+func presignedRequest(t *testing.T, date time.Time, expires string) *http.Request {
+	request, _ := http.NewRequest(http.MethodGet, "http://localhost:9000/bucket/key.txt?X-Amz-Expires="+expires, nil)
+
+	creds := aws.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: credentials["AKIDEXAMPLE"]}
+	url, _, err := newSigner().PresignHTTP(context.Background(), creds, request, sigv4.UnsignedPayload, "s3", "us-west-2", date)
+	if err != nil {
+		t.Fatalf("Unable to presign request: %v", err)
+	}
+
+	return httptest.NewRequest(request.Method, url, nil)
+}
+
+func assertErrorCode(t *testing.T, code string, err error) {
+	var sigErr sigv4.Error
+	if assert.True(t, errors.As(err, &sigErr), "expected sigv4.Error but got %v", err) {
+		assert.Equal(t, code, sigErr.Code)
+	}
+}
+
+func TestVerifyAuthorizationHeader(t *testing.T) {
+	verifier := sigv4.NewVerifier(credentials, "us-west-2")
+
+	signature, err := verifier.Verify(signedRequest(t, credentials["AKIDEXAMPLE"], "us-west-2", time.Now()))
+	assert.NoError(t, err)
+	assert.Equal(t, "AKIDEXAMPLE", signature.AccessKey)
+	assert.False(t, signature.Presigned)
+}
+
+func TestVerifyAuthorizationHeaderErrors(t *testing.T) {
+	verifier := sigv4.NewVerifier(credentials, "us-west-2")
+
+	_, err := verifier.Verify(signedRequest(t, "wrong-secret", "us-west-2", time.Now()))
+	assertErrorCode(t, "SignatureDoesNotMatch", err)
+
+	_, err = verifier.Verify(signedRequest(t, credentials["AKIDEXAMPLE"], "us-east-1", time.Now()))
+	assertErrorCode(t, "AuthorizationHeaderMalformed", err)
+
+	_, err = verifier.Verify(signedRequest(t, credentials["AKIDEXAMPLE"], "us-west-2", time.Now().Add(-time.Hour)))
+	assertErrorCode(t, "RequestTimeTooSkewed", err)
+
+	unknown := sigv4.NewVerifier(map[string]string{"OTHER": "secret"}, "us-west-2")
+	_, err = unknown.Verify(signedRequest(t, credentials["AKIDEXAMPLE"], "us-west-2", time.Now()))
+	assertErrorCode(t, "InvalidAccessKeyId", err)
+
+	tampered := signedRequest(t, credentials["AKIDEXAMPLE"], "us-west-2", time.Now())
+	tampered.Header.Set("Content-Type", "application/json")
+	_, err = verifier.Verify(tampered)
+	assertErrorCode(t, "SignatureDoesNotMatch", err)
+
+	_, err = verifier.Verify(httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket/key.txt", nil))
+	assertErrorCode(t, "AccessDenied", err)
+}
+
+func TestVerifyPresigned(t *testing.T) {
+	verifier := sigv4.NewVerifier(credentials, "us-west-2")
+
+	request := presignedRequest(t, time.Now(), "900")
+	assert.True(t, sigv4.IsPresigned(request))
+
+	signature, err := verifier.Verify(request)
+	assert.NoError(t, err)
+	assert.Equal(t, "AKIDEXAMPLE", signature.AccessKey)
+	assert.True(t, signature.Presigned)
+
+	_, err = verifier.Verify(presignedRequest(t, time.Now().Add(-time.Hour), "900"))
+	assertErrorCode(t, "AccessDenied", err)
+
+	_, err = verifier.Verify(presignedRequest(t, time.Now(), "604801"))
+	assertErrorCode(t, "AuthorizationQueryParametersError", err)
+
+	tampered := presignedRequest(t, time.Now(), "900")
+	tampered.URL.Path = "/bucket/other.txt"
+	_, err = verifier.Verify(tampered)
+	assertErrorCode(t, "SignatureDoesNotMatch", err)
+}
+
+func TestAccessKey(t *testing.T) {
+	accessKey, ok := sigv4.AccessKey(signedRequest(t, "wrong secret", "us-west-2", time.Now()))
+	assert.True(t, ok)
+	assert.Equal(t, "AKIDEXAMPLE", accessKey)
+
+	accessKey, ok = sigv4.AccessKey(presignedRequest(t, time.Now(), "900"))
+	assert.True(t, ok)
+	assert.Equal(t, "AKIDEXAMPLE", accessKey)
+
+	_, ok = sigv4.AccessKey(httptest.NewRequest(http.MethodGet, "http://localhost:9000/bucket/key.txt", nil))
+	assert.False(t, ok)
+}